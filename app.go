@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// errNoInstallationOwner はGitHub Appのインストールを特定できない場合のエラーメッセージです
+const errNoInstallationOwner = "GitHub Appで認証する場合は owner を指定するか、--github-app-default-owner を設定してください"
+
+// installationOwnerArguments はインストールの特定に owner 以外の引数を使用するツールです
+// リポジトリの作成とフォークには作成先のアカウントのインストールトークンが必要です
+// インストールトークンはユーザーとして認証されず個人アカウントには作成できないため、この引数は必須です
+var installationOwnerArguments = map[string]string{
+	"create_repository": "organization",
+	"fork_repository":   "organization",
+}
+
+// withInstallationToken はGitHub Appが設定されている場合に、ツール引数の owner と repo に対応する
+// インストールトークンをコンテキストに設定します
+// owner 引数のないツールでは既定のオーナーのインストールを使用します
+func withInstallationToken(opts ServerOptions, toolName string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if opts.GitHubApp == nil {
+		return handler
+	}
+	ownerArgument := installationOwnerArguments[toolName]
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		owner, _ := args["owner"].(string)
+		repo, _ := args["repo"].(string)
+		if ownerArgument != "" {
+			owner, _ = args[ownerArgument].(string)
+			if owner == "" {
+				return toolResultArgumentError(fmt.Sprintf("GitHub Appで認証する場合は %s を指定してください", ownerArgument)), nil
+			}
+			repo = ""
+		}
+		if owner == "" {
+			owner, repo = opts.GitHubAppDefaultOwner, ""
+		}
+		if owner == "" {
+			return toolResultArgumentError(errNoInstallationOwner), nil
+		}
+		ctx, err := installationContext(ctx, opts.GitHubApp, owner, repo)
+		if err != nil {
+			return toolResultError(err), nil
+		}
+		return handler(ctx, request)
+	}
+}
+
+// installationContext は owner と repo に対応するインストールトークンをコンテキストに設定します
+func installationContext(ctx context.Context, app *operations.GitHubApp, owner, repo string) (context.Context, error) {
+	token, err := app.InstallationToken(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return common.WithAuthToken(ctx, token), nil
+}
+
+// loadGitHubApp はコマンドライン引数からGitHub Appの設定を読み込みます
+// App IDと秘密鍵のどちらも指定されていない場合はnilを返します
+func loadGitHubApp(appID int64, keyFile string) (*operations.GitHubApp, error) {
+	if appID == 0 && keyFile == "" {
+		return nil, nil
+	}
+	if appID == 0 || keyFile == "" {
+		return nil, fmt.Errorf("--github-app-id と --github-app-private-key は両方を指定してください")
+	}
+	return operations.LoadGitHubApp(appID, keyFile)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+// withInboundAuth はリクエストの呼び出し元を認証するHTTPハンドラーを返します
+//
+// 認証できないリクエストはMCPのセッションを作成する前に401で拒否します。
+// 認証された呼び出し元はコンテキストに保存され、ポリシーや監査ログで参照できます。
+func (s *GitHubMCPServer) withInboundAuth(next http.Handler) http.Handler {
+	if s.opts.InboundAuth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := s.opts.InboundAuth.Authenticate(r)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(common.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// withTokenValidation はHTTPリクエストの認証トークンを検証するHTTPハンドラーを返します
+//
+// SSEの接続時やStreamable HTTPの初期化時に無効なトークンを拒否することで、
+// 最初のツール呼び出しまで認証エラーに気付けない状況を防ぎます。
+// 検証結果はキャッシュされるため、同じトークンによる以降のリクエストではGitHub APIを呼び出しません。
+func (s *GitHubMCPServer) withTokenValidation(next http.Handler) http.Handler {
+	if s.opts.TokenValidator == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := s.requestContext(r.Context(), r)
+		info, err := s.validateToken(ctx)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(common.WithTokenInfo(r.Context(), info)))
+	})
+}
+
+// validateToken はコンテキストの認証トークンを検証します
+func (s *GitHubMCPServer) validateToken(ctx context.Context) (*common.TokenInfo, error) {
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := common.GitHubHostFromContext(ctx); !ok {
+		ctx = common.WithGitHubHost(ctx, s.opts.GitHubHost)
+	}
+	return s.opts.TokenValidator.Validate(ctx, token)
+}
+
+// writeAuthError はトークンの検証エラーをツールのエラー結果と同じ形式のJSONで返します
+// トークンが無効な場合は401、GitHub APIに接続できないなどの場合は502を返します
+func writeAuthError(w http.ResponseWriter, err error) {
+	code := common.ErrorCode(err)
+	status := http.StatusBadGateway
+	if code == common.ErrorCodeAuthentication {
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Bearer realm="GitHub"`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(toolErrorResult{
+		Code:    code,
+		Message: common.FormatGitHubError(err),
+	})
+}
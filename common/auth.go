@@ -2,22 +2,97 @@ package common
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 // authKey は認証トークンを保存するためのコンテキストキー
 type authKey struct{}
 
+// authErrorKey は認証情報の解析エラーを保存するためのコンテキストキー
+type authErrorKey struct{}
+
+// tokenInfoKey は検証済みトークンの情報を保存するためのコンテキストキー
+type tokenInfoKey struct{}
+
 // WithAuthToken はコンテキストに認証トークンを追加します
+// コンテキストに保存済みの認証情報の解析エラーは、新しいトークンで置き換えられます
 func WithAuthToken(ctx context.Context, token string) context.Context {
+	if ctx.Value(authErrorKey{}) != nil {
+		ctx = context.WithValue(ctx, authErrorKey{}, nil)
+	}
 	return context.WithValue(ctx, authKey{}, token)
 }
 
+// ParseAuthorization はAuthorizationヘッダーの値から認証トークンを取り出します
+// "Bearer <token>" と "token <token>" 形式に加え、互換性のためスキームのないトークンも受け付けます
+func ParseAuthorization(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	scheme, token, ok := strings.Cut(value, " ")
+	isSupported := strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, "token")
+	if !ok && !isSupported {
+		return value, nil
+	}
+	if !isSupported {
+		return "", &GitHubAuthenticationError{
+			GitHubError: GitHubError{
+				Message: fmt.Sprintf("Authorizationヘッダーの認証方式 %q には対応していません。Bearer または token を指定してください", scheme),
+				Status:  http.StatusUnauthorized,
+			},
+		}
+	}
+	token = strings.TrimSpace(token)
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", &GitHubAuthenticationError{
+			GitHubError: GitHubError{
+				Message: "Authorizationヘッダーのトークンが不正です",
+				Status:  http.StatusUnauthorized,
+			},
+		}
+	}
+	return token, nil
+}
+
 // AuthTokenFromRequest はHTTPリクエストから認証トークンを抽出してコンテキストに追加します
-func AuthTokenFromRequest(ctx context.Context, r *http.Request) context.Context {
-	return WithAuthToken(ctx, r.Header.Get("Authorization"))
+// Authorizationヘッダーがない場合、allowServerToken が true であれば環境変数GITHUB_TOKENのトークンを使用します
+// ただし、サーバーのトークンを任意の接続先に送信させないように、X-GitHub-Hostヘッダーで
+// 接続先が指定されている場合はサーバーのトークンを使用しません
+// ヘッダーが不正な場合はエラーをコンテキストに保存し、GetAuthTokenFromContext で返します
+func AuthTokenFromRequest(ctx context.Context, r *http.Request, allowServerToken bool) context.Context {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		if !allowServerToken {
+			return context.WithValue(WithAuthToken(ctx, ""), authErrorKey{}, &GitHubAuthenticationError{
+				GitHubError: GitHubError{
+					Message: "Authorizationヘッダーでトークンを指定してください (認証されていない呼び出し元はサーバーのトークンを使用できません)",
+					Status:  http.StatusUnauthorized,
+				},
+			})
+		}
+		if r.Header.Get(GitHubHostHeader) != "" {
+			return context.WithValue(WithAuthToken(ctx, ""), authErrorKey{}, &GitHubAuthenticationError{
+				GitHubError: GitHubError{
+					Message: fmt.Sprintf("%sヘッダーで接続先を指定する場合は、Authorizationヘッダーでトークンを指定してください", GitHubHostHeader),
+					Status:  http.StatusUnauthorized,
+				},
+			})
+		}
+		return AuthTokenFromEnv(ctx)
+	}
+	token, err := ParseAuthorization(header)
+	if err != nil {
+		return context.WithValue(WithAuthToken(ctx, ""), authErrorKey{}, err)
+	}
+	return WithAuthToken(ctx, token)
 }
 
 // AuthTokenFromEnv は環境変数から認証トークンを抽出してコンテキストに追加します
@@ -27,9 +102,130 @@ func AuthTokenFromEnv(ctx context.Context) context.Context {
 
 // GetAuthTokenFromContext はコンテキストから認証トークンを取得します
 func GetAuthTokenFromContext(ctx context.Context) (string, error) {
+	if err, ok := ctx.Value(authErrorKey{}).(error); ok {
+		return "", err
+	}
 	token, ok := ctx.Value(authKey{}).(string)
 	if !ok || token == "" {
-		return "", fmt.Errorf("認証トークンがありません。環境変数GITHUB_TOKENを設定するか、Authorizationヘッダーを指定してください")
+		return "", &GitHubAuthenticationError{
+			GitHubError: GitHubError{
+				Message: "認証トークンがありません。環境変数GITHUB_TOKENを設定するか、Authorizationヘッダーを指定してください",
+				Status:  http.StatusUnauthorized,
+			},
+		}
 	}
 	return token, nil
 }
+
+// TokenInfo は検証済みトークンの情報を表します
+type TokenInfo struct {
+	// Login はトークンに対応するユーザーのログイン名です
+	Login string
+	// Scopes はトークンに付与されたOAuthスコープです (Fine-grainedトークンやGitHub Appでは空)
+	Scopes []string
+	// ExpiresAt はトークンの有効期限です (期限がない場合はゼロ値)
+	ExpiresAt time.Time
+}
+
+// HasScope はトークンに指定されたスコープが付与されているかどうかを判断します
+func (i *TokenInfo) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// WithTokenInfo はコンテキストに検証済みトークンの情報を追加します
+func WithTokenInfo(ctx context.Context, info *TokenInfo) context.Context {
+	return context.WithValue(ctx, tokenInfoKey{}, info)
+}
+
+// TokenInfoFromContext はコンテキストから検証済みトークンの情報を取得します
+// トークンが検証されていない場合はnilを返します
+func TokenInfoFromContext(ctx context.Context) *TokenInfo {
+	info, _ := ctx.Value(tokenInfoKey{}).(*TokenInfo)
+	return info
+}
+
+// TokenInfoFunc はトークンをGitHub APIで検証し、その情報を返す関数です
+type TokenInfoFunc func(ctx context.Context, token string) (*TokenInfo, error)
+
+// TokenValidator はトークンを検証し、結果を一定時間キャッシュします
+//
+// 検証に成功したトークンの情報のみをキャッシュし、失敗した場合は次回も検証します。
+// キャッシュはトークンのハッシュとGitHub APIの接続先で識別されます。
+type TokenValidator struct {
+	fetch TokenInfoFunc
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]tokenCacheEntry
+}
+
+// tokenCacheEntry はキャッシュされたトークンの情報を表します
+type tokenCacheEntry struct {
+	info      *TokenInfo
+	expiresAt time.Time
+}
+
+// NewTokenValidator は新しいTokenValidatorを作成します
+// ttl は検証結果をキャッシュする時間で、トークンの有効期限がそれより早い場合は有効期限までキャッシュします
+func NewTokenValidator(fetch TokenInfoFunc, ttl time.Duration) *TokenValidator {
+	return &TokenValidator{
+		fetch: fetch,
+		ttl:   ttl,
+		now:   time.Now,
+		cache: make(map[string]tokenCacheEntry),
+	}
+}
+
+// Validate はトークンを検証し、その情報を返します
+// 有効期限が過ぎたトークンは GitHubAuthenticationError になります
+func (v *TokenValidator) Validate(ctx context.Context, token string) (*TokenInfo, error) {
+	key := v.cacheKey(ctx, token)
+	now := v.now()
+
+	v.mu.Lock()
+	entry, ok := v.cache[key]
+	if ok && now.After(entry.expiresAt) {
+		delete(v.cache, key)
+		ok = false
+	}
+	v.mu.Unlock()
+	if ok {
+		return entry.info, nil
+	}
+
+	info, err := v.fetch(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !info.ExpiresAt.IsZero() && !now.Before(info.ExpiresAt) {
+		return nil, &GitHubAuthenticationError{
+			GitHubError: GitHubError{
+				Message: fmt.Sprintf("トークンの有効期限が切れています (%s)", info.ExpiresAt.Format(time.RFC3339)),
+				Status:  http.StatusUnauthorized,
+			},
+		}
+	}
+
+	expiresAt := now.Add(v.ttl)
+	if !info.ExpiresAt.IsZero() && info.ExpiresAt.Before(expiresAt) {
+		expiresAt = info.ExpiresAt
+	}
+	v.mu.Lock()
+	v.cache[key] = tokenCacheEntry{info: info, expiresAt: expiresAt}
+	v.mu.Unlock()
+	return info, nil
+}
+
+// cacheKey はトークンと接続先からキャッシュのキーを作成します
+// トークンそのものをメモリに保持しないようにハッシュを使用します
+func (v *TokenValidator) cacheKey(ctx context.Context, token string) string {
+	host, _ := GitHubHostFromContext(ctx)
+	sum := sha256.Sum256([]byte(host.APIURL + "\x00" + token))
+	return hex.EncodeToString(sum[:])
+}
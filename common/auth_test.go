@@ -0,0 +1,159 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseAuthorization(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: ""},
+		{value: "Bearer ghp_abc", want: "ghp_abc"},
+		{value: "bearer  ghp_abc ", want: "ghp_abc"},
+		{value: "token ghp_abc", want: "ghp_abc"},
+		{value: "ghp_abc", want: "ghp_abc"},
+		{value: "Basic dXNlcjpwYXNz", wantErr: true},
+		{value: "Bearer ", wantErr: true},
+		{value: "Bearer a b", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAuthorization(tt.value)
+		if tt.wantErr {
+			if ErrorCode(err) != ErrorCodeAuthentication {
+				t.Errorf("ParseAuthorization(%q): expected authentication error, got %v", tt.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAuthorization(%q): unexpected error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAuthorization(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAuthTokenFromRequest(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "server-token")
+
+	tests := []struct {
+		name      string
+		header    string
+		host      string
+		anonymous bool
+		want      string
+		wantErr   bool
+	}{
+		{name: "bearer header", header: "Bearer client-token", want: "client-token"},
+		{name: "fallback to server token", anonymous: true, want: "server-token"},
+		{name: "server token is not allowed", wantErr: true},
+		{name: "unsupported scheme does not fall back", header: "Basic dXNlcjpwYXNz", wantErr: true},
+		{name: "host override with own token", header: "Bearer client-token", host: "ghe.example.com", want: "client-token"},
+		// サーバーのトークンが呼び出し元の指定した接続先に送信されないこと
+		{name: "host override does not fall back", host: "attacker.example.com", anonymous: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/sse", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.host != "" {
+				r.Header.Set(GitHubHostHeader, tt.host)
+			}
+			got, err := GetAuthTokenFromContext(AuthTokenFromRequest(context.Background(), r, tt.anonymous))
+			if tt.wantErr {
+				if ErrorCode(err) != ErrorCodeAuthentication {
+					t.Errorf("expected authentication error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenValidator(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	infos := map[string]*TokenInfo{
+		"valid":    {Login: "octocat", Scopes: []string{"repo"}},
+		"expiring": {Login: "octocat", ExpiresAt: now.Add(time.Minute)},
+		"expired":  {Login: "octocat", ExpiresAt: now.Add(-time.Minute)},
+	}
+	v := NewTokenValidator(func(ctx context.Context, token string) (*TokenInfo, error) {
+		calls++
+		if info, ok := infos[token]; ok {
+			return info, nil
+		}
+		return nil, &GitHubAuthenticationError{GitHubError: GitHubError{Message: "Bad credentials", Status: http.StatusUnauthorized}}
+	}, 5*time.Minute)
+	v.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	info, err := v.Validate(ctx, "valid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Login != "octocat" || !info.HasScope("repo") {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	if _, err := v.Validate(ctx, "valid"); err != nil || calls != 1 {
+		t.Errorf("second validation should be cached: calls = %d, err = %v", calls, err)
+	}
+
+	// 接続先が異なる場合は別のトークンとして検証する
+	if _, err := v.Validate(WithGitHubHost(ctx, GitHubHost{APIURL: "https://ghe.example.com/api/v3/"}), "valid"); err != nil || calls != 2 {
+		t.Errorf("validation for another host should not be cached: calls = %d, err = %v", calls, err)
+	}
+
+	// 失敗した検証はキャッシュしない
+	for i := 0; i < 2; i++ {
+		if _, err := v.Validate(ctx, "invalid"); ErrorCode(err) != ErrorCodeAuthentication {
+			t.Errorf("expected authentication error, got %v", err)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("calls = %d, want 4", calls)
+	}
+
+	if _, err := v.Validate(ctx, "expired"); ErrorCode(err) != ErrorCodeAuthentication {
+		t.Errorf("expected authentication error for expired token, got %v", err)
+	}
+
+	// 有効期限がキャッシュの期間より早い場合は有効期限で再検証する
+	calls = 0
+	if _, err := v.Validate(ctx, "expiring"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := v.Validate(ctx, "expiring"); ErrorCode(err) != ErrorCodeAuthentication || calls != 2 {
+		t.Errorf("expired cache entry should be revalidated: calls = %d, err = %v", calls, err)
+	}
+
+	// キャッシュの期間が過ぎた場合は再検証する
+	calls = 0
+	now = now.Add(10 * time.Minute)
+	if _, err := v.Validate(ctx, "valid"); err != nil || calls != 1 {
+		t.Errorf("stale cache entry should be revalidated: calls = %d, err = %v", calls, err)
+	}
+
+	var authErr *GitHubAuthenticationError
+	if _, err := v.Validate(ctx, "invalid"); !errors.As(err, &authErr) {
+		t.Errorf("expected *GitHubAuthenticationError, got %T", err)
+	}
+}
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -47,27 +49,51 @@ type GitHubConflictError struct {
 	GitHubError
 }
 
+// ツールのエラー結果に含める機械判読可能なエラーコード
+const (
+	ErrorCodeInvalidArgument = "invalid_argument"
+	ErrorCodeValidation      = "validation_failed"
+	ErrorCodeNotFound        = "not_found"
+	ErrorCodeAuthentication  = "authentication_failed"
+	ErrorCodePermission      = "permission_denied"
+	ErrorCodeRateLimit       = "rate_limited"
+	ErrorCodeConflict        = "conflict"
+	ErrorCodeGitHub          = "github_error"
+	ErrorCodeCanceled        = "canceled"
+	ErrorCodeTimeout         = "timeout"
+	ErrorCodeInternal        = "internal_error"
+	ErrorCodeUnavailable     = "unavailable"
+)
+
+// findGitHubError はエラーチェーンを辿って最初に見つかったGitHubエラーを返します
+func findGitHubError(err error) error {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e.(type) {
+		case *GitHubError, *GitHubValidationError, *GitHubResourceNotFoundError,
+			*GitHubAuthenticationError, *GitHubPermissionError, *GitHubRateLimitError,
+			*GitHubConflictError:
+			return e
+		}
+	}
+	return nil
+}
+
 // IsGitHubError は指定されたエラーがGitHubエラーかどうかを判断します
+// fmt.Errorf の %w でラップされたエラーも対象になります
 func IsGitHubError(err error) bool {
-	switch err.(type) {
-	case *GitHubError, *GitHubValidationError, *GitHubResourceNotFoundError,
-		*GitHubAuthenticationError, *GitHubPermissionError, *GitHubRateLimitError,
-		*GitHubConflictError:
-		return true
-	default:
-		return false
-	}
+	return findGitHubError(err) != nil
 }
 
 // FormatGitHubError はGitHubエラーを人間が読みやすい形式にフォーマットします
 func FormatGitHubError(err error) string {
-	if !IsGitHubError(err) {
+	ghErr := findGitHubError(err)
+	if ghErr == nil {
 		return err.Error()
 	}
 
 	message := "GitHub API Error"
 
-	switch e := err.(type) {
+	switch e := ghErr.(type) {
 	case *GitHubValidationError:
 		message = fmt.Sprintf("Validation Error: %s", e.Message)
 		if e.Response != nil {
@@ -80,7 +106,10 @@ func FormatGitHubError(err error) string {
 	case *GitHubPermissionError:
 		message = fmt.Sprintf("Permission Denied: %s", e.Message)
 	case *GitHubRateLimitError:
-		message = fmt.Sprintf("Rate Limit Exceeded: %s\nResets at: %s", e.Message, e.ResetAt.Format(time.RFC3339))
+		message = fmt.Sprintf("Rate Limit Exceeded: %s", e.Message)
+		if !e.ResetAt.IsZero() {
+			message += fmt.Sprintf("\nResets at: %s", e.ResetAt.Format(time.RFC3339))
+		}
 	case *GitHubConflictError:
 		message = fmt.Sprintf("Conflict: %s", e.Message)
 	case *GitHubError:
@@ -89,3 +118,32 @@ func FormatGitHubError(err error) string {
 
 	return message
 }
+
+// ErrorCode はエラーに対応する機械判読可能なエラーコードを返します
+func ErrorCode(err error) string {
+	switch findGitHubError(err).(type) {
+	case *GitHubValidationError:
+		return ErrorCodeValidation
+	case *GitHubResourceNotFoundError:
+		return ErrorCodeNotFound
+	case *GitHubAuthenticationError:
+		return ErrorCodeAuthentication
+	case *GitHubPermissionError:
+		return ErrorCodePermission
+	case *GitHubRateLimitError:
+		return ErrorCodeRateLimit
+	case *GitHubConflictError:
+		return ErrorCodeConflict
+	case *GitHubError:
+		return ErrorCodeGitHub
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorCodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	default:
+		return ErrorCodeInternal
+	}
+}
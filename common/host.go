@@ -0,0 +1,194 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// GitHubHostHeader はSSEモードでリクエストごとにGitHub APIの接続先を指定するヘッダーです
+const GitHubHostHeader = "X-GitHub-Host"
+
+// GitHubUploadURLHeader はSSEモードでリクエストごとにアップロードURLを指定するヘッダーです
+const GitHubUploadURLHeader = "X-GitHub-Upload-URL"
+
+// GitHubHost はGitHub APIの接続先を表します
+// ゼロ値は github.com (api.github.com) を表します
+type GitHubHost struct {
+	// APIURL はREST APIのベースURLです (例: https://ghe.example.com/api/v3/)
+	APIURL string
+	// UploadURL はアップロードAPIのベースURLです (例: https://ghe.example.com/api/uploads/)
+	UploadURL string
+}
+
+// IsDefault は接続先が github.com かどうかを判断します
+func (h GitHubHost) IsDefault() bool {
+	return h.APIURL == ""
+}
+
+// String は接続先を表示用の文字列で返します
+func (h GitHubHost) String() string {
+	if h.IsDefault() {
+		return "api.github.com"
+	}
+	return h.APIURL
+}
+
+// githubHostKey はGitHub APIの接続先を保存するためのコンテキストキー
+type githubHostKey struct{}
+
+// ParseGitHubHost はホスト名またはURLからGitHub APIの接続先を作成します
+// "ghe.example.com"、"https://ghe.example.com"、"https://ghe.example.com/api/v3" のいずれの形式も受け付けます
+// uploadURL を省略した場合はAPIのホストから導出します
+func ParseGitHubHost(apiURL, uploadURL string) (GitHubHost, error) {
+	apiURL = strings.TrimSpace(apiURL)
+	uploadURL = strings.TrimSpace(uploadURL)
+	if apiURL == "" {
+		if uploadURL != "" {
+			return GitHubHost{}, fmt.Errorf("アップロードURLを指定する場合はAPIのURLも指定してください")
+		}
+		return GitHubHost{}, nil
+	}
+
+	api, err := parseHostURL(apiURL)
+	if err != nil {
+		return GitHubHost{}, fmt.Errorf("GitHub APIのURLが不正です: %v", err)
+	}
+	if api.Host == "github.com" || api.Host == "api.github.com" {
+		return GitHubHost{}, nil
+	}
+
+	var upload *url.URL
+	if uploadURL != "" {
+		if upload, err = parseHostURL(uploadURL); err != nil {
+			return GitHubHost{}, fmt.Errorf("アップロードURLが不正です: %v", err)
+		}
+	} else {
+		upload = &url.URL{Scheme: api.Scheme, Host: api.Host, Path: "/"}
+	}
+
+	// go-github の WithEnterpriseURLs と同じ規則でパスを補完する
+	api.Path = completeEnterprisePath(api, "api/v3/")
+	upload.Path = completeEnterprisePath(upload, "api/uploads/")
+
+	return GitHubHost{APIURL: api.String(), UploadURL: upload.String()}, nil
+}
+
+// completeEnterprisePath は go-github の WithEnterpriseURLs と同じ規則でAPIのパスを補完します
+func completeEnterprisePath(u *url.URL, suffix string) string {
+	path := u.Path
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	if !strings.HasSuffix(path, "/"+suffix) && !strings.HasPrefix(u.Host, "api.") && !strings.Contains(u.Host, ".api.") {
+		path += suffix
+	}
+	return path
+}
+
+// parseHostURL はスキームを省略したホスト名も受け付けてURLを解析します
+func parseHostURL(value string) (*url.URL, error) {
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q にホスト名がありません", value)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("%q のスキームはhttpまたはhttpsである必要があります", value)
+	}
+	return u, nil
+}
+
+// WithGitHubHost はコンテキストにGitHub APIの接続先を追加します
+func WithGitHubHost(ctx context.Context, host GitHubHost) context.Context {
+	return context.WithValue(ctx, githubHostKey{}, host)
+}
+
+// GitHubHostFromContext はコンテキストからGitHub APIの接続先を取得します
+// 2番目の戻り値は接続先がコンテキストに設定されていたかどうかを表します
+func GitHubHostFromContext(ctx context.Context) (GitHubHost, bool) {
+	host, ok := ctx.Value(githubHostKey{}).(GitHubHost)
+	return host, ok
+}
+
+// Hostnames はAPIとアップロードAPIのホスト名を返します (github.com の場合は空)
+func (h GitHubHost) Hostnames() []string {
+	var hosts []string
+	for _, raw := range []string{h.APIURL, h.UploadURL} {
+		if u, err := url.Parse(raw); err == nil && u.Host != "" {
+			hosts = append(hosts, strings.ToLower(u.Host))
+		}
+	}
+	return hosts
+}
+
+// HostAllowlist はX-GitHub-Hostヘッダーで指定できる接続先のホスト名の一覧です
+// ホスト名は "ghe.example.com" や "*.ghe.example.com" のようなglobで指定します
+type HostAllowlist []string
+
+// ParseHostAllowlist はカンマ区切りのホスト名の一覧を解析します
+func ParseHostAllowlist(value string) (HostAllowlist, error) {
+	var allowlist HostAllowlist
+	for _, item := range strings.Split(value, ",") {
+		pattern := strings.ToLower(strings.TrimSpace(item))
+		if pattern == "" {
+			continue
+		}
+		if strings.ContainsAny(pattern, "/?#@") {
+			return nil, fmt.Errorf("%q はホスト名ではありません (スキームやパスは指定できません)", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("ホスト名のパターン %q が不正です: %w", item, err)
+		}
+		allowlist = append(allowlist, pattern)
+	}
+	return allowlist, nil
+}
+
+// Allows はURLがhttpsで、そのホストが一覧に含まれているかどうかを判断します
+func (a HostAllowlist) Allows(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	for _, pattern := range a {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GitHubHostFromRequest はHTTPリクエストのヘッダーで指定されたGitHub APIの接続先をコンテキストに追加します
+// 呼び出し元が任意のホストにリクエストを送信させることを防ぐため、接続先はhttpsで
+// allowlist に含まれるホストに限られます
+// ヘッダーがない場合や不正な場合、許可されていない場合はコンテキストを変更せず、サーバーの既定の接続先が使用されます
+func GitHubHostFromRequest(ctx context.Context, r *http.Request, allowlist HostAllowlist) context.Context {
+	apiURL := r.Header.Get(GitHubHostHeader)
+	if apiURL == "" {
+		return ctx
+	}
+	host, err := ParseGitHubHost(apiURL, r.Header.Get(GitHubUploadURLHeader))
+	if err != nil {
+		log.Printf("%sヘッダーを無視します: %v", GitHubHostHeader, err)
+		return ctx
+	}
+	if host.IsDefault() {
+		return WithGitHubHost(ctx, host)
+	}
+	if !allowlist.Allows(host.APIURL) || !allowlist.Allows(host.UploadURL) {
+		log.Printf("%sヘッダーを無視します: %s は許可されていない接続先です", GitHubHostHeader, host)
+		return ctx
+	}
+	return WithGitHubHost(ctx, host)
+}
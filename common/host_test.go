@@ -0,0 +1,95 @@
+package common
+
+import "testing"
+
+func TestParseGitHubHost(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiURL        string
+		uploadURL     string
+		wantAPIURL    string
+		wantUploadURL string
+		wantErr       bool
+	}{
+		{name: "empty is github.com"},
+		{name: "github.com", apiURL: "github.com"},
+		{name: "api.github.com", apiURL: "https://api.github.com/"},
+		{
+			name:          "host name",
+			apiURL:        "ghe.example.com",
+			wantAPIURL:    "https://ghe.example.com/api/v3/",
+			wantUploadURL: "https://ghe.example.com/api/uploads/",
+		},
+		{
+			name:          "api url",
+			apiURL:        "https://ghe.example.com/api/v3",
+			wantAPIURL:    "https://ghe.example.com/api/v3/",
+			wantUploadURL: "https://ghe.example.com/api/uploads/",
+		},
+		{
+			name:          "explicit upload url",
+			apiURL:        "http://ghe.internal:8080",
+			uploadURL:     "http://uploads.ghe.internal:8080",
+			wantAPIURL:    "http://ghe.internal:8080/api/v3/",
+			wantUploadURL: "http://uploads.ghe.internal:8080/api/uploads/",
+		},
+		{
+			name:          "ghe.com api host",
+			apiURL:        "https://api.tenant.ghe.com",
+			wantAPIURL:    "https://api.tenant.ghe.com/",
+			wantUploadURL: "https://api.tenant.ghe.com/",
+		},
+		{name: "unsupported scheme", apiURL: "ftp://ghe.example.com", wantErr: true},
+		{name: "upload without api", uploadURL: "https://ghe.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, err := ParseGitHubHost(tt.apiURL, tt.uploadURL)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", host)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if host.APIURL != tt.wantAPIURL || host.UploadURL != tt.wantUploadURL {
+				t.Errorf("got %+v, want api=%q upload=%q", host, tt.wantAPIURL, tt.wantUploadURL)
+			}
+		})
+	}
+}
+
+func TestHostAllowlist(t *testing.T) {
+	allowlist, err := ParseHostAllowlist("GHE.example.com, *.ghe.com,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://ghe.example.com/api/v3/", want: true},
+		{url: "https://api.tenant.ghe.com/", want: true},
+		{url: "http://ghe.example.com/api/v3/", want: false},
+		{url: "https://ghe.example.com:8443/api/v3/", want: false},
+		{url: "https://attacker.example.com/", want: false},
+	}
+	for _, tt := range tests {
+		if got := allowlist.Allows(tt.url); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	if HostAllowlist(nil).Allows("https://ghe.example.com/") {
+		t.Error("empty allowlist should not allow any host")
+	}
+	for _, value := range []string{"https://ghe.example.com", "ghe.example.com/api", "[invalid"} {
+		if _, err := ParseHostAllowlist(value); err == nil {
+			t.Errorf("ParseHostAllowlist(%q): expected error", value)
+		}
+	}
+}
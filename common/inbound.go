@@ -0,0 +1,414 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 呼び出し元の認証方式
+const (
+	IdentityMethodAPIKey = "api_key"
+	IdentityMethodJWT    = "jwt"
+)
+
+// jwtClockSkew はJWTの有効期限と開始時刻の確認で許容する時刻のずれです
+const jwtClockSkew = time.Minute
+
+// Identity はSSE/HTTPモードで認証された呼び出し元を表します
+type Identity struct {
+	// Subject は呼び出し元の識別子です (APIキーの名前またはJWTのsubクレーム)
+	Subject string
+	// Method は認証方式です (IdentityMethodAPIKey または IdentityMethodJWT)
+	Method string
+	// Claims はJWTのクレームです (APIキーの場合はnil)
+	Claims map[string]interface{}
+
+	// githubToken は呼び出し元に割り当てられたGitHubトークンです (空の場合はサーバーのトークン)
+	githubToken string
+}
+
+// identityKey は認証された呼び出し元を保存するためのコンテキストキー
+type identityKey struct{}
+
+// WithIdentity はコンテキストに認証された呼び出し元を追加します
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext はコンテキストから認証された呼び出し元を取得します
+// 呼び出し元が認証されていない場合はnilを返します
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// AuthTokenFromIdentity は認証された呼び出し元に割り当てられたGitHubトークンをコンテキストに追加します
+// GitHubトークンが割り当てられていない場合は環境変数GITHUB_TOKENのトークンを使用します
+// ただし、X-GitHub-Hostヘッダーで接続先が指定されている場合はサーバーのトークンを使用しません
+func AuthTokenFromIdentity(ctx context.Context, r *http.Request, identity *Identity) context.Context {
+	if identity.githubToken != "" {
+		return WithAuthToken(ctx, identity.githubToken)
+	}
+	if r.Header.Get(GitHubHostHeader) != "" {
+		return context.WithValue(WithAuthToken(ctx, ""), authErrorKey{}, &GitHubAuthenticationError{
+			GitHubError: GitHubError{
+				Message: fmt.Sprintf("%s にGitHubトークンが割り当てられていないため、%sヘッダーで接続先を指定できません", identity.Subject, GitHubHostHeader),
+				Status:  http.StatusUnauthorized,
+			},
+		})
+	}
+	return AuthTokenFromEnv(ctx)
+}
+
+// InboundAuthConfig はSSE/HTTPモードで呼び出し元を認証する設定を表します
+//
+// 呼び出し元はAuthorizationヘッダーでAPIキーまたはJWTを送信します。
+// GitHubトークンは設定ファイルに書かずに環境変数の名前で指定し、
+// 指定しない場合はサーバーの環境変数GITHUB_TOKEN (またはGitHub App) を使用します。
+type InboundAuthConfig struct {
+	APIKeys []APIKeyConfig `json:"api_keys" yaml:"api_keys"`
+	JWT     *JWTConfig     `json:"jwt" yaml:"jwt"`
+}
+
+// APIKeyConfig はAPIキーで認証する呼び出し元を表します
+type APIKeyConfig struct {
+	// Name は呼び出し元の名前で、Identity.Subject になります
+	Name string `json:"name" yaml:"name"`
+	// KeySHA256 はAPIキーのSHA-256ハッシュ (16進数) です
+	KeySHA256 string `json:"key_sha256" yaml:"key_sha256"`
+	// GitHubTokenEnv はこの呼び出し元が使用するGitHubトークンを保持する環境変数の名前です
+	GitHubTokenEnv string `json:"github_token_env" yaml:"github_token_env"`
+}
+
+// JWTConfig はJWT (OIDCのIDトークンなど) で認証する設定を表します
+type JWTConfig struct {
+	// JWKSFile は署名の検証に使用するJWKSファイルのパスです
+	JWKSFile string `json:"jwks_file" yaml:"jwks_file"`
+	// Issuer を指定した場合はissクレームが一致する必要があります
+	Issuer string `json:"issuer" yaml:"issuer"`
+	// Audience を指定した場合はaudクレームに含まれている必要があります
+	Audience string `json:"audience" yaml:"audience"`
+	// SubjectClaim は呼び出し元の識別子として使用するクレームです (省略時は sub)
+	SubjectClaim string `json:"subject_claim" yaml:"subject_claim"`
+	// GitHubTokenEnv はすべての呼び出し元が使用するGitHubトークンを保持する環境変数の名前です
+	GitHubTokenEnv string `json:"github_token_env" yaml:"github_token_env"`
+	// GitHubTokenEnvs は呼び出し元の識別子ごとのGitHubトークンの環境変数の名前で、GitHubTokenEnvより優先されます
+	GitHubTokenEnvs map[string]string `json:"github_token_envs" yaml:"github_token_envs"`
+}
+
+// InboundAuth はSSE/HTTPモードのリクエストの呼び出し元を認証します
+type InboundAuth struct {
+	apiKeys map[string]*Identity
+	jwt     *jwtVerifier
+	now     func() time.Time
+}
+
+// jwtVerifier はJWTの署名とクレームを検証します
+type jwtVerifier struct {
+	keys         []jsonWebKey
+	issuer       string
+	audience     string
+	subjectClaim string
+	defaultToken string
+	tokens       map[string]string
+}
+
+// jsonWebKey はJWKSに含まれる公開鍵を表します
+type jsonWebKey struct {
+	kid string
+	key crypto.PublicKey
+}
+
+// LoadInboundAuth はYAMLまたはJSON形式の設定ファイルを読み込みます
+func LoadInboundAuth(filename string) (*InboundAuth, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("認証設定ファイルを読み込めません: %w", err)
+	}
+	var config InboundAuthConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("認証設定ファイルを解析できません: %w", err)
+	}
+	return NewInboundAuth(config)
+}
+
+// NewInboundAuth は設定からInboundAuthを作成します
+// GitHubトークンの環境変数とJWKSファイルは作成時に読み込みます
+func NewInboundAuth(config InboundAuthConfig) (*InboundAuth, error) {
+	if len(config.APIKeys) == 0 && config.JWT == nil {
+		return nil, errors.New("api_keys または jwt のいずれかを指定してください")
+	}
+
+	a := &InboundAuth{apiKeys: make(map[string]*Identity), now: time.Now}
+	for _, key := range config.APIKeys {
+		if key.Name == "" {
+			return nil, errors.New("APIキーの name を指定してください")
+		}
+		hash, err := hex.DecodeString(key.KeySHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("APIキー %s の key_sha256 はSHA-256ハッシュの16進数で指定してください", key.Name)
+		}
+		token, err := githubTokenFromEnv(key.GitHubTokenEnv)
+		if err != nil {
+			return nil, fmt.Errorf("APIキー %s: %w", key.Name, err)
+		}
+		a.apiKeys[string(hash)] = &Identity{Subject: key.Name, Method: IdentityMethodAPIKey, githubToken: token}
+	}
+
+	if config.JWT != nil {
+		verifier, err := newJWTVerifier(*config.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = verifier
+	}
+	return a, nil
+}
+
+// githubTokenFromEnv は環境変数からGitHubトークンを読み込みます
+// 名前が空の場合は空文字列を返し、サーバーのトークンを使用させます
+func githubTokenFromEnv(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	token := os.Getenv(name)
+	if token == "" {
+		return "", fmt.Errorf("環境変数 %s にGitHubトークンが設定されていません", name)
+	}
+	return token, nil
+}
+
+// newJWTVerifier はJWTの検証設定を作成します
+func newJWTVerifier(config JWTConfig) (*jwtVerifier, error) {
+	if config.JWKSFile == "" {
+		return nil, errors.New("jwt の jwks_file を指定してください")
+	}
+	data, err := os.ReadFile(config.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("JWKSファイルを読み込めません: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("JWKSファイルを解析できません: %w", err)
+	}
+
+	v := &jwtVerifier{
+		keys:         keys,
+		issuer:       config.Issuer,
+		audience:     config.Audience,
+		subjectClaim: config.SubjectClaim,
+		tokens:       make(map[string]string),
+	}
+	if v.subjectClaim == "" {
+		v.subjectClaim = "sub"
+	}
+	if v.defaultToken, err = githubTokenFromEnv(config.GitHubTokenEnv); err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+	for subject, env := range config.GitHubTokenEnvs {
+		token, err := githubTokenFromEnv(env)
+		if err != nil {
+			return nil, fmt.Errorf("jwt (%s): %w", subject, err)
+		}
+		v.tokens[subject] = token
+	}
+	return v, nil
+}
+
+// parseJWKS はJWKSからRSA鍵とP-256のEC鍵を読み込みます
+func parseJWKS(data []byte) ([]jsonWebKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+
+	var keys []jsonWebKey
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("RSA鍵 %q が不正です", k.Kid)
+			}
+			exponent := 0
+			for _, b := range e {
+				exponent = exponent<<8 | int(b)
+			}
+			keys = append(keys, jsonWebKey{kid: k.Kid, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}})
+		case "EC":
+			if k.Crv != "P-256" {
+				return nil, fmt.Errorf("EC鍵 %q の曲線 %s には対応していません", k.Kid, k.Crv)
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("EC鍵 %q が不正です", k.Kid)
+			}
+			keys = append(keys, jsonWebKey{kid: k.Kid, key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("署名に使用できる鍵がありません")
+	}
+	return keys, nil
+}
+
+// Authenticate はリクエストのAuthorizationヘッダーから呼び出し元を認証します
+// APIキーに一致しない場合は、JWTの検証が設定されていればJWTとして検証します
+func (a *InboundAuth) Authenticate(r *http.Request) (*Identity, error) {
+	credential, err := ParseAuthorization(r.Header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+	if credential == "" {
+		return nil, inboundAuthError("Authorizationヘッダーで認証情報を指定してください")
+	}
+
+	hash := sha256.Sum256([]byte(credential))
+	for key, identity := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(key), hash[:]) == 1 {
+			return identity, nil
+		}
+	}
+
+	if a.jwt != nil && strings.Count(credential, ".") == 2 {
+		return a.jwt.verify(credential, a.now())
+	}
+	return nil, inboundAuthError("認証情報が無効です")
+}
+
+// inboundAuthError は呼び出し元の認証エラーを作成します
+func inboundAuthError(message string) error {
+	return &GitHubAuthenticationError{
+		GitHubError: GitHubError{Message: message, Status: http.StatusUnauthorized},
+	}
+}
+
+// verify はJWTの署名とクレームを検証し、呼び出し元を返します
+// 署名アルゴリズムはRS256とES256のみを受け付けます
+func (v *jwtVerifier) verify(token string, now time.Time) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, inboundAuthError("JWTのヘッダーを解析できません")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, inboundAuthError("JWTの署名を解析できません")
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	verified := false
+	for _, key := range v.keys {
+		if header.Kid != "" && key.kid != header.Kid {
+			continue
+		}
+		switch pub := key.key.(type) {
+		case *rsa.PublicKey:
+			verified = header.Alg == "RS256" && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+		case *ecdsa.PublicKey:
+			verified = header.Alg == "ES256" && len(signature) == 64 &&
+				ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:]))
+		}
+		if verified {
+			break
+		}
+	}
+	if !verified {
+		return nil, inboundAuthError("JWTの署名を検証できません")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, inboundAuthError("JWTのクレームを解析できません")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, inboundAuthError("JWTに有効期限 (exp) がありません")
+	}
+	if now.Add(-jwtClockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, inboundAuthError("JWTの有効期限が切れています")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, inboundAuthError("JWTはまだ有効ではありません")
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return nil, inboundAuthError("JWTの発行者 (iss) が一致しません")
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return nil, inboundAuthError("JWTの対象者 (aud) が一致しません")
+	}
+	subject, _ := claims[v.subjectClaim].(string)
+	if subject == "" {
+		return nil, inboundAuthError(fmt.Sprintf("JWTに呼び出し元の識別子 (%s) がありません", v.subjectClaim))
+	}
+
+	githubToken, ok := v.tokens[subject]
+	if !ok {
+		githubToken = v.defaultToken
+	}
+	return &Identity{Subject: subject, Method: IdentityMethodJWT, Claims: claims, githubToken: githubToken}, nil
+}
+
+// decodeJWTPart はbase64urlでエンコードされたJWTのヘッダーまたはクレームをデコードします
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// hasAudience はaudクレーム (文字列または文字列の配列) に指定した対象者が含まれているかどうかを判断します
+func hasAudience(aud interface{}, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []interface{}:
+		for _, item := range v {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// signJWT はテスト用のJWTを作成します (RSA鍵はRS256、EC鍵はES256で署名)
+func signJWT(t *testing.T, key crypto.Signer, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// writeJWKS は公開鍵をJWKSファイルに書き込みます
+func writeJWKS(t *testing.T, rsaKey *rsa.PublicKey, ecKey *ecdsa.PublicKey) string {
+	t.Helper()
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+		},
+	}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write jwks: %v", err)
+	}
+	return path
+}
+
+func TestInboundAuth(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	t.Setenv("CI_BOT_TOKEN", "ci-bot-github-token")
+	t.Setenv("ALICE_TOKEN", "alice-github-token")
+
+	hash := sha256.Sum256([]byte("secret-key"))
+	auth, err := NewInboundAuth(InboundAuthConfig{
+		APIKeys: []APIKeyConfig{{Name: "ci-bot", KeySHA256: hex.EncodeToString(hash[:]), GitHubTokenEnv: "CI_BOT_TOKEN"}},
+		JWT: &JWTConfig{
+			JWKSFile:        writeJWKS(t, &rsaKey.PublicKey, &ecKey.PublicKey),
+			Issuer:          "https://issuer.example.com",
+			Audience:        "github-mcp",
+			GitHubTokenEnvs: map[string]string{"alice": "ALICE_TOKEN"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": "https://issuer.example.com",
+			"aud": []string{"other", "github-mcp"},
+			"sub": "alice",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		header      string
+		wantSubject string
+		wantMethod  string
+		wantToken   string
+		wantErr     bool
+	}{
+		{name: "api key", header: "Bearer secret-key", wantSubject: "ci-bot", wantMethod: IdentityMethodAPIKey, wantToken: "ci-bot-github-token"},
+		{name: "unknown api key", header: "Bearer wrong-key", wantErr: true},
+		{name: "missing header", wantErr: true},
+		{name: "rs256 jwt", header: "Bearer " + signJWT(t, rsaKey, "rsa-1", claims(nil)), wantSubject: "alice", wantMethod: IdentityMethodJWT, wantToken: "alice-github-token"},
+		{name: "es256 jwt without mapped token", header: "Bearer " + signJWT(t, ecKey, "ec-1", claims(map[string]interface{}{"sub": "bob"})), wantSubject: "bob", wantMethod: IdentityMethodJWT},
+		{name: "expired jwt", header: "Bearer " + signJWT(t, rsaKey, "rsa-1", claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})), wantErr: true},
+		{name: "jwt without exp", header: "Bearer " + signJWT(t, rsaKey, "rsa-1", claims(map[string]interface{}{"exp": nil})), wantErr: true},
+		{name: "jwt not yet valid", header: "Bearer " + signJWT(t, rsaKey, "rsa-1", claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})), wantErr: true},
+		{name: "wrong issuer", header: "Bearer " + signJWT(t, rsaKey, "rsa-1", claims(map[string]interface{}{"iss": "https://evil.example.com"})), wantErr: true},
+		{name: "wrong audience", header: "Bearer " + signJWT(t, rsaKey, "rsa-1", claims(map[string]interface{}{"aud": "other"})), wantErr: true},
+		{name: "key id mismatch", header: "Bearer " + signJWT(t, rsaKey, "ec-1", claims(nil)), wantErr: true},
+		{name: "unsigned jwt", header: "Bearer eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9.", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/sse", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			identity, err := auth.Authenticate(r)
+			if tt.wantErr {
+				if ErrorCode(err) != ErrorCodeAuthentication {
+					t.Errorf("expected authentication error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if identity.Subject != tt.wantSubject || identity.Method != tt.wantMethod || identity.githubToken != tt.wantToken {
+				t.Errorf("identity = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestNewInboundAuthErrors(t *testing.T) {
+	hash := sha256.Sum256([]byte("key"))
+	tests := []struct {
+		name   string
+		config InboundAuthConfig
+	}{
+		{name: "empty"},
+		{name: "missing name", config: InboundAuthConfig{APIKeys: []APIKeyConfig{{KeySHA256: hex.EncodeToString(hash[:])}}}},
+		{name: "plain key", config: InboundAuthConfig{APIKeys: []APIKeyConfig{{Name: "a", KeySHA256: "secret"}}}},
+		{name: "unset token env", config: InboundAuthConfig{APIKeys: []APIKeyConfig{{Name: "a", KeySHA256: hex.EncodeToString(hash[:]), GitHubTokenEnv: "UNSET_GITHUB_TOKEN_FOR_TEST"}}}},
+		{name: "missing jwks", config: InboundAuthConfig{JWT: &JWTConfig{JWKSFile: filepath.Join(t.TempDir(), "missing.json")}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewInboundAuth(tt.config); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Access はリポジトリに対するアクセス権限を表します
+type Access int
+
+const (
+	// AccessNone はアクセスが禁止されていることを表します
+	AccessNone Access = iota
+	// AccessRead は読み取りのみ許可されていることを表します
+	AccessRead
+	// AccessWrite は読み取りと書き込みが許可されていることを表します
+	AccessWrite
+)
+
+// String はアクセス権限を表示用の文字列で返します
+func (a Access) String() string {
+	switch a {
+	case AccessRead:
+		return "read"
+	case AccessWrite:
+		return "write"
+	default:
+		return "none"
+	}
+}
+
+// Policy はツールが操作できるリポジトリを制限するポリシーを表します
+//
+// パターンは "owner/repo" 形式のglobで、"owner" のみを指定した場合は "owner/*" として扱います。
+// Forbidden に一致するリポジトリへのアクセスはすべて拒否されます。
+// Writable に一致するリポジトリは読み取りと書き込み、Readable に一致するリポジトリは読み取りのみ許可されます。
+// いずれにも一致しないリポジトリへのアクセスは拒否されます。
+// nilのPolicyはすべてのアクセスを許可します。
+type Policy struct {
+	Readable  []string `json:"readable" yaml:"readable"`
+	Writable  []string `json:"writable" yaml:"writable"`
+	Forbidden []string `json:"forbidden" yaml:"forbidden"`
+}
+
+// policyKey はポリシーを保存するためのコンテキストキー
+type policyKey struct{}
+
+// LoadPolicy はYAMLまたはJSON形式のポリシーファイルを読み込みます
+func LoadPolicy(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("ポリシーファイルを読み込めません: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy はYAMLまたはJSON形式のポリシーを解析します
+// JSONはYAMLのサブセットであるため、どちらの形式もYAMLとして解析します
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("ポリシーファイルを解析できません: %w", err)
+	}
+
+	for _, patterns := range [][]string{policy.Readable, policy.Writable, policy.Forbidden} {
+		for i, pattern := range patterns {
+			normalized := normalizePattern(pattern)
+			if _, err := path.Match(normalized, ""); err != nil {
+				return nil, fmt.Errorf("ポリシーのパターン %q が不正です: %w", pattern, err)
+			}
+			patterns[i] = normalized
+		}
+	}
+	return &policy, nil
+}
+
+// normalizePattern はパターンを小文字の "owner/repo" 形式に変換します
+func normalizePattern(pattern string) string {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if !strings.Contains(pattern, "/") {
+		pattern += "/*"
+	}
+	return pattern
+}
+
+// matchAny はリポジトリ名がいずれかのパターンに一致するかどうかを判断します
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Access は指定されたリポジトリに対して許可されているアクセス権限を返します
+func (p *Policy) Access(owner, repo string) Access {
+	if p == nil {
+		return AccessWrite
+	}
+	name := strings.ToLower(owner + "/" + repo)
+	switch {
+	case matchAny(p.Forbidden, name):
+		return AccessNone
+	case matchAny(p.Writable, name):
+		return AccessWrite
+	case matchAny(p.Readable, name):
+		return AccessRead
+	default:
+		return AccessNone
+	}
+}
+
+// Check は指定されたリポジトリに必要なアクセス権限があるかどうかを確認します
+// 権限がない場合は GitHubPermissionError を返します
+func (p *Policy) Check(owner, repo string, required Access) error {
+	if allowed := p.Access(owner, repo); allowed < required {
+		return &GitHubPermissionError{
+			GitHubError: GitHubError{
+				Message: fmt.Sprintf("ポリシーにより %s/%s への%sアクセスは許可されていません", owner, repo, required),
+				Status:  http.StatusForbidden,
+			},
+		}
+	}
+	return nil
+}
+
+// WithPolicy はコンテキストにポリシーを追加します
+func WithPolicy(ctx context.Context, policy *Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+// PolicyFromContext はコンテキストからポリシーを取得します
+// ポリシーが設定されていない場合はnil (すべて許可) を返します
+func PolicyFromContext(ctx context.Context) *Policy {
+	policy, _ := ctx.Value(policyKey{}).(*Policy)
+	return policy
+}
@@ -0,0 +1,67 @@
+package common
+
+import "testing"
+
+func TestPolicyAccess(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+readable:
+  - octocat
+  - "other/*-docs"
+writable:
+  - octocat/hello*
+forbidden:
+  - octocat/hello-secret
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		owner, repo string
+		want        Access
+	}{
+		{"octocat", "hello", AccessWrite},
+		{"Octocat", "Hello-Go", AccessWrite},
+		{"octocat", "hello-secret", AccessNone},
+		{"octocat", "linguist", AccessRead},
+		{"other", "api-docs", AccessRead},
+		{"other", "api", AccessNone},
+		{"someone", "hello", AccessNone},
+	}
+	for _, tt := range tests {
+		if got := policy.Access(tt.owner, tt.repo); got != tt.want {
+			t.Errorf("Access(%s/%s) = %s, want %s", tt.owner, tt.repo, got, tt.want)
+		}
+	}
+
+	err = policy.Check("octocat", "linguist", AccessWrite)
+	if ErrorCode(err) != ErrorCodePermission {
+		t.Errorf("Check returned %v, want permission error", err)
+	}
+
+	var nilPolicy *Policy
+	if got := nilPolicy.Access("anyone", "anything"); got != AccessWrite {
+		t.Errorf("nil policy access = %s, want write", got)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "json", data: `{"readable": ["octocat/*"], "forbidden": ["octocat/secret"]}`},
+		{name: "empty"},
+		{name: "unknown field", data: `writeable: [octocat]`, wantErr: true},
+		{name: "invalid pattern", data: `readable: ["octocat/["]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePolicy error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
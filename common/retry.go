@@ -0,0 +1,24 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+// RetryNotifyFunc はGitHub APIへのリクエストを再試行する前に呼び出されます
+// attempt は何回目の再試行か、wait は再試行までの待ち時間、reason は再試行する理由を表します
+type RetryNotifyFunc func(attempt int, wait time.Duration, reason string)
+
+// retryNotifyKey は再試行の通知先を保存するためのコンテキストキー
+type retryNotifyKey struct{}
+
+// WithRetryNotify はコンテキストに再試行の通知先を追加します
+func WithRetryNotify(ctx context.Context, notify RetryNotifyFunc) context.Context {
+	return context.WithValue(ctx, retryNotifyKey{}, notify)
+}
+
+// RetryNotifyFromContext はコンテキストから再試行の通知先を取得します (設定されていない場合はnil)
+func RetryNotifyFromContext(ctx context.Context) RetryNotifyFunc {
+	notify, _ := ctx.Value(retryNotifyKey{}).(RetryNotifyFunc)
+	return notify
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// httpEndpoint はStreamable HTTPのリクエストを受け付けるパスです
+const httpEndpoint = "/mcp"
+
+// httpRouter はツールの選択条件ごとにStreamable HTTPサーバーを振り分けるHTTPハンドラーです
+//
+// Streamable HTTPではリクエストごとにヘッダーが送信されるため、SSEと異なり
+// 選択条件ごとのパスは不要で、各リクエストのヘッダーから選択条件を決定します。
+// セッションIDはサーバー間で状態を共有しない形式のため、ロードバランサーの背後でも動作します。
+type httpRouter struct {
+	base *GitHubMCPServer
+
+	mu      sync.Mutex
+	servers map[string]*server.StreamableHTTPServer
+}
+
+// newHTTPRouter は新しいhttpRouterを作成します
+func newHTTPRouter(base *GitHubMCPServer) *httpRouter {
+	r := &httpRouter{
+		base:    base,
+		servers: make(map[string]*server.StreamableHTTPServer),
+	}
+	r.servers[base.opts.toolConfig().key()] = newStreamableHTTPServer(base)
+	return r
+}
+
+// newStreamableHTTPServer はMCPサーバーをStreamable HTTPで公開するサーバーを作成します
+func newStreamableHTTPServer(s *GitHubMCPServer) *server.StreamableHTTPServer {
+	return server.NewStreamableHTTPServer(s.server,
+		server.WithEndpointPath(httpEndpoint),
+		server.WithHTTPContextFunc(s.requestContext),
+	)
+}
+
+// serverFor は選択条件に対応するStreamable HTTPサーバーを返します
+// 初めて使用される選択条件の場合はサーバーを作成します
+func (r *httpRouter) serverFor(config toolConfig) *server.StreamableHTTPServer {
+	key := config.key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if srv, ok := r.servers[key]; ok {
+		return srv
+	}
+
+	srv := newStreamableHTTPServer(r.base.withToolConfig(config))
+	r.servers[key] = srv
+	return srv
+}
+
+// ServeHTTP はリクエストをヘッダーの選択条件に対応するサーバーに振り分けます
+func (r *httpRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != httpEndpoint {
+		http.NotFound(w, req)
+		return
+	}
+	config, err := toolConfigFromRequest(r.base.opts.toolConfig(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.serverFor(config).ServeHTTP(w, req)
+}
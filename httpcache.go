@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// HTTPキャッシュの保存先の種類
+const (
+	httpCacheMemory = "memory"
+	httpCacheDisk   = "disk"
+	httpCacheOff    = "off"
+)
+
+// newClientCache はHTTPキャッシュの設定からGitHubクライアントのキャッシュを作成します
+// キャッシュはレート制限と一時的な障害の再試行より外側で、条件付きリクエストを送信します
+func newClientCache(mode, dir string, sizeMB int64) (*operations.ClientCache, error) {
+	if sizeMB < 0 {
+		return nil, fmt.Errorf("キャッシュのサイズには0以上を指定してください: %d", sizeMB)
+	}
+	var backend operations.CacheBackend
+	switch mode {
+	case httpCacheMemory:
+		backend = operations.NewMemoryCache(sizeMB << 20)
+	case httpCacheDisk:
+		if dir == "" {
+			cacheDir, err := os.UserCacheDir()
+			if err != nil {
+				return nil, fmt.Errorf("キャッシュディレクトリを決定できません。--http-cache-dir を指定してください: %v", err)
+			}
+			dir = filepath.Join(cacheDir, "github-mcp-server", "http")
+		}
+		disk, err := operations.NewDiskCache(dir, sizeMB<<20)
+		if err != nil {
+			return nil, err
+		}
+		backend = disk
+	case httpCacheOff:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("不明なキャッシュの種類: %s (memory, disk または off を指定してください)", mode)
+	}
+
+	transport := operations.NewCachingTransport(operations.NewRetryTransport(operations.NewTransport()), backend)
+	return operations.NewClientCache(operations.NewClientFactory(transport), operations.DefaultClientCacheSize, operations.DefaultClientCacheTTL), nil
+}
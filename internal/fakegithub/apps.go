@@ -0,0 +1,188 @@
+package fakegithub
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAppTokenLifetime はインストールトークンの既定の有効期間です (GitHubと同じ1時間)
+const DefaultAppTokenLifetime = time.Hour
+
+// app はGitHub Appの登録情報とインストールを表します
+type app struct {
+	id        int64
+	publicKey *rsa.PublicKey
+	// installations はオーナー名 (小文字) からインストールIDへの対応です
+	installations map[string]int64
+	// tokens は発行済みのインストールトークンの有効期限です
+	tokens map[string]time.Time
+}
+
+// EnableApp はGitHub Appとしての認証を有効にします
+// JWTはpublicKeyで検証され、発行者がappIDと一致する必要があります
+func (s *Server) EnableApp(appID int64, publicKey *rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.app = &app{
+		id:            appID,
+		publicKey:     publicKey,
+		installations: make(map[string]int64),
+		tokens:        make(map[string]time.Time),
+	}
+}
+
+// AddInstallation はオーナーにGitHub Appをインストールし、インストールIDを返します
+func (s *Server) AddInstallation(owner string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.newID()
+	s.app.installations[strings.ToLower(owner)] = id
+	return id
+}
+
+// Authorizations はこれまでに受け付けたリクエストのAuthorizationヘッダーを返します
+func (s *Server) Authorizations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.auths...)
+}
+
+// checkInstallationToken はインストールトークンが発行済みで有効期限内かどうかを確認します
+// インストールトークン以外のトークンは確認しません (ロック取得済みで呼び出すこと)
+func (s *Server) checkInstallationToken(r *http.Request) bool {
+	token := bearerToken(r)
+	if s.app == nil || !strings.HasPrefix(token, "ghs_") {
+		return true
+	}
+	expiresAt, ok := s.app.tokens[token]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// bearerToken はAuthorizationヘッダーからトークンを取り出します
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if _, token, ok := strings.Cut(header, " "); ok {
+		return token
+	}
+	return header
+}
+
+// verifyJWT はGitHub AppのJWTを検証し、失敗した場合は401を書き込みます
+func (s *Server) verifyJWT(w http.ResponseWriter, r *http.Request) (*app, bool) {
+	s.mu.Lock()
+	a := s.app
+	s.mu.Unlock()
+	if a == nil {
+		writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return nil, false
+	}
+
+	parts := strings.Split(bearerToken(r), ".")
+	if len(parts) != 3 {
+		writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return nil, false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return nil, false
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(a.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return nil, false
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		writeError(w, http.StatusUnauthorized, "A JSON web token could not be decoded")
+		return nil, false
+	}
+	if claims.Iss != strconv.FormatInt(a.id, 10) {
+		writeError(w, http.StatusUnauthorized, fmt.Sprintf("Integration not found for iss %q", claims.Iss))
+		return nil, false
+	}
+	now := time.Now().Unix()
+	if claims.Exp <= now || claims.Exp-claims.Iat > int64((10*time.Minute).Seconds()) {
+		writeError(w, http.StatusUnauthorized, "'Expiration time' claim ('exp') is too far in the future or has expired")
+		return nil, false
+	}
+	return a, true
+}
+
+// handleGetInstallation は GET /repos/{owner}/{repo}/installation、/orgs/{org}/installation、
+// /users/{user}/installation を処理します
+// このサーバーではオーナー単位でインストールを管理するため、リポジトリはオーナーのインストールを返します
+func (s *Server) handleGetInstallation(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.verifyJWT(w, r)
+	if !ok {
+		return
+	}
+	owner := r.PathValue("owner")
+	if owner == "" {
+		owner = r.PathValue("org") + r.PathValue("user")
+	}
+
+	s.mu.Lock()
+	id, ok := a.installations[strings.ToLower(owner)]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"app_id":  a.id,
+		"account": map[string]interface{}{"login": owner},
+	})
+}
+
+// handleCreateInstallationToken は POST /app/installations/{id}/access_tokens を処理します
+func (s *Server) handleCreateInstallationToken(w http.ResponseWriter, r *http.Request) {
+	a, ok := s.verifyJWT(w, r)
+	if !ok {
+		return
+	}
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	for _, installationID := range a.installations {
+		found = found || installationID == id
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	lifetime := s.AppTokenLifetime
+	if lifetime <= 0 {
+		lifetime = DefaultAppTokenLifetime
+	}
+	token := fmt.Sprintf("ghs_%d_%d", id, s.newID())
+	// トークンの有効期限はクライアントが実時間で判断するため、フェイクの時計ではなく現在時刻を使用する
+	expiresAt := time.Now().Add(lifetime).UTC().Truncate(time.Second)
+	a.tokens[token] = expiresAt
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      token,
+		"expires_at": expiresAt.Format(time.RFC3339),
+	})
+}
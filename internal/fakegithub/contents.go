@@ -0,0 +1,174 @@
+package fakegithub
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxContentSize はContents APIが内容を返すファイルサイズの上限です
+const maxContentSize = 1024 * 1024
+
+// contentJSON はContents APIのファイルエントリを作成します
+func contentJSON(r *http.Request, repo *repository, ref, filePath string, e treeEntry, withContent bool) map[string]interface{} {
+	content := repo.blobs[e.sha]
+	kind := "file"
+	if e.mode == "120000" {
+		kind = "symlink"
+	}
+	result := map[string]interface{}{
+		"type":         kind,
+		"size":         len(content),
+		"name":         path.Base(filePath),
+		"path":         filePath,
+		"sha":          e.sha,
+		"url":          fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", baseURL(r), repo.fullName(), filePath, ref),
+		"html_url":     fmt.Sprintf("%s/%s/blob/%s/%s", webURL(r), repo.fullName(), ref, filePath),
+		"git_url":      fmt.Sprintf("%s/repos/%s/git/blobs/%s", baseURL(r), repo.fullName(), e.sha),
+		"download_url": fmt.Sprintf("%s/raw/%s/%s/%s", baseURL(r), repo.fullName(), ref, filePath),
+	}
+	if kind == "symlink" {
+		result["target"] = string(content)
+	}
+	if withContent {
+		if len(content) > maxContentSize {
+			result["encoding"] = "none"
+			result["content"] = ""
+		} else {
+			result["encoding"] = "base64"
+			result["content"] = base64.StdEncoding.EncodeToString(content)
+		}
+	}
+	return result
+}
+
+// handleGetContents は GET /repos/{owner}/{repo}/contents/{path...} を処理します
+func (s *Server) handleGetContents(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		ref = repo.defaultBranch
+	}
+	t, ok := repo.treeAt(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No commit found for the ref %s", ref))
+		return
+	}
+
+	filePath := strings.Trim(r.PathValue("path"), "/")
+	if e, ok := t[filePath]; ok {
+		writeJSON(w, http.StatusOK, contentJSON(r, repo, ref, filePath, e, true))
+		return
+	}
+
+	// ディレクトリの場合は直下のエントリ一覧を返す
+	prefix := ""
+	if filePath != "" {
+		prefix = filePath + "/"
+	}
+	children := map[string]bool{}
+	for p := range t {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(p, prefix)
+		name, _, isDir := strings.Cut(rest, "/")
+		children[name] = children[name] || isDir
+	}
+	if len(children) == 0 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		childPath := prefix + name
+		if !children[name] {
+			entries = append(entries, contentJSON(r, repo, ref, childPath, t[childPath], false))
+			continue
+		}
+		entries = append(entries, map[string]interface{}{
+			"type":     "dir",
+			"size":     0,
+			"name":     name,
+			"path":     childPath,
+			"sha":      repo.putTree(subtree(t, childPath)),
+			"url":      fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s", baseURL(r), repo.fullName(), childPath, ref),
+			"html_url": fmt.Sprintf("%s/%s/tree/%s/%s", webURL(r), repo.fullName(), ref, childPath),
+		})
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handlePutContents は PUT /repos/{owner}/{repo}/contents/{path...} を処理します
+func (s *Server) handlePutContents(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message string `json:"message"`
+		Content []byte `json:"content"`
+		SHA     string `json:"sha"`
+		Branch  string `json:"branch"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	if body.Message == "" {
+		writeValidationError(w, "Invalid request.\n\n\"message\" wasn't supplied.", "Content", "message", "missing_field")
+		return
+	}
+
+	branch := body.Branch
+	if branch == "" {
+		branch = repo.defaultBranch
+	}
+	t, ok := repo.treeAt(branch)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Branch %s not found", branch))
+		return
+	}
+
+	filePath := strings.Trim(r.PathValue("path"), "/")
+	existing, exists := t[filePath]
+	switch {
+	case exists && body.SHA == "":
+		writeValidationError(w, "Invalid request.\n\n\"sha\" wasn't supplied.", "Content", "sha", "missing_field")
+		return
+	case exists && body.SHA != existing.sha:
+		writeError(w, http.StatusConflict, fmt.Sprintf("%s does not match %s", filePath, body.SHA))
+		return
+	}
+
+	content := string(body.Content)
+	commitSHA := repo.commitFiles(branch, body.Message, map[string]*string{filePath: &content}, s.now())
+	t, _ = repo.treeAt(branch)
+
+	status := http.StatusCreated
+	if exists {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"content": contentJSON(r, repo, branch, filePath, t[filePath], false),
+		"commit":  commitJSON(r, repo, repo.commits[commitSHA]),
+	})
+}
@@ -0,0 +1,310 @@
+package fakegithub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// diffContext はハンクの前後に含める変更のない行数です (git diff の既定と同じ)
+const diffContext = 3
+
+// fileChange はPull Requestで変更されたファイルを表します
+type fileChange struct {
+	filename  string
+	previous  string // リネームの場合の変更前のパス
+	status    string // added, removed, modified, renamed
+	oldSHA    string
+	newSHA    string
+	mode      string
+	additions int
+	deletions int
+	patch     string // @@ から始まるハンクの一覧
+}
+
+// diffLine は行単位の差分の1行を表します
+type diffLine struct {
+	op   byte // ' ', '-', '+'
+	text string
+}
+
+// splitLines はファイルの内容を行に分割します (末尾の改行は区切りとして扱います)
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// lineDiff は最長共通部分列から行単位の差分を求めます
+func lineDiff(old, new []string) []diffLine {
+	// lcs[i][j] は old[i:] と new[j:] の最長共通部分列の長さ
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			lines = append(lines, diffLine{' ', old[i]})
+			i++
+			j++
+		case j < len(new) && (i == len(old) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, diffLine{'+', new[j]})
+			j++
+		default:
+			lines = append(lines, diffLine{'-', old[i]})
+			i++
+		}
+	}
+	return lines
+}
+
+// unifiedPatch は2つの内容のunified形式のハンクと追加・削除の行数を返します
+func unifiedPatch(oldContent, newContent string) (patch string, additions, deletions int) {
+	lines := lineDiff(splitLines(oldContent), splitLines(newContent))
+
+	// 変更行の前後 diffContext 行をハンクに含め、重なるハンクはまとめる
+	include := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(lines)-1, k+diffContext); c++ {
+			include[c] = true
+		}
+	}
+
+	var b strings.Builder
+	oldLine, newLine := 1, 1
+	for k := 0; k < len(lines); {
+		if !include[k] {
+			if lines[k].op != '+' {
+				oldLine++
+			}
+			if lines[k].op != '-' {
+				newLine++
+			}
+			k++
+			continue
+		}
+
+		end := k
+		for end < len(lines) && include[end] {
+			end++
+		}
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, l := range lines[k:end] {
+			body.WriteByte(l.op)
+			body.WriteString(l.text)
+			body.WriteByte('\n')
+			switch l.op {
+			case ' ':
+				oldCount++
+				newCount++
+			case '-':
+				oldCount++
+				deletions++
+			case '+':
+				newCount++
+				additions++
+			}
+		}
+		// git と同じく、行を含まない側の開始行は直前の行番号 (0 行の場合は 0) で表す
+		oldStart, newStart := oldLine, newLine
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n%s", oldStart, oldCount, newStart, newCount, body.String())
+		oldLine += oldCount
+		newLine += newCount
+		k = end
+	}
+	return strings.TrimSuffix(b.String(), "\n"), additions, deletions
+}
+
+// mergeBase はベースとヘッドの共通の祖先のうち、ヘッドから最も近いコミットを返します
+func (repo *repository) mergeBase(base, head string) string {
+	queue := []string{head}
+	seen := map[string]bool{}
+	for len(queue) > 0 {
+		sha := queue[0]
+		queue = queue[1:]
+		if seen[sha] {
+			continue
+		}
+		seen[sha] = true
+		if repo.isAncestor(sha, base) {
+			return sha
+		}
+		if c, ok := repo.commits[sha]; ok {
+			queue = append(queue, c.parents...)
+		}
+	}
+	return ""
+}
+
+// pullChanges はPull Requestで変更されたファイルをパス順に返します
+// GitHubと同じく、ベースとヘッドの共通の祖先からヘッドまでの変更を対象とします
+func (repo *repository) pullChanges(p *pull) []fileChange {
+	head := repo.refs["refs/heads/"+p.head]
+	oldTree := tree{}
+	if base := repo.mergeBase(repo.refs["refs/heads/"+p.base], head); base != "" {
+		oldTree = repo.trees[repo.commits[base].tree]
+	}
+	newTree := repo.trees[repo.commits[head].tree]
+
+	var added, removed []string
+	var changes []fileChange
+	for path, e := range newTree {
+		old, ok := oldTree[path]
+		switch {
+		case !ok:
+			added = append(added, path)
+		case old.sha != e.sha:
+			change := fileChange{filename: path, status: "modified", oldSHA: old.sha, newSHA: e.sha, mode: e.mode}
+			change.patch, change.additions, change.deletions = unifiedPatch(string(repo.blobs[old.sha]), string(repo.blobs[e.sha]))
+			changes = append(changes, change)
+		}
+	}
+	for path := range oldTree {
+		if _, ok := newTree[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	// 内容が同じファイルの削除と追加はリネームとして扱う
+	renamedFrom := map[string]string{}
+	for _, to := range added {
+		for k, from := range removed {
+			if from != "" && oldTree[from].sha == newTree[to].sha {
+				renamedFrom[to] = from
+				removed[k] = ""
+				break
+			}
+		}
+	}
+
+	for _, path := range added {
+		e := newTree[path]
+		if from, ok := renamedFrom[path]; ok {
+			changes = append(changes, fileChange{filename: path, previous: from, status: "renamed", oldSHA: e.sha, newSHA: e.sha, mode: e.mode})
+			continue
+		}
+		change := fileChange{filename: path, status: "added", newSHA: e.sha, mode: e.mode}
+		change.patch, change.additions, change.deletions = unifiedPatch("", string(repo.blobs[e.sha]))
+		changes = append(changes, change)
+	}
+	for _, path := range removed {
+		if path == "" {
+			continue
+		}
+		e := oldTree[path]
+		change := fileChange{filename: path, status: "removed", oldSHA: e.sha, mode: e.mode}
+		change.patch, change.additions, change.deletions = unifiedPatch(string(repo.blobs[e.sha]), "")
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].filename < changes[j].filename })
+	return changes
+}
+
+// unifiedDiff はファイルの変更を git diff 形式のテキストにします
+func unifiedDiff(changes []fileChange) string {
+	short := func(sha string) string {
+		if sha == "" {
+			return "0000000"
+		}
+		return sha[:7]
+	}
+
+	var b strings.Builder
+	for _, c := range changes {
+		from := c.filename
+		if c.previous != "" {
+			from = c.previous
+		}
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n", from, c.filename)
+		switch c.status {
+		case "added":
+			fmt.Fprintf(&b, "new file mode %s\nindex %s..%s\n--- /dev/null\n+++ b/%s\n", c.mode, short(c.oldSHA), short(c.newSHA), c.filename)
+		case "removed":
+			fmt.Fprintf(&b, "deleted file mode %s\nindex %s..%s\n--- a/%s\n+++ /dev/null\n", c.mode, short(c.oldSHA), short(c.newSHA), c.filename)
+		case "renamed":
+			fmt.Fprintf(&b, "similarity index 100%%\nrename from %s\nrename to %s\n", c.previous, c.filename)
+		default:
+			fmt.Fprintf(&b, "index %s..%s %s\n--- a/%s\n+++ b/%s\n", short(c.oldSHA), short(c.newSHA), c.mode, c.filename, c.filename)
+		}
+		if c.patch != "" {
+			b.WriteString(c.patch)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// fileChangeJSON は変更されたファイルをGitHub APIのレスポンス形式に変換します
+func fileChangeJSON(r *http.Request, repo *repository, c fileChange) map[string]interface{} {
+	sha := c.newSHA
+	if sha == "" {
+		sha = c.oldSHA
+	}
+	result := map[string]interface{}{
+		"sha":          sha,
+		"filename":     c.filename,
+		"status":       c.status,
+		"additions":    c.additions,
+		"deletions":    c.deletions,
+		"changes":      c.additions + c.deletions,
+		"blob_url":     fmt.Sprintf("%s/%s/blob/%s/%s", webURL(r), repo.fullName(), sha, c.filename),
+		"contents_url": fmt.Sprintf("%s/repos/%s/contents/%s", baseURL(r), repo.fullName(), c.filename),
+	}
+	if c.patch != "" {
+		result["patch"] = c.patch
+	}
+	if c.previous != "" {
+		result["previous_filename"] = c.previous
+	}
+	return result
+}
+
+// handleListPullFiles は GET /repos/{owner}/{repo}/pulls/{number}/files を処理します
+func (s *Server) handleListPullFiles(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	changes := repo.pullChanges(p)
+	start, end := paginate(w, r, len(changes))
+	files := make([]map[string]interface{}, 0, end-start)
+	for _, c := range changes[start:end] {
+		files = append(files, fileChangeJSON(r, repo, c))
+	}
+	writeJSON(w, http.StatusOK, files)
+}
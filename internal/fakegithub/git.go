@@ -0,0 +1,728 @@
+package fakegithub
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// treeEntry はフラット化したツリー内のblobを表します
+type treeEntry struct {
+	mode string
+	sha  string
+}
+
+// tree はパスをキーとしたblobの一覧です (ディレクトリは保持しません)
+type tree map[string]treeEntry
+
+// commit はGitのコミットを表します
+type commit struct {
+	sha     string
+	tree    string
+	message string
+	parents []string
+	date    time.Time
+}
+
+// Commit はテストから参照するコミットの情報です
+type Commit struct {
+	SHA     string
+	Tree    string
+	Message string
+	Parents []string
+}
+
+// repository はインメモリのリポジトリを表します
+type repository struct {
+	id            int64
+	owner         string
+	name          string
+	description   string
+	private       bool
+	fork          bool
+	parent        string
+	defaultBranch string
+	createdAt     time.Time
+
+	refs    map[string]string
+	commits map[string]*commit
+	trees   map[string]tree
+	blobs   map[string][]byte
+
+	pulls      map[int]*pull
+	issues     map[int]*issue
+	milestones map[int]string
+	nextNumber int
+
+	// mergeMethods はPull Requestのマージで許可されている方法です (nilの場合はすべて許可)
+	mergeMethods []string
+}
+
+// fullName は owner/name 形式のリポジトリ名を返します
+func (repo *repository) fullName() string {
+	return repo.owner + "/" + repo.name
+}
+
+// gitHash はGitと同じ形式でオブジェクトのSHA-1を計算します
+func gitHash(kind string, data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00", kind, len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// putBlob はblobを保存してSHAを返します
+func (repo *repository) putBlob(content []byte) string {
+	sha := gitHash("blob", content)
+	repo.blobs[sha] = append([]byte(nil), content...)
+	return sha
+}
+
+// putTree はツリーを保存してSHAを返します
+func (repo *repository) putTree(t tree) string {
+	paths := make([]string, 0, len(t))
+	for p := range t {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%s %s %s\n", t[p].mode, p, t[p].sha)
+	}
+	sha := gitHash("tree", []byte(b.String()))
+	repo.trees[sha] = t
+	return sha
+}
+
+// putCommit はコミットを保存してSHAを返します
+func (repo *repository) putCommit(treeSHA string, parents []string, message string, date time.Time) string {
+	data := fmt.Sprintf("tree %s\nparents %s\ndate %d\n\n%s", treeSHA, strings.Join(parents, " "), date.Unix(), message)
+	sha := gitHash("commit", []byte(data))
+	repo.commits[sha] = &commit{
+		sha:     sha,
+		tree:    treeSHA,
+		message: message,
+		parents: append([]string(nil), parents...),
+		date:    date,
+	}
+	return sha
+}
+
+// resolve はブランチ名、refまたはコミットSHAをコミットSHAに解決します
+func (repo *repository) resolve(ref string) (string, bool) {
+	if ref == "" || ref == "HEAD" {
+		ref = repo.defaultBranch
+	}
+	for _, candidate := range []string{ref, "refs/" + ref, "refs/heads/" + ref, "refs/tags/" + ref} {
+		if sha, ok := repo.refs[candidate]; ok {
+			return sha, true
+		}
+	}
+	if _, ok := repo.commits[ref]; ok {
+		return ref, true
+	}
+	return "", false
+}
+
+// treeAt は指定されたrefのコミットのツリーを返します
+func (repo *repository) treeAt(ref string) (tree, bool) {
+	sha, ok := repo.resolve(ref)
+	if !ok {
+		return nil, false
+	}
+	return repo.trees[repo.commits[sha].tree], true
+}
+
+// isAncestor はancestorがdescendantの祖先 (または同一) かどうかを判断します
+func (repo *repository) isAncestor(ancestor, descendant string) bool {
+	queue := []string{descendant}
+	seen := map[string]bool{}
+	for len(queue) > 0 {
+		sha := queue[0]
+		queue = queue[1:]
+		if sha == ancestor {
+			return true
+		}
+		if seen[sha] {
+			continue
+		}
+		seen[sha] = true
+		if c, ok := repo.commits[sha]; ok {
+			queue = append(queue, c.parents...)
+		}
+	}
+	return false
+}
+
+// commitFiles はブランチに対してファイルを追加・更新するコミットを作成します
+// 値がnilのファイルは削除されます
+func (repo *repository) commitFiles(branch, message string, files map[string]*string, date time.Time) string {
+	ref := "refs/heads/" + branch
+	parentSHA, hasParent := repo.refs[ref]
+
+	next := tree{}
+	var parents []string
+	if hasParent {
+		for p, e := range repo.trees[repo.commits[parentSHA].tree] {
+			next[p] = e
+		}
+		parents = []string{parentSHA}
+	}
+	for p, content := range files {
+		if content == nil {
+			delete(next, p)
+			continue
+		}
+		mode := "100644"
+		if existing, ok := next[p]; ok {
+			mode = existing.mode
+		}
+		next[p] = treeEntry{mode: mode, sha: repo.putBlob([]byte(*content))}
+	}
+
+	sha := repo.putCommit(repo.putTree(next), parents, message, date)
+	repo.refs[ref] = sha
+	return sha
+}
+
+// CreateRepo はREADME.mdを含む初期コミットを持つリポジトリを作成します
+func (s *Server) CreateRepo(owner, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createRepo(owner, name, "", false, true)
+}
+
+// createRepo はリポジトリを作成します (ロック取得済みで呼び出すこと)
+func (s *Server) createRepo(owner, name, description string, private, autoInit bool) *repository {
+	repo := &repository{
+		id:            s.newID(),
+		owner:         owner,
+		name:          name,
+		description:   description,
+		private:       private,
+		defaultBranch: "main",
+		createdAt:     s.now(),
+		refs:          make(map[string]string),
+		commits:       make(map[string]*commit),
+		trees:         make(map[string]tree),
+		blobs:         make(map[string][]byte),
+		pulls:         make(map[int]*pull),
+		issues:        make(map[int]*issue),
+		milestones:    make(map[int]string),
+		nextNumber:    1,
+	}
+	if autoInit {
+		readme := "# " + name + "\n"
+		repo.commitFiles("main", "Initial commit", map[string]*string{"README.md": &readme}, s.now())
+	}
+	s.repos[repo.fullName()] = repo
+	return repo
+}
+
+// SetFiles はブランチにファイルを追加・更新するコミットを作成し、そのSHAを返します
+// ブランチが存在しない場合はデフォルトブランチから作成します
+func (s *Server) SetFiles(owner, name, branch string, files map[string]string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	s.ensureBranch(repo, branch)
+
+	contents := make(map[string]*string, len(files))
+	for p, content := range files {
+		content := content
+		contents[p] = &content
+	}
+	return repo.commitFiles(branch, "Update files", contents, s.now())
+}
+
+// DeleteFiles はブランチからファイルを削除するコミットを作成し、そのSHAを返します
+func (s *Server) DeleteFiles(owner, name, branch string, paths ...string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	s.ensureBranch(repo, branch)
+
+	contents := make(map[string]*string, len(paths))
+	for _, p := range paths {
+		contents[p] = nil
+	}
+	return repo.commitFiles(branch, "Delete files", contents, s.now())
+}
+
+// SetFileMode はブランチ上のファイルのモードを変更するコミットを作成します
+func (s *Server) SetFileMode(owner, name, branch, filePath, mode string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	t, _ := repo.treeAt(branch)
+	next := tree{}
+	for p, e := range t {
+		next[p] = e
+	}
+	next[filePath] = treeEntry{mode: mode, sha: next[filePath].sha}
+	ref := "refs/heads/" + branch
+	sha := repo.putCommit(repo.putTree(next), []string{repo.refs[ref]}, "Change mode", s.now())
+	repo.refs[ref] = sha
+	return sha
+}
+
+// CreateBranch はfromブランチの先頭から新しいブランチを作成します
+func (s *Server) CreateBranch(owner, name, branch, from string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	repo.refs["refs/heads/"+branch] = repo.refs["refs/heads/"+from]
+}
+
+// ensureBranch はブランチがなければデフォルトブランチから作成します (ロック取得済みで呼び出すこと)
+func (s *Server) ensureBranch(repo *repository, branch string) {
+	if _, ok := repo.refs["refs/heads/"+branch]; ok {
+		return
+	}
+	if sha, ok := repo.refs["refs/heads/"+repo.defaultBranch]; ok {
+		repo.refs["refs/heads/"+branch] = sha
+	}
+}
+
+// BranchSHA はブランチの先頭コミットのSHAを返します
+func (s *Server) BranchSHA(owner, name, branch string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mustRepo(owner, name).refs["refs/heads/"+branch]
+}
+
+// File はref上のファイルの内容を返します
+func (s *Server) File(owner, name, ref, filePath string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	t, ok := repo.treeAt(ref)
+	if !ok {
+		return "", false
+	}
+	e, ok := t[filePath]
+	if !ok {
+		return "", false
+	}
+	return string(repo.blobs[e.sha]), true
+}
+
+// FileSHA はref上のファイルのblob SHAを返します
+func (s *Server) FileSHA(owner, name, ref, filePath string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, _ := s.mustRepo(owner, name).treeAt(ref)
+	return t[filePath].sha
+}
+
+// FileMode はref上のファイルのモードを返します
+func (s *Server) FileMode(owner, name, ref, filePath string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, _ := s.mustRepo(owner, name).treeAt(ref)
+	return t[filePath].mode
+}
+
+// GetCommit はコミットの情報を返します
+func (s *Server) GetCommit(owner, name, sha string) (Commit, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.mustRepo(owner, name).commits[sha]
+	if !ok {
+		return Commit{}, false
+	}
+	return Commit{SHA: c.sha, Tree: c.tree, Message: c.message, Parents: append([]string(nil), c.parents...)}, true
+}
+
+// mustRepo はリポジトリを返します。存在しない場合はpanicします (ロック取得済みで呼び出すこと)
+func (s *Server) mustRepo(owner, name string) *repository {
+	repo, ok := s.repos[owner+"/"+name]
+	if !ok {
+		panic(fmt.Sprintf("fakegithub: repository %s/%s does not exist", owner, name))
+	}
+	return repo
+}
+
+// lookupRepo はパスパラメータからリポジトリを取得します。存在しない場合は404を書き込みます
+// 呼び出し元はロックを取得済みであること
+func (s *Server) lookupRepo(w http.ResponseWriter, r *http.Request) *repository {
+	repo, ok := s.repos[r.PathValue("owner")+"/"+r.PathValue("repo")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil
+	}
+	return repo
+}
+
+// refJSON はrefのレスポンスを作成します
+func refJSON(r *http.Request, repo *repository, ref, sha string) map[string]interface{} {
+	return map[string]interface{}{
+		"ref": ref,
+		"url": fmt.Sprintf("%s/repos/%s/git/%s", baseURL(r), repo.fullName(), ref),
+		"object": map[string]interface{}{
+			"type": "commit",
+			"sha":  sha,
+			"url":  fmt.Sprintf("%s/repos/%s/git/commits/%s", baseURL(r), repo.fullName(), sha),
+		},
+	}
+}
+
+// commitJSON はGitコミットのレスポンスを作成します
+func commitJSON(r *http.Request, repo *repository, c *commit) map[string]interface{} {
+	parents := make([]map[string]interface{}, 0, len(c.parents))
+	for _, p := range c.parents {
+		parents = append(parents, map[string]interface{}{
+			"sha": p,
+			"url": fmt.Sprintf("%s/repos/%s/git/commits/%s", baseURL(r), repo.fullName(), p),
+		})
+	}
+	author := map[string]interface{}{
+		"name":  "The Octocat",
+		"email": "octocat@github.com",
+		"date":  c.date.Format(time.RFC3339),
+	}
+	return map[string]interface{}{
+		"sha":       c.sha,
+		"url":       fmt.Sprintf("%s/repos/%s/git/commits/%s", baseURL(r), repo.fullName(), c.sha),
+		"html_url":  fmt.Sprintf("%s/%s/commit/%s", webURL(r), repo.fullName(), c.sha),
+		"message":   c.message,
+		"author":    author,
+		"committer": author,
+		"tree": map[string]interface{}{
+			"sha": c.tree,
+			"url": fmt.Sprintf("%s/repos/%s/git/trees/%s", baseURL(r), repo.fullName(), c.tree),
+		},
+		"parents": parents,
+	}
+}
+
+// handleGetRef は GET /repos/{owner}/{repo}/git/ref/{ref...} を処理します
+func (s *Server) handleGetRef(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	ref := "refs/" + r.PathValue("ref")
+	sha, ok := repo.refs[ref]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, refJSON(r, repo, ref, sha))
+}
+
+// handleCreateRef は POST /repos/{owner}/{repo}/git/refs を処理します
+func (s *Server) handleCreateRef(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	if _, ok := repo.refs[body.Ref]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference already exists")
+		return
+	}
+	if _, ok := repo.commits[body.SHA]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	repo.refs[body.Ref] = body.SHA
+	writeJSON(w, http.StatusCreated, refJSON(r, repo, body.Ref, body.SHA))
+}
+
+// handleUpdateRef は PATCH /repos/{owner}/{repo}/git/refs/{ref...} を処理します
+func (s *Server) handleUpdateRef(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		SHA   string `json:"sha"`
+		Force bool   `json:"force"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	ref := "refs/" + r.PathValue("ref")
+	current, ok := repo.refs[ref]
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	if _, ok := repo.commits[body.SHA]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	if !body.Force && !repo.isAncestor(current, body.SHA) {
+		writeError(w, http.StatusUnprocessableEntity, "Update is not a fast forward")
+		return
+	}
+	repo.refs[ref] = body.SHA
+	writeJSON(w, http.StatusOK, refJSON(r, repo, ref, body.SHA))
+}
+
+// handleDeleteRef は DELETE /repos/{owner}/{repo}/git/refs/{ref...} を処理します
+func (s *Server) handleDeleteRef(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	ref := "refs/" + r.PathValue("ref")
+	if _, ok := repo.refs[ref]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	delete(repo.refs, ref)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetCommit は GET /repos/{owner}/{repo}/git/commits/{sha} を処理します
+func (s *Server) handleGetCommit(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	c, ok := repo.commits[r.PathValue("sha")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, commitJSON(r, repo, c))
+}
+
+// handleCreateCommit は POST /repos/{owner}/{repo}/git/commits を処理します
+func (s *Server) handleCreateCommit(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Message string   `json:"message"`
+		Tree    string   `json:"tree"`
+		Parents []string `json:"parents"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	if _, ok := repo.trees[body.Tree]; !ok {
+		writeValidationError(w, "Tree SHA does not exist", "Commit", "tree", "invalid")
+		return
+	}
+	for _, p := range body.Parents {
+		if _, ok := repo.commits[p]; !ok {
+			writeValidationError(w, "Parent SHA does not exist or is not a commit object", "Commit", "parents", "invalid")
+			return
+		}
+	}
+	sha := repo.putCommit(body.Tree, body.Parents, body.Message, s.now())
+	writeJSON(w, http.StatusCreated, commitJSON(r, repo, repo.commits[sha]))
+}
+
+// handleGetTree は GET /repos/{owner}/{repo}/git/trees/{sha} を処理します
+func (s *Server) handleGetTree(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+
+	sha := r.PathValue("sha")
+	t, ok := repo.trees[sha]
+	if !ok {
+		// コミットSHAやブランチ名も受け付ける
+		commitSHA, found := repo.resolve(sha)
+		if !found {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		sha = repo.commits[commitSHA].tree
+		t = repo.trees[sha]
+	}
+
+	recursive := r.URL.Query().Get("recursive") != ""
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sha":       sha,
+		"url":       fmt.Sprintf("%s/repos/%s/git/trees/%s", baseURL(r), repo.fullName(), sha),
+		"tree":      repo.treeEntriesJSON(t, recursive),
+		"truncated": false,
+	})
+}
+
+// treeEntriesJSON はツリーのエントリ一覧を作成します
+// ディレクトリはサブツリーとして保存し、typeがtreeのエントリとして返します
+func (repo *repository) treeEntriesJSON(t tree, recursive bool) []map[string]interface{} {
+	dirs := map[string]bool{}
+	for p := range t {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	var paths []string
+	for p := range t {
+		if recursive || !strings.Contains(p, "/") {
+			paths = append(paths, p)
+		}
+	}
+	for dir := range dirs {
+		if recursive || !strings.Contains(dir, "/") {
+			paths = append(paths, dir)
+		}
+	}
+	sort.Strings(paths)
+
+	entries := make([]map[string]interface{}, 0, len(paths))
+	for _, p := range paths {
+		if dirs[p] {
+			entries = append(entries, map[string]interface{}{
+				"path": p,
+				"mode": "040000",
+				"type": "tree",
+				"sha":  repo.putTree(subtree(t, p)),
+			})
+			continue
+		}
+		e := t[p]
+		entries = append(entries, map[string]interface{}{
+			"path": p,
+			"mode": e.mode,
+			"type": "blob",
+			"sha":  e.sha,
+			"size": len(repo.blobs[e.sha]),
+		})
+	}
+	return entries
+}
+
+// subtree はディレクトリ配下のエントリを相対パスのツリーとして返します
+func subtree(t tree, dir string) tree {
+	sub := tree{}
+	prefix := dir + "/"
+	for p, e := range t {
+		if strings.HasPrefix(p, prefix) {
+			sub[strings.TrimPrefix(p, prefix)] = e
+		}
+	}
+	return sub
+}
+
+// handleCreateTree は POST /repos/{owner}/{repo}/git/trees を処理します
+func (s *Server) handleCreateTree(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string  `json:"path"`
+			Mode    string  `json:"mode"`
+			Type    string  `json:"type"`
+			SHA     *string `json:"sha"`
+			Content *string `json:"content"`
+		} `json:"tree"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+
+	next := tree{}
+	if body.BaseTree != "" {
+		base, ok := repo.trees[body.BaseTree]
+		if !ok {
+			writeValidationError(w, "base_tree is not a valid tree oid", "Tree", "base_tree", "invalid")
+			return
+		}
+		for p, e := range base {
+			next[p] = e
+		}
+	}
+
+	for _, entry := range body.Tree {
+		switch entry.Mode {
+		case "100644", "100755", "120000":
+		default:
+			writeValidationError(w, fmt.Sprintf("tree.mode contains a malformed mode %q", entry.Mode), "Tree", "mode", "invalid")
+			return
+		}
+		switch {
+		case entry.Content != nil:
+			next[entry.Path] = treeEntry{mode: entry.Mode, sha: repo.putBlob([]byte(*entry.Content))}
+		case entry.SHA != nil:
+			if _, ok := repo.blobs[*entry.SHA]; !ok {
+				writeValidationError(w, fmt.Sprintf("tree.sha %s is not a valid blob", *entry.SHA), "Tree", "sha", "invalid")
+				return
+			}
+			next[entry.Path] = treeEntry{mode: entry.Mode, sha: *entry.SHA}
+		default:
+			if _, ok := next[entry.Path]; !ok {
+				writeValidationError(w, fmt.Sprintf("tree.path %s does not exist", entry.Path), "Tree", "path", "invalid")
+				return
+			}
+			delete(next, entry.Path)
+		}
+	}
+
+	sha := repo.putTree(next)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"sha":       sha,
+		"url":       fmt.Sprintf("%s/repos/%s/git/trees/%s", baseURL(r), repo.fullName(), sha),
+		"tree":      repo.treeEntriesJSON(next, false),
+		"truncated": false,
+	})
+}
+
+// handleGetBlob は GET /repos/{owner}/{repo}/git/blobs/{sha} を処理します
+func (s *Server) handleGetBlob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	sha := r.PathValue("sha")
+	content, ok := repo.blobs[sha]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sha":      sha,
+		"size":     len(content),
+		"url":      fmt.Sprintf("%s/repos/%s/git/blobs/%s", baseURL(r), repo.fullName(), sha),
+		"content":  base64.StdEncoding.EncodeToString(content),
+		"encoding": "base64",
+	})
+}
@@ -0,0 +1,178 @@
+package fakegithub
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// graphQLVariables はフェイクが対応するクエリで使用する変数です
+type graphQLVariables struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+}
+
+// handleGraphQL は POST /graphql を処理します
+// Pull Requestのドラフトの切り替え、自動マージの設定、レビュースレッドの取得と解決のみに対応します
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query     string           `json:"query"`
+		Variables graphQLVariables `json:"variables"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case strings.Contains(body.Query, "reviewThreads"):
+		s.graphQLReviewThreads(w, body.Variables)
+	case strings.Contains(body.Query, "ReviewThread("):
+		s.graphQLResolveReviewThread(w, body.Query, body.Variables)
+	default:
+		s.graphQLPullRequestMutation(w, body.Query, body.Variables)
+	}
+}
+
+// graphQLPullRequestMutation はPull Requestのドラフトと自動マージのミューテーションを処理します
+func (s *Server) graphQLPullRequestMutation(w http.ResponseWriter, query string, vars graphQLVariables) {
+	var p *pull
+	for _, repo := range s.repos {
+		for _, candidate := range repo.pulls {
+			if candidate.nodeID() == vars.ID {
+				p = candidate
+			}
+		}
+	}
+	if p == nil {
+		writeGraphQLError(w, "NOT_FOUND", "Could not resolve to a node with the global id of '"+vars.ID+"'")
+		return
+	}
+
+	var mutation string
+	switch {
+	case strings.Contains(query, "markPullRequestReadyForReview"):
+		mutation = "markPullRequestReadyForReview"
+		p.draft = false
+	case strings.Contains(query, "convertPullRequestToDraft"):
+		mutation = "convertPullRequestToDraft"
+		p.draft = true
+	case strings.Contains(query, "enablePullRequestAutoMerge"):
+		mutation = "enablePullRequestAutoMerge"
+		// GitHubと同じく、すぐにマージできる状態では自動マージを有効にできない
+		if p.state != "open" || p.mergeableState == "clean" && !p.draft {
+			writeGraphQLError(w, "UNPROCESSABLE", "Pull request Pull request is in clean status")
+			return
+		}
+		method := strings.ToLower(vars.Method)
+		if method == "" {
+			method = "merge"
+		}
+		p.autoMerge = method
+	case strings.Contains(query, "disablePullRequestAutoMerge"):
+		mutation = "disablePullRequestAutoMerge"
+		p.autoMerge = ""
+	default:
+		writeGraphQLError(w, "UNPROCESSABLE", "unsupported query")
+		return
+	}
+	p.updatedAt = s.now()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			mutation: map[string]interface{}{
+				"pullRequest": map[string]interface{}{"number": p.number, "isDraft": p.draft},
+			},
+		},
+	})
+}
+
+// graphQLReviewThreads はPull Requestのレビュースレッドの一覧のクエリを処理します
+// スレッドはすべて1ページで返します
+func (s *Server) graphQLReviewThreads(w http.ResponseWriter, vars graphQLVariables) {
+	repo, ok := s.repos[vars.Owner+"/"+vars.Repo]
+	if !ok {
+		writeGraphQLError(w, "NOT_FOUND", "Could not resolve to a Repository with the name '"+vars.Owner+"/"+vars.Repo+"'.")
+		return
+	}
+	p, ok := repo.pulls[vars.Number]
+	if !ok {
+		writeGraphQLError(w, "NOT_FOUND", fmt.Sprintf("Could not resolve to a PullRequest with the number of %d.", vars.Number))
+		return
+	}
+
+	var order []int64
+	comments := map[int64][]map[string]interface{}{}
+	for _, c := range p.reviewComments {
+		root := c.threadID()
+		if _, ok := comments[root]; !ok {
+			order = append(order, root)
+		}
+		comments[root] = append(comments[root], map[string]interface{}{"databaseId": c.id})
+	}
+	threads := make([]map[string]interface{}, 0, len(order))
+	for _, root := range order {
+		threads = append(threads, map[string]interface{}{
+			"id":         threadNodeID(root),
+			"isResolved": p.resolvedThreads[root],
+			"comments":   map[string]interface{}{"nodes": comments[root]},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{
+			"repository": map[string]interface{}{
+				"pullRequest": map[string]interface{}{
+					"reviewThreads": map[string]interface{}{
+						"nodes":    threads,
+						"pageInfo": map[string]interface{}{"hasNextPage": false, "endCursor": nil},
+					},
+				},
+			},
+		},
+	})
+}
+
+// graphQLResolveReviewThread はレビュースレッドの解決と解決の取り消しのミューテーションを処理します
+func (s *Server) graphQLResolveReviewThread(w http.ResponseWriter, query string, vars graphQLVariables) {
+	mutation := "resolveReviewThread"
+	if strings.Contains(query, "unresolveReviewThread") {
+		mutation = "unresolveReviewThread"
+	}
+
+	for _, repo := range s.repos {
+		for _, p := range repo.pulls {
+			for _, c := range p.reviewComments {
+				if c.inReplyTo != 0 || threadNodeID(c.id) != vars.ID {
+					continue
+				}
+				if p.resolvedThreads == nil {
+					p.resolvedThreads = map[int64]bool{}
+				}
+				p.resolvedThreads[c.id] = mutation == "resolveReviewThread"
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"data": map[string]interface{}{
+						mutation: map[string]interface{}{
+							"thread": map[string]interface{}{"id": vars.ID, "isResolved": p.resolvedThreads[c.id]},
+						},
+					},
+				})
+				return
+			}
+		}
+	}
+	writeGraphQLError(w, "NOT_FOUND", "Could not resolve to a node with the global id of '"+vars.ID+"'")
+}
+
+// writeGraphQLError はGraphQL形式のエラーレスポンスを書き込みます (GitHubと同じくステータスは200)
+func writeGraphQLError(w http.ResponseWriter, errorType, message string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data":   nil,
+		"errors": []map[string]interface{}{{"type": errorType, "message": message}},
+	})
+}
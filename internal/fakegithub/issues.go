@@ -0,0 +1,426 @@
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// issue はIssueを表します
+type issue struct {
+	id          int64
+	number      int
+	title       string
+	body        string
+	state       string
+	stateReason string
+	user        string
+	labels      []string
+	assignees   []string
+	milestone   int
+	comments    []*issueComment
+	createdAt   time.Time
+	updatedAt   time.Time
+	closedAt    time.Time
+}
+
+// issueComment はIssueのコメントを表します
+type issueComment struct {
+	id        int64
+	user      string
+	body      string
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// CreateIssue はIssueを作成し、その番号を返します
+func (s *Server) CreateIssue(owner, name, title string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	return s.createIssue(repo, title, "").number
+}
+
+// CreateMilestone はマイルストーンを作成し、その番号を返します
+func (s *Server) CreateMilestone(owner, name, title string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	number := len(repo.milestones) + 1
+	repo.milestones[number] = title
+	return number
+}
+
+// createIssue はIssueを作成します (ロック取得済みで呼び出すこと)
+// Issue番号はPull Requestと共通の連番です
+func (s *Server) createIssue(repo *repository, title, body string) *issue {
+	now := s.now()
+	i := &issue{
+		id:        s.newID(),
+		number:    repo.nextNumber,
+		title:     title,
+		body:      body,
+		state:     "open",
+		user:      Login,
+		createdAt: now,
+		updatedAt: now,
+	}
+	repo.nextNumber++
+	repo.issues[i.number] = i
+	return i
+}
+
+// issueJSON はIssueのレスポンスを作成します
+func issueJSON(r *http.Request, repo *repository, i *issue) map[string]interface{} {
+	labels := make([]interface{}, 0, len(i.labels))
+	for _, label := range i.labels {
+		labels = append(labels, map[string]interface{}{"name": label})
+	}
+	assignees := make([]interface{}, 0, len(i.assignees))
+	for _, login := range i.assignees {
+		assignees = append(assignees, userJSON(r, login))
+	}
+	result := map[string]interface{}{
+		"id":             i.id,
+		"number":         i.number,
+		"title":          i.title,
+		"body":           i.body,
+		"state":          i.state,
+		"user":           userJSON(r, i.user),
+		"labels":         labels,
+		"assignees":      assignees,
+		"comments":       len(i.comments),
+		"html_url":       fmt.Sprintf("%s/%s/issues/%d", webURL(r), repo.fullName(), i.number),
+		"repository_url": fmt.Sprintf("%s/repos/%s", baseURL(r), repo.fullName()),
+		"created_at":     i.createdAt.Format(time.RFC3339),
+		"updated_at":     i.updatedAt.Format(time.RFC3339),
+	}
+	if i.stateReason != "" {
+		result["state_reason"] = i.stateReason
+	}
+	if i.milestone != 0 {
+		result["milestone"] = map[string]interface{}{"number": i.milestone, "title": repo.milestones[i.milestone]}
+	}
+	if !i.closedAt.IsZero() {
+		result["closed_at"] = i.closedAt.Format(time.RFC3339)
+	}
+	return result
+}
+
+// issueCommentJSON はIssueコメントのレスポンスを作成します
+func issueCommentJSON(r *http.Request, repo *repository, i *issue, c *issueComment) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         c.id,
+		"user":       userJSON(r, c.user),
+		"body":       c.body,
+		"html_url":   fmt.Sprintf("%s/%s/issues/%d#issuecomment-%d", webURL(r), repo.fullName(), i.number, c.id),
+		"created_at": c.createdAt.Format(time.RFC3339),
+		"updated_at": c.updatedAt.Format(time.RFC3339),
+	}
+}
+
+// lookupIssue はパスパラメータからIssueを取得します。存在しない場合は404を書き込みます
+func lookupIssue(w http.ResponseWriter, r *http.Request, repo *repository) *issue {
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil
+	}
+	i, ok := repo.issues[number]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil
+	}
+	return i
+}
+
+// validateIssueFields はラベル以外の関連項目を検証します。不正な場合は422を書き込みます
+func validateIssueFields(w http.ResponseWriter, repo *repository, assignees []string, milestone int) bool {
+	for _, login := range assignees {
+		if login != Login && login != repo.owner {
+			writeValidationError(w, "Validation Failed", "Issue", "assignees", "invalid")
+			return false
+		}
+	}
+	if _, ok := repo.milestones[milestone]; milestone != 0 && !ok {
+		writeValidationError(w, "Validation Failed", "Issue", "milestone", "invalid")
+		return false
+	}
+	return true
+}
+
+// handleCreateIssue は POST /repos/{owner}/{repo}/issues を処理します
+func (s *Server) handleCreateIssue(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title     string   `json:"title"`
+		Body      string   `json:"body"`
+		Labels    []string `json:"labels"`
+		Assignees []string `json:"assignees"`
+		Milestone int      `json:"milestone"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	if strings.TrimSpace(body.Title) == "" {
+		writeValidationError(w, "Validation Failed", "Issue", "title", "missing_field")
+		return
+	}
+	if !validateIssueFields(w, repo, body.Assignees, body.Milestone) {
+		return
+	}
+
+	i := s.createIssue(repo, body.Title, body.Body)
+	i.labels = body.Labels
+	i.assignees = body.Assignees
+	i.milestone = body.Milestone
+	writeJSON(w, http.StatusCreated, issueJSON(r, repo, i))
+}
+
+// handleGetIssue は GET /repos/{owner}/{repo}/issues/{number} を処理します
+func (s *Server) handleGetIssue(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	i := lookupIssue(w, r, repo)
+	if i == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, issueJSON(r, repo, i))
+}
+
+// handleListIssues は GET /repos/{owner}/{repo}/issues を処理します
+// state / labels / assignee / since による絞り込みと sort / direction による並べ替えに対応します
+// (フェイクではPull Requestは含めません)
+func (s *Server) handleListIssues(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var since time.Time
+	if value := query.Get("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeValidationError(w, "Validation Failed", "Issue", "since", "invalid")
+			return
+		}
+		since = t
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+
+	state := query.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	var labels []string
+	if value := query.Get("labels"); value != "" {
+		labels = strings.Split(value, ",")
+	}
+	assignee := query.Get("assignee")
+
+	var matched []*issue
+	for _, i := range repo.issues {
+		switch {
+		case state != "all" && i.state != state,
+			!since.IsZero() && i.updatedAt.Before(since),
+			assignee == "none" && len(i.assignees) > 0,
+			assignee == "*" && len(i.assignees) == 0,
+			assignee != "" && assignee != "none" && assignee != "*" && !containsString(i.assignees, assignee):
+			continue
+		}
+		hasLabels := true
+		for _, label := range labels {
+			hasLabels = hasLabels && containsString(i.labels, label)
+		}
+		if hasLabels {
+			matched = append(matched, i)
+		}
+	}
+
+	key := func(i *issue) time.Time { return i.createdAt }
+	if query.Get("sort") == "updated" {
+		key = func(i *issue) time.Time { return i.updatedAt }
+	}
+	desc := query.Get("direction") != "asc"
+	sort.Slice(matched, func(a, b int) bool {
+		ka, kb := key(matched[a]), key(matched[b])
+		if ka.Equal(kb) {
+			return (matched[a].number > matched[b].number) == desc
+		}
+		return ka.After(kb) == desc
+	})
+
+	start, end := paginate(w, r, len(matched))
+	items := make([]map[string]interface{}, 0, end-start)
+	for _, i := range matched[start:end] {
+		items = append(items, issueJSON(r, repo, i))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// handleUpdateIssue は PATCH /repos/{owner}/{repo}/issues/{number} を処理します
+func (s *Server) handleUpdateIssue(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title       *string         `json:"title"`
+		Body        *string         `json:"body"`
+		State       *string         `json:"state"`
+		StateReason *string         `json:"state_reason"`
+		Labels      *[]string       `json:"labels"`
+		Assignees   *[]string       `json:"assignees"`
+		Milestone   json.RawMessage `json:"milestone"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	i := lookupIssue(w, r, repo)
+	if i == nil {
+		return
+	}
+
+	// milestone は省略時は変更せず、null の場合は外す
+	milestone := i.milestone
+	if len(body.Milestone) > 0 {
+		milestone = 0
+		if string(body.Milestone) != "null" {
+			if err := json.Unmarshal(body.Milestone, &milestone); err != nil {
+				writeValidationError(w, "Validation Failed", "Issue", "milestone", "invalid")
+				return
+			}
+		}
+	}
+	assignees := i.assignees
+	if body.Assignees != nil {
+		assignees = *body.Assignees
+	}
+	if !validateIssueFields(w, repo, assignees, milestone) {
+		return
+	}
+	if body.State != nil && *body.State != "open" && *body.State != "closed" {
+		writeValidationError(w, "Validation Failed", "Issue", "state", "invalid")
+		return
+	}
+	if body.StateReason != nil && !containsString([]string{"completed", "not_planned", "reopened"}, *body.StateReason) {
+		writeValidationError(w, "Validation Failed", "Issue", "state_reason", "invalid")
+		return
+	}
+
+	now := s.now()
+	if body.Title != nil {
+		i.title = *body.Title
+	}
+	if body.Body != nil {
+		i.body = *body.Body
+	}
+	if body.State != nil && *body.State != i.state {
+		i.state = *body.State
+		if i.state == "closed" {
+			i.closedAt = now
+			i.stateReason = "completed"
+		} else {
+			i.closedAt = time.Time{}
+			i.stateReason = "reopened"
+		}
+	}
+	if body.StateReason != nil {
+		i.stateReason = *body.StateReason
+	}
+	if body.Labels != nil {
+		i.labels = *body.Labels
+	}
+	i.assignees = assignees
+	i.milestone = milestone
+	i.updatedAt = now
+	writeJSON(w, http.StatusOK, issueJSON(r, repo, i))
+}
+
+// handleCreateIssueComment は POST /repos/{owner}/{repo}/issues/{number}/comments を処理します
+func (s *Server) handleCreateIssueComment(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Body string `json:"body"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	i := lookupIssue(w, r, repo)
+	if i == nil {
+		return
+	}
+	if strings.TrimSpace(body.Body) == "" {
+		writeValidationError(w, "Validation Failed", "IssueComment", "body", "missing_field")
+		return
+	}
+
+	now := s.now()
+	c := &issueComment{id: s.newID(), user: Login, body: body.Body, createdAt: now, updatedAt: now}
+	i.comments = append(i.comments, c)
+	i.updatedAt = now
+	writeJSON(w, http.StatusCreated, issueCommentJSON(r, repo, i, c))
+}
+
+// handleListIssueComments は GET /repos/{owner}/{repo}/issues/{number}/comments を処理します
+// コメントは作成順に返し、since で更新日時による絞り込みができます
+func (s *Server) handleListIssueComments(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeValidationError(w, "Validation Failed", "IssueComment", "since", "invalid")
+			return
+		}
+		since = t
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	i := lookupIssue(w, r, repo)
+	if i == nil {
+		return
+	}
+
+	var matched []*issueComment
+	for _, c := range i.comments {
+		if since.IsZero() || !c.updatedAt.Before(since) {
+			matched = append(matched, c)
+		}
+	}
+	start, end := paginate(w, r, len(matched))
+	items := make([]map[string]interface{}, 0, end-start)
+	for _, c := range matched[start:end] {
+		items = append(items, issueCommentJSON(r, repo, i, c))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
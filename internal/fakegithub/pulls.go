@@ -0,0 +1,634 @@
+package fakegithub
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pull はPull Requestを表します
+type pull struct {
+	id                  int64
+	number              int
+	title               string
+	body                string
+	head                string
+	base                string
+	state               string
+	draft               bool
+	user                string
+	maintainerCanModify bool
+	createdAt           time.Time
+	updatedAt           time.Time
+	closedAt            time.Time
+	reviews             []*review
+	reviewComments      []*reviewComment
+	// resolvedThreads は解決済みのレビュースレッドを、最初のコメントのIDをキーとして保持します
+	resolvedThreads map[int64]bool
+
+	// mergeableState はマージ可否の状態です (clean, dirty, behind, blocked)
+	mergeableState string
+	merged         bool
+	mergedAt       time.Time
+	mergeCommitSHA string
+	// autoMerge は有効な自動マージの方式です (無効な場合は空)
+	autoMerge string
+}
+
+// review はPull Requestのレビューを表します
+type review struct {
+	id          int64
+	user        string
+	body        string
+	state       string
+	commitID    string
+	submittedAt time.Time
+}
+
+// Review はテストから参照するレビューの情報です
+type Review struct {
+	ID       int64
+	Body     string
+	State    string
+	CommitID string
+}
+
+// CreatePull はheadブランチからbaseブランチへのPull Requestを作成し、その番号を返します
+func (s *Server) CreatePull(owner, name, head, base, title string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.mustRepo(owner, name)
+	return s.createPull(repo, head, base, title, "", false, false).number
+}
+
+// SetMergeableState はPull Requestのマージ可否の状態を設定します
+// dirty (競合あり)、behind (ベースより古い)、blocked (必須の条件を未達成)、clean を指定できます
+func (s *Server) SetMergeableState(owner, name string, number int, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mustRepo(owner, name).pulls[number].mergeableState = state
+}
+
+// SetAllowedMergeMethods はリポジトリで許可するマージの方法 (merge, squash, rebase) を設定します
+func (s *Server) SetAllowedMergeMethods(owner, name string, methods ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mustRepo(owner, name).mergeMethods = methods
+}
+
+// Reviews はPull Requestのレビュー一覧を返します
+func (s *Server) Reviews(owner, name string, number int) []Review {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.mustRepo(owner, name).pulls[number]
+	if !ok {
+		return nil
+	}
+	reviews := make([]Review, 0, len(p.reviews))
+	for _, rv := range p.reviews {
+		reviews = append(reviews, Review{ID: rv.id, Body: rv.body, State: rv.state, CommitID: rv.commitID})
+	}
+	return reviews
+}
+
+// createPull はPull Requestを作成します (ロック取得済みで呼び出すこと)
+func (s *Server) createPull(repo *repository, head, base, title, body string, draft, maintainerCanModify bool) *pull {
+	now := s.now()
+	p := &pull{
+		id:                  s.newID(),
+		number:              repo.nextNumber,
+		title:               title,
+		body:                body,
+		head:                head,
+		base:                base,
+		state:               "open",
+		draft:               draft,
+		user:                Login,
+		maintainerCanModify: maintainerCanModify,
+		createdAt:           now,
+		updatedAt:           now,
+		mergeableState:      "clean",
+	}
+	repo.nextNumber++
+	repo.pulls[p.number] = p
+	return p
+}
+
+// pullJSON はPull Requestのレスポンスを作成します
+func pullJSON(r *http.Request, repo *repository, p *pull) map[string]interface{} {
+	branchJSON := func(branch string) map[string]interface{} {
+		return map[string]interface{}{
+			"label": repo.owner + ":" + branch,
+			"ref":   branch,
+			"sha":   repo.refs["refs/heads/"+branch],
+			"user":  userJSON(r, repo.owner),
+			"repo":  repoJSON(r, repo),
+		}
+	}
+	prURL := fmt.Sprintf("%s/%s/pull/%d", webURL(r), repo.fullName(), p.number)
+	mergeableState := p.mergeableState
+	if p.draft {
+		mergeableState = "draft"
+	}
+	result := map[string]interface{}{
+		"id":                    p.id,
+		"node_id":               p.nodeID(),
+		"number":                p.number,
+		"state":                 p.state,
+		"title":                 p.title,
+		"body":                  p.body,
+		"created_at":            p.createdAt.Format(time.RFC3339),
+		"updated_at":            p.updatedAt.Format(time.RFC3339),
+		"user":                  userJSON(r, p.user),
+		"html_url":              prURL,
+		"diff_url":              prURL + ".diff",
+		"patch_url":             prURL + ".patch",
+		"base":                  branchJSON(p.base),
+		"head":                  branchJSON(p.head),
+		"merged":                p.merged,
+		"mergeable":             p.mergeableState != "dirty",
+		"mergeable_state":       mergeableState,
+		"draft":                 p.draft,
+		"maintainer_can_modify": p.maintainerCanModify,
+		"review_comments":       len(p.reviewComments),
+		"requested_reviewers":   []interface{}{},
+		"labels":                []interface{}{},
+	}
+	changes := repo.pullChanges(p)
+	additions, deletions := 0, 0
+	for _, c := range changes {
+		additions += c.additions
+		deletions += c.deletions
+	}
+	result["additions"], result["deletions"], result["changed_files"] = additions, deletions, len(changes)
+	if !p.closedAt.IsZero() {
+		result["closed_at"] = p.closedAt.Format(time.RFC3339)
+	}
+	if p.merged {
+		result["merged_at"] = p.mergedAt.Format(time.RFC3339)
+		result["merge_commit_sha"] = p.mergeCommitSHA
+	}
+	if p.autoMerge != "" {
+		result["auto_merge"] = map[string]interface{}{
+			"enabled_by":   userJSON(r, Login),
+			"merge_method": p.autoMerge,
+		}
+	}
+	return result
+}
+
+// nodeID はGraphQL APIで使用するPull RequestのノードIDを返します
+func (p *pull) nodeID() string {
+	return fmt.Sprintf("PR_%d", p.id)
+}
+
+// lookupPull はパスパラメータからPull Requestを取得します。存在しない場合は404を書き込みます
+func lookupPull(w http.ResponseWriter, r *http.Request, repo *repository) *pull {
+	number, err := strconv.Atoi(r.PathValue("number"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil
+	}
+	p, ok := repo.pulls[number]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return nil
+	}
+	return p
+}
+
+// handleCreatePull は POST /repos/{owner}/{repo}/pulls を処理します
+func (s *Server) handleCreatePull(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title               string `json:"title"`
+		Head                string `json:"head"`
+		Base                string `json:"base"`
+		Body                string `json:"body"`
+		Draft               bool   `json:"draft"`
+		MaintainerCanModify bool   `json:"maintainer_can_modify"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+
+	// "owner:branch" 形式のheadはブランチ名のみを使用する
+	if _, branch, ok := strings.Cut(body.Head, ":"); ok {
+		body.Head = branch
+	}
+	if _, ok := repo.refs["refs/heads/"+body.Head]; !ok {
+		writeValidationError(w, "Validation Failed", "PullRequest", "head", "invalid")
+		return
+	}
+	if _, ok := repo.refs["refs/heads/"+body.Base]; !ok {
+		writeValidationError(w, "Validation Failed", "PullRequest", "base", "invalid")
+		return
+	}
+	if repo.refs["refs/heads/"+body.Head] == repo.refs["refs/heads/"+body.Base] {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"message": "Validation Failed",
+			"errors": []map[string]string{{
+				"resource": "PullRequest",
+				"code":     "custom",
+				"message":  fmt.Sprintf("No commits between %s and %s", body.Base, body.Head),
+			}},
+		})
+		return
+	}
+
+	p := s.createPull(repo, body.Head, body.Base, body.Title, body.Body, body.Draft, body.MaintainerCanModify)
+	writeJSON(w, http.StatusCreated, pullJSON(r, repo, p))
+}
+
+// handleGetPull は GET /repos/{owner}/{repo}/pulls/{number} を処理します
+// Accept ヘッダーでdiff形式が指定された場合はunified diffを返します
+func (s *Server) handleGetPull(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	// diff形式のメディアタイプが指定された場合はunified diffを返す
+	if strings.Contains(r.Header.Get("Accept"), "diff") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, unifiedDiff(repo.pullChanges(p)))
+		return
+	}
+	writeJSON(w, http.StatusOK, pullJSON(r, repo, p))
+}
+
+// handleListPulls は GET /repos/{owner}/{repo}/pulls を処理します
+// state / head ("user:branch" 形式) / base による絞り込みと sort / direction による並べ替えに対応します
+func (s *Server) handleListPulls(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+
+	state := query.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	var matched []*pull
+	for _, p := range repo.pulls {
+		switch {
+		case state != "all" && p.state != state,
+			query.Get("base") != "" && p.base != query.Get("base"),
+			query.Get("head") != "" && repo.owner+":"+p.head != query.Get("head"):
+			continue
+		}
+		matched = append(matched, p)
+	}
+
+	// GitHubと同様に、created (既定) 以外の並べ替えでは昇順が既定になる
+	sortKey := query.Get("sort")
+	key := func(p *pull) time.Time { return p.createdAt }
+	if sortKey == "updated" {
+		key = func(p *pull) time.Time { return p.updatedAt }
+	}
+	desc := query.Get("direction") == "desc" || (query.Get("direction") == "" && (sortKey == "" || sortKey == "created"))
+	sort.Slice(matched, func(a, b int) bool {
+		ka, kb := key(matched[a]), key(matched[b])
+		if ka.Equal(kb) {
+			return (matched[a].number > matched[b].number) == desc
+		}
+		return ka.After(kb) == desc
+	})
+
+	start, end := paginate(w, r, len(matched))
+	items := make([]map[string]interface{}, 0, end-start)
+	for _, p := range matched[start:end] {
+		items = append(items, pullJSON(r, repo, p))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// reviewJSON はレビューのレスポンスを作成します
+func reviewJSON(r *http.Request, repo *repository, p *pull, rv *review) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           rv.id,
+		"user":         userJSON(r, rv.user),
+		"body":         rv.body,
+		"state":        rv.state,
+		"commit_id":    rv.commitID,
+		"html_url":     fmt.Sprintf("%s/%s/pull/%d#pullrequestreview-%d", webURL(r), repo.fullName(), p.number, rv.id),
+		"submitted_at": rv.submittedAt.Format(time.RFC3339),
+	}
+}
+
+// handleCreateReview は POST /repos/{owner}/{repo}/pulls/{number}/reviews を処理します
+func (s *Server) handleCreateReview(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Body     string               `json:"body"`
+		Event    string               `json:"event"`
+		CommitID string               `json:"commit_id"`
+		Comments []draftReviewComment `json:"comments"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	var state string
+	switch body.Event {
+	case "APPROVE":
+		state = "APPROVED"
+	case "REQUEST_CHANGES":
+		state = "CHANGES_REQUESTED"
+	case "COMMENT":
+		state = "COMMENTED"
+	case "":
+		state = "PENDING"
+	default:
+		writeValidationError(w, "Validation Failed", "PullRequestReview", "event", "invalid")
+		return
+	}
+	if (body.Event == "REQUEST_CHANGES" || body.Event == "COMMENT" && len(body.Comments) == 0) && body.Body == "" {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"message": "Unprocessable Entity",
+			"errors":  []string{"Review body is required for this event"},
+		})
+		return
+	}
+
+	commitID := body.CommitID
+	if commitID == "" {
+		commitID = repo.refs["refs/heads/"+p.head]
+	} else if _, ok := repo.commits[commitID]; !ok {
+		writeValidationError(w, "Validation Failed", "PullRequestReview", "commit_id", "invalid")
+		return
+	}
+
+	// コメントをすべて検証してから作成し、一部だけが作成されないようにする
+	changes := repo.pullChanges(p)
+	hunks := make([]string, len(body.Comments))
+	for i := range body.Comments {
+		hunk, message := resolveReviewComment(changes, &body.Comments[i])
+		if message != "" {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"message": "Unprocessable Entity",
+				"errors":  []string{message},
+			})
+			return
+		}
+		hunks[i] = hunk
+	}
+
+	now := s.now()
+	rv := &review{
+		id:          s.newID(),
+		user:        Login,
+		body:        body.Body,
+		state:       state,
+		commitID:    commitID,
+		submittedAt: now,
+	}
+	p.reviews = append(p.reviews, rv)
+	for i, c := range body.Comments {
+		p.reviewComments = append(p.reviewComments, &reviewComment{
+			id:        s.newID(),
+			reviewID:  rv.id,
+			user:      Login,
+			body:      c.Body,
+			path:      c.Path,
+			line:      c.Line,
+			side:      c.Side,
+			startLine: c.StartLine,
+			startSide: c.StartSide,
+			commitID:  commitID,
+			diffHunk:  hunks[i],
+			createdAt: now,
+			updatedAt: now,
+		})
+	}
+	writeJSON(w, http.StatusOK, reviewJSON(r, repo, p, rv))
+}
+
+// handleUpdatePull は PATCH /repos/{owner}/{repo}/pulls/{number} を処理します
+func (s *Server) handleUpdatePull(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Title               *string `json:"title"`
+		Body                *string `json:"body"`
+		State               *string `json:"state"`
+		Base                *string `json:"base"`
+		MaintainerCanModify *bool   `json:"maintainer_can_modify"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	if body.State != nil && *body.State != "open" && *body.State != "closed" {
+		writeValidationError(w, "Validation Failed", "PullRequest", "state", "invalid")
+		return
+	}
+	if body.State != nil && *body.State == "open" && p.merged {
+		writeValidationError(w, "Validation Failed", "PullRequest", "state", "invalid")
+		return
+	}
+	if body.Base != nil {
+		if _, ok := repo.refs["refs/heads/"+*body.Base]; !ok {
+			writeValidationError(w, "Validation Failed", "PullRequest", "base", "invalid")
+			return
+		}
+	}
+
+	now := s.now()
+	if body.Title != nil {
+		p.title = *body.Title
+	}
+	if body.Body != nil {
+		p.body = *body.Body
+	}
+	if body.Base != nil {
+		p.base = *body.Base
+	}
+	if body.MaintainerCanModify != nil {
+		p.maintainerCanModify = *body.MaintainerCanModify
+	}
+	if body.State != nil && *body.State != p.state {
+		p.state = *body.State
+		p.closedAt = time.Time{}
+		if p.state == "closed" {
+			p.closedAt = now
+		}
+	}
+	p.updatedAt = now
+	writeJSON(w, http.StatusOK, pullJSON(r, repo, p))
+}
+
+// handleMergePull は PUT /repos/{owner}/{repo}/pulls/{number}/merge を処理します
+// ヘッドブランチのツリーでベースブランチにコミットを作成します (merge は2つの親を持つマージコミット)
+func (s *Server) handleMergePull(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		CommitTitle   string `json:"commit_title"`
+		CommitMessage string `json:"commit_message"`
+		SHA           string `json:"sha"`
+		MergeMethod   string `json:"merge_method"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	if body.MergeMethod == "" {
+		body.MergeMethod = "merge"
+	}
+	if !containsString([]string{"merge", "squash", "rebase"}, body.MergeMethod) {
+		writeValidationError(w, "Validation Failed", "PullRequest", "merge_method", "invalid")
+		return
+	}
+	if repo.mergeMethods != nil && !containsString(repo.mergeMethods, body.MergeMethod) {
+		// GitHubと同じく、リポジトリの設定で無効なマージ方法は405になる
+		messages := map[string]string{
+			"merge":  "Merge commits are not allowed on this repository.",
+			"squash": "Squash merges are not allowed on this repository.",
+			"rebase": "Rebase merges are not allowed on this repository.",
+		}
+		writeError(w, http.StatusMethodNotAllowed, messages[body.MergeMethod])
+		return
+	}
+	if p.state != "open" || p.draft || p.mergeableState != "clean" {
+		writeError(w, http.StatusMethodNotAllowed, "Pull Request is not mergeable")
+		return
+	}
+	headSHA := repo.refs["refs/heads/"+p.head]
+	if body.SHA != "" && body.SHA != headSHA {
+		writeError(w, http.StatusConflict, "Head branch was modified. Review and try the merge again.")
+		return
+	}
+
+	baseRef := "refs/heads/" + p.base
+	parents := []string{repo.refs[baseRef]}
+	title := fmt.Sprintf("%s (#%d)", p.title, p.number)
+	if body.MergeMethod == "merge" {
+		parents = append(parents, headSHA)
+		title = fmt.Sprintf("Merge pull request #%d from %s/%s", p.number, repo.owner, p.head)
+	}
+	if body.CommitTitle != "" {
+		title = body.CommitTitle
+	}
+	message := title
+	if body.CommitMessage != "" {
+		message += "\n\n" + body.CommitMessage
+	}
+
+	now := s.now()
+	sha := repo.putCommit(repo.commits[headSHA].tree, parents, message, now)
+	repo.refs[baseRef] = sha
+	p.state = "closed"
+	p.merged = true
+	p.mergedAt = now
+	p.closedAt = now
+	p.mergeCommitSHA = sha
+	p.autoMerge = ""
+	p.updatedAt = now
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sha":     sha,
+		"merged":  true,
+		"message": "Pull Request successfully merged",
+	})
+}
+
+// handleUpdatePullBranch は PUT /repos/{owner}/{repo}/pulls/{number}/update-branch を処理します
+// ベースブランチをヘッドブランチにマージするコミットを作成し、GitHubと同じく202を返します
+func (s *Server) handleUpdatePullBranch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ExpectedHeadSHA string `json:"expected_head_sha"`
+	}
+	if r.ContentLength != 0 && !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	headRef := "refs/heads/" + p.head
+	headSHA := repo.refs[headRef]
+	if body.ExpectedHeadSHA != "" && body.ExpectedHeadSHA != headSHA {
+		writeError(w, http.StatusUnprocessableEntity, "expected head sha didn't match current head ref.")
+		return
+	}
+	if p.mergeableState == "dirty" {
+		writeError(w, http.StatusUnprocessableEntity, "merge conflict between base and head")
+		return
+	}
+
+	// ヘッドブランチの変更を優先してベースブランチのファイルを取り込む
+	baseSHA := repo.refs["refs/heads/"+p.base]
+	merged := tree{}
+	for path, e := range repo.trees[repo.commits[baseSHA].tree] {
+		merged[path] = e
+	}
+	for path, e := range repo.trees[repo.commits[headSHA].tree] {
+		merged[path] = e
+	}
+	message := fmt.Sprintf("Merge branch '%s' into %s", p.base, p.head)
+	repo.refs[headRef] = repo.putCommit(repo.putTree(merged), []string{headSHA, baseSHA}, message, s.now())
+	if p.mergeableState == "behind" {
+		p.mergeableState = "clean"
+	}
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Updating pull request branch.",
+		"url":     fmt.Sprintf("%s/%s/pull/%d", webURL(r), repo.fullName(), p.number),
+	})
+}
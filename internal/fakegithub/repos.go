@@ -0,0 +1,191 @@
+package fakegithub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// userJSON はユーザーのレスポンスを作成します
+func userJSON(r *http.Request, login string) map[string]interface{} {
+	return map[string]interface{}{
+		"login":      login,
+		"id":         len(login) * 1000,
+		"avatar_url": fmt.Sprintf("%s/avatars/%s", baseURL(r), login),
+		"html_url":   fmt.Sprintf("%s/%s", webURL(r), login),
+		"type":       "User",
+	}
+}
+
+// repoJSON はリポジトリのレスポンスを作成します
+func repoJSON(r *http.Request, repo *repository) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":             repo.id,
+		"name":           repo.name,
+		"full_name":      repo.fullName(),
+		"description":    repo.description,
+		"private":        repo.private,
+		"fork":           repo.fork,
+		"owner":          userJSON(r, repo.owner),
+		"html_url":       fmt.Sprintf("%s/%s", webURL(r), repo.fullName()),
+		"clone_url":      fmt.Sprintf("%s/%s.git", webURL(r), repo.fullName()),
+		"ssh_url":        fmt.Sprintf("git@%s:%s.git", r.Host, repo.fullName()),
+		"default_branch": repo.defaultBranch,
+		"created_at":     repo.createdAt.Format(time.RFC3339),
+	}
+	if repo.parent != "" {
+		result["parent"] = map[string]interface{}{"full_name": repo.parent}
+	}
+	return result
+}
+
+// handleSearchRepositories は GET /search/repositories を処理します
+// クエリの user: / org: 修飾子はオーナーで、それ以外の語は名前と説明の部分一致で絞り込みます
+func (s *Server) handleSearchRepositories(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeValidationError(w, "Validation Failed", "Search", "q", "missing")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var owners, terms []string
+	for _, token := range strings.Fields(query) {
+		switch {
+		case strings.HasPrefix(token, "user:"), strings.HasPrefix(token, "org:"):
+			_, owner, _ := strings.Cut(token, ":")
+			owners = append(owners, owner)
+		case strings.Contains(token, ":"):
+			// その他の修飾子は無視する
+		default:
+			terms = append(terms, strings.ToLower(token))
+		}
+	}
+
+	var matched []*repository
+	for _, repo := range s.repos {
+		if len(owners) > 0 && !containsString(owners, repo.owner) {
+			continue
+		}
+		text := strings.ToLower(repo.name + " " + repo.description)
+		ok := true
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, repo)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].fullName() < matched[j].fullName() })
+
+	start, end := paginate(w, r, len(matched))
+	items := make([]map[string]interface{}, 0, end-start)
+	for _, repo := range matched[start:end] {
+		items = append(items, repoJSON(r, repo))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_count":        len(matched),
+		"incomplete_results": false,
+		"items":              items,
+	})
+}
+
+// handleGetAuthenticatedUser は GET /user を処理します
+// クラシックなPersonal Access Tokenと同様に、付与されたスコープをヘッダーで返します
+func (s *Server) handleGetAuthenticatedUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-OAuth-Scopes", Scopes)
+	writeJSON(w, http.StatusOK, userJSON(r, Login))
+}
+
+// handleCreateRepository は POST /user/repos と POST /orgs/{org}/repos を処理します
+func (s *Server) handleCreateRepository(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Private     bool   `json:"private"`
+		AutoInit    bool   `json:"auto_init"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		writeValidationError(w, "Repository creation failed.", "Repository", "name", "missing_field")
+		return
+	}
+
+	owner := Login
+	if org := r.PathValue("org"); org != "" {
+		owner = org
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.repos[owner+"/"+body.Name]; ok {
+		writeValidationError(w, "Repository creation failed.", "Repository", "name", "name already exists on this account")
+		return
+	}
+	repo := s.createRepo(owner, body.Name, body.Description, body.Private, body.AutoInit)
+	writeJSON(w, http.StatusCreated, repoJSON(r, repo))
+}
+
+// handleCreateFork は POST /repos/{owner}/{repo}/forks を処理します
+// 実際のAPIと同様にフォークは非同期に作成されるため202を返します
+func (s *Server) handleCreateFork(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Organization string `json:"organization"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source := s.lookupRepo(w, r)
+	if source == nil {
+		return
+	}
+
+	owner := Login
+	if body.Organization != "" {
+		owner = body.Organization
+	}
+	if existing, ok := s.repos[owner+"/"+source.name]; ok {
+		writeJSON(w, http.StatusAccepted, repoJSON(r, existing))
+		return
+	}
+
+	fork := s.createRepo(owner, source.name, source.description, source.private, false)
+	fork.fork = true
+	fork.parent = source.fullName()
+	fork.defaultBranch = source.defaultBranch
+	for k, v := range source.refs {
+		fork.refs[k] = v
+	}
+	for k, v := range source.commits {
+		fork.commits[k] = v
+	}
+	for k, v := range source.trees {
+		fork.trees[k] = v
+	}
+	for k, v := range source.blobs {
+		fork.blobs[k] = v
+	}
+	writeJSON(w, http.StatusAccepted, repoJSON(r, fork))
+}
+
+// containsString はスライスに値が含まれているかどうかを返します
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package fakegithub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reviewComment はPull Requestのレビューコメント (行へのコメント) を表します
+type reviewComment struct {
+	id        int64
+	reviewID  int64
+	inReplyTo int64 // スレッドの最初のコメントのID (返信の場合)
+	user      string
+	body      string
+	path      string
+	line      int
+	side      string
+	startLine int
+	startSide string
+	commitID  string
+	diffHunk  string
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// threadID はコメントが属するスレッドの最初のコメントのIDを返します
+func (c *reviewComment) threadID() int64 {
+	if c.inReplyTo != 0 {
+		return c.inReplyTo
+	}
+	return c.id
+}
+
+// threadNodeID はGraphQL APIで使用するレビュースレッドのノードIDを返します
+func threadNodeID(rootID int64) string {
+	return fmt.Sprintf("PRRT_%d", rootID)
+}
+
+// draftReviewComment はレビュー作成時に指定されるコメントを表します
+type draftReviewComment struct {
+	Path      string `json:"path"`
+	Position  int    `json:"position"`
+	Body      string `json:"body"`
+	Line      int    `json:"line"`
+	Side      string `json:"side"`
+	StartLine int    `json:"start_line"`
+	StartSide string `json:"start_side"`
+}
+
+// diffHunk はパッチのうち、指定された側の行を含むハンクの先頭からその行までを返します
+// 行がdiffに含まれない場合は ok が false になります
+func diffHunk(patch, side string, line int) (hunk string, ok bool) {
+	lines := strings.Split(patch, "\n")
+	start, oldLine, newLine := 0, 0, 0
+	for i, l := range lines {
+		if strings.HasPrefix(l, "@@") {
+			var oldCount, newCount int
+			fmt.Sscanf(l, "@@ -%d,%d +%d,%d @@", &oldLine, &oldCount, &newLine, &newCount)
+			start = i
+			continue
+		}
+		op := byte(' ')
+		if l != "" {
+			op = l[0]
+		}
+		if (side == "LEFT" && op != '+' && oldLine == line) || (side == "RIGHT" && op != '-' && newLine == line) {
+			return strings.Join(lines[start:i+1], "\n"), true
+		}
+		if op != '+' {
+			oldLine++
+		}
+		if op != '-' {
+			newLine++
+		}
+	}
+	return "", false
+}
+
+// resolveReviewComment はレビューコメントの位置をPull Requestの差分に対して検証し、diff_hunk を返します
+// 不正な場合はGitHubと同じエラーメッセージを返します
+func resolveReviewComment(changes []fileChange, c *draftReviewComment) (string, string) {
+	if c.Side == "" {
+		c.Side = "RIGHT"
+	}
+	if c.StartLine != 0 && c.StartSide == "" {
+		c.StartSide = c.Side
+	}
+	var change *fileChange
+	for i := range changes {
+		if changes[i].filename == c.Path {
+			change = &changes[i]
+		}
+	}
+	switch {
+	case change == nil:
+		return "", "Path could not be resolved"
+	case c.Body == "":
+		return "", "Body can't be blank"
+	case c.Side != "LEFT" && c.Side != "RIGHT", c.StartSide != "" && c.StartSide != "LEFT" && c.StartSide != "RIGHT":
+		return "", "Side must be LEFT or RIGHT"
+	case c.StartLine != 0 && c.StartSide == c.Side && c.StartLine >= c.Line:
+		return "", "start_line must precede the end line."
+	}
+	hunk, ok := diffHunk(change.patch, c.Side, c.Line)
+	if !ok {
+		return "", "Line could not be resolved"
+	}
+	if c.StartLine != 0 {
+		if _, ok := diffHunk(change.patch, c.StartSide, c.StartLine); !ok {
+			return "", "Start line could not be resolved"
+		}
+	}
+	return hunk, ""
+}
+
+// reviewCommentJSON はレビューコメントをGitHub APIのレスポンス形式に変換します
+func reviewCommentJSON(r *http.Request, repo *repository, p *pull, c *reviewComment) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":                     c.id,
+		"node_id":                fmt.Sprintf("PRRC_%d", c.id),
+		"pull_request_review_id": c.reviewID,
+		"user":                   userJSON(r, c.user),
+		"body":                   c.body,
+		"path":                   c.path,
+		"line":                   c.line,
+		"original_line":          c.line,
+		"side":                   c.side,
+		"commit_id":              c.commitID,
+		"original_commit_id":     c.commitID,
+		"diff_hunk":              c.diffHunk,
+		"subject_type":           "line",
+		"html_url":               fmt.Sprintf("%s/%s/pull/%d#discussion_r%d", webURL(r), repo.fullName(), p.number, c.id),
+		"created_at":             c.createdAt.Format(time.RFC3339),
+		"updated_at":             c.updatedAt.Format(time.RFC3339),
+	}
+	if c.inReplyTo != 0 {
+		result["in_reply_to_id"] = c.inReplyTo
+	}
+	if c.startLine != 0 {
+		result["start_line"] = c.startLine
+		result["original_start_line"] = c.startLine
+		result["start_side"] = c.startSide
+	}
+	return result
+}
+
+// handleListReviewComments は GET /repos/{owner}/{repo}/pulls/{number}/comments を処理します
+// sort (created, updated) / direction / since に対応します
+func (s *Server) handleListReviewComments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var since time.Time
+	if value := query.Get("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeValidationError(w, "Validation Failed", "PullRequestReviewComment", "since", "invalid")
+			return
+		}
+		since = t
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	var matched []*reviewComment
+	for _, c := range p.reviewComments {
+		if since.IsZero() || !c.updatedAt.Before(since) {
+			matched = append(matched, c)
+		}
+	}
+	key := func(c *reviewComment) time.Time { return c.createdAt }
+	if query.Get("sort") == "updated" {
+		key = func(c *reviewComment) time.Time { return c.updatedAt }
+	}
+	desc := query.Get("direction") == "desc"
+	sort.SliceStable(matched, func(i, j int) bool {
+		if desc {
+			return key(matched[i]).After(key(matched[j]))
+		}
+		return key(matched[i]).Before(key(matched[j]))
+	})
+
+	start, end := paginate(w, r, len(matched))
+	items := make([]map[string]interface{}, 0, end-start)
+	for _, c := range matched[start:end] {
+		items = append(items, reviewCommentJSON(r, repo, p, c))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// handleCreateReviewComment は POST /repos/{owner}/{repo}/pulls/{number}/comments を処理します
+// このフェイクでは in_reply_to を指定したスレッドへの返信のみに対応します
+func (s *Server) handleCreateReviewComment(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Body      string `json:"body"`
+		InReplyTo int64  `json:"in_reply_to"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.lookupRepo(w, r)
+	if repo == nil {
+		return
+	}
+	p := lookupPull(w, r, repo)
+	if p == nil {
+		return
+	}
+
+	if body.InReplyTo == 0 {
+		writeValidationError(w, "Validation Failed", "PullRequestReviewComment", "in_reply_to", "missing_field")
+		return
+	}
+	if body.Body == "" {
+		writeValidationError(w, "Validation Failed", "PullRequestReviewComment", "body", "missing_field")
+		return
+	}
+	var parent *reviewComment
+	for _, c := range p.reviewComments {
+		if c.id == body.InReplyTo {
+			parent = c
+		}
+	}
+	if parent == nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	// 返信への返信も、GitHubと同じくスレッドの最初のコメントへの返信として扱う
+	now := s.now()
+	reply := &reviewComment{
+		id:        s.newID(),
+		reviewID:  s.newID(),
+		inReplyTo: parent.threadID(),
+		user:      Login,
+		body:      body.Body,
+		path:      parent.path,
+		line:      parent.line,
+		side:      parent.side,
+		startLine: parent.startLine,
+		startSide: parent.startSide,
+		commitID:  parent.commitID,
+		diffHunk:  parent.diffHunk,
+		createdAt: now,
+		updatedAt: now,
+	}
+	p.reviewComments = append(p.reviewComments, reply)
+	writeJSON(w, http.StatusCreated, reviewCommentJSON(r, repo, p, reply))
+}
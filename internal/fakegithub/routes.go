@@ -0,0 +1,66 @@
+package fakegithub
+
+import "net/http"
+
+// routes はエンドポイントを登録します
+func (s *Server) routes(mux *http.ServeMux) {
+	// ユーザー
+	mux.HandleFunc("GET /user", s.handleGetAuthenticatedUser)
+
+	// GitHub App
+	mux.HandleFunc("GET /repos/{owner}/{repo}/installation", s.handleGetInstallation)
+	mux.HandleFunc("GET /orgs/{org}/installation", s.handleGetInstallation)
+	mux.HandleFunc("GET /users/{user}/installation", s.handleGetInstallation)
+	mux.HandleFunc("POST /app/installations/{id}/access_tokens", s.handleCreateInstallationToken)
+
+	// 検索
+	mux.HandleFunc("GET /search/code", s.handleSearchCode)
+	mux.HandleFunc("GET /search/issues", s.handleSearchIssues)
+	mux.HandleFunc("GET /search/commits", s.handleSearchCommits)
+	mux.HandleFunc("GET /search/users", s.handleSearchUsers)
+
+	// リポジトリ
+	mux.HandleFunc("GET /search/repositories", s.handleSearchRepositories)
+	mux.HandleFunc("POST /user/repos", s.handleCreateRepository)
+	mux.HandleFunc("POST /orgs/{org}/repos", s.handleCreateRepository)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/forks", s.handleCreateFork)
+
+	// ファイル
+	mux.HandleFunc("GET /repos/{owner}/{repo}/contents/{path...}", s.handleGetContents)
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/contents/{path...}", s.handlePutContents)
+
+	// Git Data
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/ref/{ref...}", s.handleGetRef)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/refs", s.handleCreateRef)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/git/refs/{ref...}", s.handleUpdateRef)
+	mux.HandleFunc("DELETE /repos/{owner}/{repo}/git/refs/{ref...}", s.handleDeleteRef)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/commits/{sha}", s.handleGetCommit)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/commits", s.handleCreateCommit)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/trees/{sha}", s.handleGetTree)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/git/trees", s.handleCreateTree)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/blobs/{sha}", s.handleGetBlob)
+
+	// Pull Request
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls", s.handleListPulls)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls", s.handleCreatePull)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls/{number}", s.handleGetPull)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/pulls/{number}", s.handleUpdatePull)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls/{number}/files", s.handleListPullFiles)
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/pulls/{number}/merge", s.handleMergePull)
+	mux.HandleFunc("PUT /repos/{owner}/{repo}/pulls/{number}/update-branch", s.handleUpdatePullBranch)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls/{number}/reviews", s.handleCreateReview)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/pulls/{number}/comments", s.handleListReviewComments)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/pulls/{number}/comments", s.handleCreateReviewComment)
+
+	// GraphQL (GitHub Enterprise Serverでは /api/graphql)
+	mux.HandleFunc("POST /graphql", s.handleGraphQL)
+	mux.HandleFunc("POST /api/graphql", s.handleGraphQL)
+
+	// Issue
+	mux.HandleFunc("GET /repos/{owner}/{repo}/issues", s.handleListIssues)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/issues", s.handleCreateIssue)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/issues/{number}", s.handleGetIssue)
+	mux.HandleFunc("PATCH /repos/{owner}/{repo}/issues/{number}", s.handleUpdateIssue)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/issues/{number}/comments", s.handleListIssueComments)
+	mux.HandleFunc("POST /repos/{owner}/{repo}/issues/{number}/comments", s.handleCreateIssueComment)
+}
@@ -0,0 +1,368 @@
+package fakegithub
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// searchQuery は検索クエリを修飾子と検索語に分けたものです
+type searchQuery struct {
+	qualifiers map[string][]string
+	terms      []string
+}
+
+// parseSearchQuery は検索クエリを解析します
+// "name:value" 形式の語は修飾子、それ以外は小文字にした検索語として扱います
+func parseSearchQuery(query string) searchQuery {
+	q := searchQuery{qualifiers: map[string][]string{}}
+	for _, token := range strings.Fields(query) {
+		if name, value, ok := strings.Cut(token, ":"); ok && name != "" && value != "" {
+			q.qualifiers[strings.ToLower(name)] = append(q.qualifiers[strings.ToLower(name)], value)
+			continue
+		}
+		q.terms = append(q.terms, strings.ToLower(token))
+	}
+	return q
+}
+
+// matchesText はすべての検索語がテキストに含まれているかどうかを判断します
+func (q searchQuery) matchesText(text string) bool {
+	text = strings.ToLower(text)
+	for _, term := range q.terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesRepo は repo: / user: / org: 修飾子にリポジトリが一致するかどうかを判断します
+func (q searchQuery) matchesRepo(repo *repository) bool {
+	if repos := q.qualifiers["repo"]; len(repos) > 0 && !containsString(repos, repo.fullName()) {
+		return false
+	}
+	owners := append(append([]string(nil), q.qualifiers["user"]...), q.qualifiers["org"]...)
+	return len(owners) == 0 || containsString(owners, repo.owner)
+}
+
+// has は修飾子に値が指定されているかどうかを判断します
+func (q searchQuery) has(name, value string) bool {
+	return containsString(q.qualifiers[name], value)
+}
+
+// sortedRepos はリポジトリを名前順に返します (ロック取得済みで呼び出すこと)
+func (s *Server) sortedRepos() []*repository {
+	repos := make([]*repository, 0, len(s.repos))
+	for _, repo := range s.repos {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].fullName() < repos[j].fullName() })
+	return repos
+}
+
+// writeSearchResult は検索結果の該当ページを書き込みます
+func writeSearchResult(w http.ResponseWriter, r *http.Request, items []map[string]interface{}) {
+	start, end := paginate(w, r, len(items))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total_count":        len(items),
+		"incomplete_results": false,
+		"items":              items[start:end],
+	})
+}
+
+// requireQuery は q パラメータを解析します。空の場合は422を書き込みます
+func requireQuery(w http.ResponseWriter, r *http.Request) (searchQuery, bool) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeValidationError(w, "Validation Failed", "Search", "q", "missing")
+		return searchQuery{}, false
+	}
+	return parseSearchQuery(query), true
+}
+
+// wantsTextMatch はリクエストが一致箇所の断片 (text_matches) を要求しているかどうかを判断します
+func wantsTextMatch(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text-match")
+}
+
+// handleSearchCode は GET /search/code を処理します
+// デフォルトブランチのファイルのうち、すべての検索語を内容に含むものを返します
+// repo: / user: / org: / path: / extension: 修飾子で絞り込めます
+func (s *Server) handleSearchCode(w http.ResponseWriter, r *http.Request) {
+	q, ok := requireQuery(w, r)
+	if !ok {
+		return
+	}
+	if len(q.terms) == 0 {
+		writeValidationError(w, "Validation Failed", "Search", "q", "must include at least one search term")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []map[string]interface{}
+	for _, repo := range s.sortedRepos() {
+		if !q.matchesRepo(repo) {
+			continue
+		}
+		t, ok := repo.treeAt(repo.defaultBranch)
+		if !ok {
+			continue
+		}
+		paths := make([]string, 0, len(t))
+		for p := range t {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+
+		for _, filePath := range paths {
+			if prefixes := q.qualifiers["path"]; len(prefixes) > 0 && !strings.HasPrefix(filePath, strings.Trim(prefixes[0], "/")) {
+				continue
+			}
+			if exts := q.qualifiers["extension"]; len(exts) > 0 && strings.TrimPrefix(path.Ext(filePath), ".") != exts[0] {
+				continue
+			}
+			e := t[filePath]
+			content := string(repo.blobs[e.sha])
+			if !q.matchesText(content) {
+				continue
+			}
+			item := map[string]interface{}{
+				"name":       path.Base(filePath),
+				"path":       filePath,
+				"sha":        e.sha,
+				"html_url":   fmt.Sprintf("%s/%s/blob/%s/%s", webURL(r), repo.fullName(), repo.defaultBranch, filePath),
+				"repository": repoJSON(r, repo),
+			}
+			if wantsTextMatch(r) {
+				item["text_matches"] = []interface{}{textMatchJSON("FileContent", "content", content, q.terms[0])}
+			}
+			items = append(items, item)
+		}
+	}
+	writeSearchResult(w, r, items)
+}
+
+// textMatchJSON は最初に検索語が現れる行を断片とした一致箇所を作成します
+func textMatchJSON(objectType, property, text, term string) map[string]interface{} {
+	index := strings.Index(strings.ToLower(text), term)
+	lineStart := strings.LastIndex(text[:index], "\n") + 1
+	lineEnd := len(text)
+	if n := strings.Index(text[index:], "\n"); n >= 0 {
+		lineEnd = index + n
+	}
+	fragment := text[lineStart:lineEnd]
+	begin := index - lineStart
+	return map[string]interface{}{
+		"object_type": objectType,
+		"property":    property,
+		"fragment":    fragment,
+		"matches": []interface{}{map[string]interface{}{
+			"text":    fragment[begin : begin+len(term)],
+			"indices": []int{begin, begin + len(term)},
+		}},
+	}
+}
+
+// searchIssue は検索対象のIssueまたはPull Requestです
+type searchIssue struct {
+	repo      *repository
+	number    int
+	title     string
+	body      string
+	state     string
+	user      string
+	labels    []string
+	comments  int
+	isPull    bool
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+// issueSearchJSON はIssue検索の結果を作成します
+func issueSearchJSON(r *http.Request, i searchIssue) map[string]interface{} {
+	kind := "issues"
+	if i.isPull {
+		kind = "pull"
+	}
+	labels := make([]interface{}, 0, len(i.labels))
+	for _, label := range i.labels {
+		labels = append(labels, map[string]interface{}{"name": label})
+	}
+	result := map[string]interface{}{
+		"number":         i.number,
+		"title":          i.title,
+		"body":           i.body,
+		"state":          i.state,
+		"user":           userJSON(r, i.user),
+		"labels":         labels,
+		"comments":       i.comments,
+		"html_url":       fmt.Sprintf("%s/%s/%s/%d", webURL(r), i.repo.fullName(), kind, i.number),
+		"repository_url": fmt.Sprintf("%s/repos/%s", baseURL(r), i.repo.fullName()),
+		"created_at":     i.createdAt.Format(time.RFC3339),
+		"updated_at":     i.updatedAt.Format(time.RFC3339),
+	}
+	if i.isPull {
+		result["pull_request"] = map[string]interface{}{
+			"url":      fmt.Sprintf("%s/repos/%s/pulls/%d", baseURL(r), i.repo.fullName(), i.number),
+			"html_url": result["html_url"],
+		}
+	}
+	return result
+}
+
+// searchIssues は検索対象のIssueとPull Requestを列挙します (ロック取得済みで呼び出すこと)
+func (s *Server) searchIssues() []searchIssue {
+	var issues []searchIssue
+	for _, repo := range s.sortedRepos() {
+		for _, p := range repo.pulls {
+			issues = append(issues, searchIssue{
+				repo: repo, number: p.number, title: p.title, body: p.body, state: p.state,
+				user: p.user, isPull: true, createdAt: p.createdAt, updatedAt: p.updatedAt,
+			})
+		}
+		for _, i := range repo.issues {
+			issues = append(issues, searchIssue{
+				repo: repo, number: i.number, title: i.title, body: i.body, state: i.state,
+				user: i.user, labels: i.labels, comments: len(i.comments), createdAt: i.createdAt, updatedAt: i.updatedAt,
+			})
+		}
+	}
+	return issues
+}
+
+// handleSearchIssues は GET /search/issues を処理します
+// is:pr / is:issue / is:open / is:closed / state: / author: / repo: / user: / org: 修飾子と、
+// sort=created / updated による並べ替えに対応します (既定は番号順)
+func (s *Server) handleSearchIssues(w http.ResponseWriter, r *http.Request) {
+	q, ok := requireQuery(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []searchIssue
+	for _, i := range s.searchIssues() {
+		switch {
+		case !q.matchesRepo(i.repo),
+			q.has("is", "pr") && !i.isPull,
+			q.has("is", "issue") && i.isPull,
+			(q.has("is", "open") || q.has("state", "open")) && i.state != "open",
+			(q.has("is", "closed") || q.has("state", "closed")) && i.state != "closed",
+			len(q.qualifiers["author"]) > 0 && !containsString(q.qualifiers["author"], i.user),
+			!q.matchesText(i.title + " " + i.body):
+			continue
+		}
+		matched = append(matched, i)
+	}
+
+	key := func(i searchIssue) time.Time { return time.Time{} }
+	switch r.URL.Query().Get("sort") {
+	case "created":
+		key = func(i searchIssue) time.Time { return i.createdAt }
+	case "updated":
+		key = func(i searchIssue) time.Time { return i.updatedAt }
+	}
+	desc := r.URL.Query().Get("order") != "asc"
+	sort.SliceStable(matched, func(a, b int) bool {
+		ka, kb := key(matched[a]), key(matched[b])
+		if ka.Equal(kb) {
+			if matched[a].repo != matched[b].repo {
+				return matched[a].repo.fullName() < matched[b].repo.fullName()
+			}
+			return matched[a].number < matched[b].number
+		}
+		return ka.After(kb) == desc
+	})
+
+	items := make([]map[string]interface{}, 0, len(matched))
+	for _, i := range matched {
+		items = append(items, issueSearchJSON(r, i))
+	}
+	writeSearchResult(w, r, items)
+}
+
+// handleSearchCommits は GET /search/commits を処理します
+// リポジトリのコミットのうち、すべての検索語をメッセージに含むものを新しい順に返します
+func (s *Server) handleSearchCommits(w http.ResponseWriter, r *http.Request) {
+	q, ok := requireQuery(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type found struct {
+		repo *repository
+		c    *commit
+	}
+	var matched []found
+	for _, repo := range s.sortedRepos() {
+		if !q.matchesRepo(repo) {
+			continue
+		}
+		for _, c := range repo.commits {
+			if q.matchesText(c.message) {
+				matched = append(matched, found{repo, c})
+			}
+		}
+	}
+	desc := r.URL.Query().Get("order") != "asc"
+	sort.SliceStable(matched, func(a, b int) bool {
+		if matched[a].c.date.Equal(matched[b].c.date) {
+			return matched[a].c.sha < matched[b].c.sha
+		}
+		return matched[a].c.date.After(matched[b].c.date) == desc
+	})
+
+	items := make([]map[string]interface{}, 0, len(matched))
+	for _, m := range matched {
+		items = append(items, map[string]interface{}{
+			"sha":      m.c.sha,
+			"html_url": fmt.Sprintf("%s/%s/commit/%s", webURL(r), m.repo.fullName(), m.c.sha),
+			"commit": map[string]interface{}{
+				"message": m.c.message,
+				"author":  map[string]interface{}{"name": m.repo.owner, "date": m.c.date.Format(time.RFC3339)},
+			},
+			"author":     userJSON(r, m.repo.owner),
+			"repository": repoJSON(r, m.repo),
+		})
+	}
+	writeSearchResult(w, r, items)
+}
+
+// handleSearchUsers は GET /search/users を処理します
+// 認証済みユーザーとリポジトリのオーナーのうち、ログイン名にすべての検索語を含むものを返します
+func (s *Server) handleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	q, ok := requireQuery(w, r)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logins := []string{Login}
+	for _, repo := range s.sortedRepos() {
+		if !containsString(logins, repo.owner) {
+			logins = append(logins, repo.owner)
+		}
+	}
+	sort.Strings(logins)
+
+	var items []map[string]interface{}
+	for _, login := range logins {
+		if q.matchesText(login) {
+			items = append(items, userJSON(r, login))
+		}
+	}
+	writeSearchResult(w, r, items)
+}
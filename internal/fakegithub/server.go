@@ -0,0 +1,384 @@
+// Package fakegithub はテスト用のインメモリGitHub REST APIサーバーを提供します
+//
+// リポジトリ、ref、コミット、ツリー、blob、Pull Request、レビュー、GitHub Appのインストールをメモリ上に保持し、
+// operations パッケージが使用するエンドポイントを httptest サーバーとして提供します。
+package fakegithub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Login は認証済みユーザーとして扱われるログイン名です
+const Login = "octocat"
+
+// Scopes は認証済みユーザーのトークンに付与されているスコープです
+const Scopes = "repo, read:org"
+
+// Fault はリクエストに対して強制的に返すエラー応答を表します
+type Fault struct {
+	// Method が空の場合はすべてのメソッドに一致します
+	Method string
+	// Path はクエリを除いたリクエストパスです (例: /repos/o/r/git/refs/heads/main)
+	Path string
+	// Status は返すHTTPステータスコードです
+	Status int
+	// Message はレスポンスボディのmessageです
+	Message string
+	// Body を指定するとMessageの代わりにそのままJSONとして返します
+	Body interface{}
+	// Header はレスポンスに追加するヘッダーです
+	Header http.Header
+	// Times は適用する回数です (0の場合は1回)
+	Times int
+}
+
+// Server はインメモリのGitHub APIサーバーです
+type Server struct {
+	*httptest.Server
+
+	// BeforeRequest はリクエストを処理する直前に呼び出されます
+	// 並行更新などの状況を再現するために使用します
+	BeforeRequest func(r *http.Request)
+
+	// AppTokenLifetime は発行するインストールトークンの有効期間です (0の場合は DefaultAppTokenLifetime)
+	AppTokenLifetime time.Duration
+
+	mu       sync.Mutex
+	repos    map[string]*repository
+	nextID   int64
+	requests []string
+	urls     []string
+	auths    []string
+	faults   []*Fault
+	// notModified は304を返したリクエストの数です
+	notModified int
+	clock       time.Time
+	app         *app
+}
+
+// New は新しいフェイクGitHubサーバーを起動します
+// 使用後はCloseを呼び出してください
+func New() *Server {
+	s := &Server{
+		repos:  make(map[string]*repository),
+		nextID: 1000,
+		clock:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	mux := http.NewServeMux()
+	s.routes(mux)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// HTTPClient はすべてのリクエストをこのサーバーに転送するHTTPクライアントを返します
+// api.github.com 宛てのリクエストもホストを書き換えてこのサーバーで処理します
+func (s *Server) HTTPClient() *http.Client {
+	target, _ := url.Parse(s.URL)
+	return &http.Client{
+		Transport: &rewriteTransport{target: target, base: s.Client().Transport},
+	}
+}
+
+// rewriteTransport はリクエストの宛先をフェイクサーバーに書き換えます
+type rewriteTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	original := req
+	req = req.Clone(req.Context())
+	req.Header.Set(originalURLHeader, original.URL.String())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil {
+		// 呼び出し元からは元のホストに接続したように見せる
+		resp.Request = original
+	}
+	return resp, err
+}
+
+// originalURLHeader は書き換え前のリクエストURLをサーバーに伝えるヘッダーです
+const originalURLHeader = "X-Fakegithub-Original-Url"
+
+// Requests はこれまでに受け付けたリクエストを "METHOD /path" の形式で返します
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// RequestURLs はこれまでに受け付けたリクエストの書き換え前のURLを返します
+func (s *Server) RequestURLs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.urls...)
+}
+
+// ResetRequests は記録済みのリクエストを消去します
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.urls = nil
+	s.auths = nil
+	s.notModified = 0
+}
+
+// NotModified はこれまでに304 Not Modifiedを返したリクエストの数を返します
+func (s *Server) NotModified() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notModified
+}
+
+// AddFault は次に一致するリクエストに対してエラー応答を返すよう設定します
+func (s *Server) AddFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.faults = append(s.faults, &f)
+}
+
+// middleware はリクエストの記録、認証の確認、フォールトの注入を行います
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// GitHub Enterprise Server形式の /api/v3 プレフィックスは取り除いて処理する
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/api/v3")
+
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		if original := r.Header.Get(originalURLHeader); original != "" {
+			s.urls = append(s.urls, original)
+		} else {
+			s.urls = append(s.urls, baseURL(r)+r.URL.RequestURI())
+		}
+		s.auths = append(s.auths, r.Header.Get("Authorization"))
+		fault := s.takeFault(r)
+		validToken := s.checkInstallationToken(r)
+		s.mu.Unlock()
+
+		if s.BeforeRequest != nil {
+			s.BeforeRequest(r)
+		}
+
+		if fault != nil {
+			for key, values := range fault.Header {
+				for _, v := range values {
+					w.Header().Add(key, v)
+				}
+			}
+			if fault.Body != nil {
+				writeJSON(w, fault.Status, fault.Body)
+			} else {
+				writeError(w, fault.Status, fault.Message)
+			}
+			return
+		}
+
+		if r.Header.Get("Authorization") == "" {
+			writeError(w, http.StatusUnauthorized, "Requires authentication")
+			return
+		}
+		if !validToken {
+			writeError(w, http.StatusUnauthorized, "Bad credentials")
+			return
+		}
+		if strings.HasPrefix(bearerToken(r), "ghs_") && (r.URL.Path == "/user" || strings.HasPrefix(r.URL.Path, "/user/")) {
+			// インストールトークンはユーザーとして認証されないため、GitHubと同じく /user のAPIは使用できない
+			writeError(w, http.StatusForbidden, "Resource not accessible by integration")
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			s.serveConditional(w, r, next)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveConditional はGETリクエストの成功レスポンスに本文から求めたETagを付けます
+// If-None-Matchが一致する場合は、GitHubと同じく本文のない304を返します
+func (s *Server) serveConditional(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	rec := httptest.NewRecorder()
+	next.ServeHTTP(rec, r)
+	for key, values := range rec.Header() {
+		w.Header()[key] = values
+	}
+	if rec.Code != http.StatusOK {
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+		return
+	}
+
+	sum := sha256.Sum256(rec.Body.Bytes())
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		s.mu.Lock()
+		s.notModified++
+		s.mu.Unlock()
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(rec.Body.Bytes())
+}
+
+// takeFault はリクエストに一致するフォールトを取り出します (ロック取得済みで呼び出すこと)
+func (s *Server) takeFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != r.URL.Path {
+			continue
+		}
+		f.Times--
+		if f.Times <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		return f
+	}
+	return nil
+}
+
+// Advance はフェイクサーバー内の時刻を進めます
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = s.clock.Add(d)
+}
+
+// now はフェイクサーバー内の時刻を1秒進めて返します (ロック取得済みで呼び出すこと)
+func (s *Server) now() time.Time {
+	s.clock = s.clock.Add(time.Second)
+	return s.clock
+}
+
+// newID は新しいIDを採番します (ロック取得済みで呼び出すこと)
+func (s *Server) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// baseURL はリクエストを受けたサーバーのURLを返します
+// HTTPClient経由のリクエストでは書き換え前のホストを使用します
+func baseURL(r *http.Request) string {
+	if original, err := url.Parse(r.Header.Get(originalURLHeader)); err == nil && original.Host != "" {
+		return original.Scheme + "://" + original.Host
+	}
+	return "http://" + r.Host
+}
+
+// webURL はhtml_urlなどに使用するWeb画面のURLを返します
+// api.github.com 宛てのリクエストでは github.com を返します
+func webURL(r *http.Request) string {
+	base := baseURL(r)
+	if base == "https://api.github.com" {
+		return "https://github.com"
+	}
+	return base
+}
+
+// writeJSON はJSONレスポンスを書き込みます
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError はGitHub形式のエラーレスポンスを書き込みます
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"message":           message,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
+
+// writeValidationError はフィールドエラーを含む422レスポンスを書き込みます
+func writeValidationError(w http.ResponseWriter, message, resource, field, code string) {
+	writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"message": message,
+		"errors": []map[string]string{{
+			"resource": resource,
+			"field":    field,
+			"code":     code,
+		}},
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
+
+// decodeBody はリクエストボディをJSONとしてデコードします
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Problems parsing JSON: %v", err))
+		return false
+	}
+	return true
+}
+
+// paginate はpage/per_pageクエリに従って範囲を計算し、Linkヘッダーを設定します
+func paginate(w http.ResponseWriter, r *http.Request, total int) (start, end int) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = 30
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+
+	lastPage := (total + perPage - 1) / perPage
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	link := func(p int, rel string) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", baseURL(r), r.URL.Path, q.Encode(), rel)
+	}
+	var links []string
+	if page < lastPage {
+		links = append(links, link(page+1, "next"), link(lastPage, "last"))
+	}
+	if page > 1 {
+		links = append(links, link(1, "first"), link(page-1, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	start = (page - 1) * perPage
+	if start > total {
+		start = total
+	}
+	end = start + perPage
+	if end > total {
+		end = total
+	}
+	return start, end
+}
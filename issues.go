@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// issueTarget はツール引数から owner / repo / issue_number を取得します
+func issueTarget(args map[string]interface{}) (owner, repo string, number int, err error) {
+	owner, ok := args["owner"].(string)
+	if !ok {
+		return "", "", 0, fmt.Errorf("owner must be a string")
+	}
+	repo, ok = args["repo"].(string)
+	if !ok {
+		return "", "", 0, fmt.Errorf("repo must be a string")
+	}
+	numberFloat, ok := args["issue_number"].(float64)
+	if !ok {
+		return "", "", 0, fmt.Errorf("issue_number must be a number")
+	}
+	return owner, repo, int(numberFloat), nil
+}
+
+// stringArrayArgument は文字列の配列の引数を取得します
+// 引数が指定されていない場合は ok が false になります
+func stringArrayArgument(args map[string]interface{}, name string) (values []string, ok bool, err error) {
+	raw, ok := args[name]
+	if !ok {
+		return nil, false, nil
+	}
+	items, isArray := raw.([]interface{})
+	if !isArray {
+		return nil, false, fmt.Errorf("%s must be an array of strings", name)
+	}
+	values = make([]string, 0, len(items))
+	for _, item := range items {
+		value, isString := item.(string)
+		if !isString {
+			return nil, false, fmt.Errorf("%s must be an array of strings", name)
+		}
+		values = append(values, value)
+	}
+	return values, true, nil
+}
+
+// timeArgument はRFC 3339形式の日時の引数を取得します
+// 引数が指定されていない場合はゼロ値を返します
+func timeArgument(args map[string]interface{}, name string) (time.Time, error) {
+	raw, ok := args[name]
+	if !ok {
+		return time.Time{}, nil
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s must be a string", name)
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp (e.g. 2024-01-02T15:04:05Z)", name)
+	}
+	return t, nil
+}
+
+// handleCreateIssue はIssue作成リクエストを処理します
+func handleCreateIssue(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, ok := args["owner"].(string)
+	if !ok {
+		return toolResultArgumentError("owner must be a string"), nil
+	}
+
+	repo, ok := args["repo"].(string)
+	if !ok {
+		return toolResultArgumentError("repo must be a string"), nil
+	}
+
+	title, ok := args["title"].(string)
+	if !ok {
+		return toolResultArgumentError("title must be a string"), nil
+	}
+
+	options := operations.CreateIssueOptions{Owner: owner, Repo: repo, Title: title}
+	if b, ok := args["body"].(string); ok {
+		options.Body = b
+	}
+	if options.Labels, _, err = stringArrayArgument(args, "labels"); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if options.Assignees, _, err = stringArrayArgument(args, "assignees"); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if m, ok := args["milestone"].(float64); ok {
+		options.Milestone = int(m)
+	}
+
+	// Issue作成の実行
+	result, err := operations.CreateIssue(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleGetIssue はIssue取得リクエストを処理します
+func handleGetIssue(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	owner, repo, number, err := issueTarget(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// Issue取得の実行
+	result, err := operations.GetIssue(ctx, operations.GetIssueOptions{
+		Owner:       owner,
+		Repo:        repo,
+		IssueNumber: number,
+	}, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleListIssues はIssue一覧の取得リクエストを処理します
+func handleListIssues(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, ok := args["owner"].(string)
+	if !ok {
+		return toolResultArgumentError("owner must be a string"), nil
+	}
+
+	repo, ok := args["repo"].(string)
+	if !ok {
+		return toolResultArgumentError("repo must be a string"), nil
+	}
+
+	options := operations.ListIssuesOptions{Owner: owner, Repo: repo}
+	if state, ok := args["state"].(string); ok {
+		if state != "open" && state != "closed" && state != "all" {
+			return toolResultArgumentError("state must be open, closed or all"), nil
+		}
+		options.State = state
+	}
+	if options.Labels, _, err = stringArrayArgument(args, "labels"); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if assignee, ok := args["assignee"].(string); ok {
+		options.Assignee = assignee
+	}
+	if options.Since, err = timeArgument(args, "since"); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if sort, ok := args["sort"].(string); ok {
+		options.Sort = sort
+	}
+	if direction, ok := args["direction"].(string); ok {
+		if direction != "asc" && direction != "desc" {
+			return toolResultArgumentError("direction must be asc or desc"), nil
+		}
+		options.Direction = direction
+	}
+	if options.PageOptions, err = pageOptionsFromArguments(args); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// Issue一覧の取得の実行
+	result, err := operations.ListIssues(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleUpdateIssue はIssue更新リクエストを処理します
+func handleUpdateIssue(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := issueTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.UpdateIssueOptions{Owner: owner, Repo: repo, IssueNumber: number}
+	for name, target := range map[string]**string{
+		"title":        &options.Title,
+		"body":         &options.Body,
+		"state":        &options.State,
+		"state_reason": &options.StateReason,
+	} {
+		raw, ok := args[name]
+		if !ok {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return toolResultArgumentError(fmt.Sprintf("%s must be a string", name)), nil
+		}
+		*target = &value
+	}
+	if options.State != nil && *options.State != "open" && *options.State != "closed" {
+		return toolResultArgumentError("state must be open or closed"), nil
+	}
+	for name, target := range map[string]**[]string{"labels": &options.Labels, "assignees": &options.Assignees} {
+		values, ok, err := stringArrayArgument(args, name)
+		if err != nil {
+			return toolResultArgumentError(err.Error()), nil
+		}
+		if ok {
+			*target = &values
+		}
+	}
+	if raw, ok := args["milestone"]; ok {
+		m, ok := raw.(float64)
+		if !ok || m < 0 {
+			return toolResultArgumentError("milestone must be a milestone number, or 0 to remove it"), nil
+		}
+		milestone := int(m)
+		options.Milestone = &milestone
+	}
+
+	// Issue更新の実行
+	result, err := operations.UpdateIssue(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleAddIssueComment はIssueコメント追加リクエストを処理します
+func handleAddIssueComment(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := issueTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	body, ok := args["body"].(string)
+	if !ok {
+		return toolResultArgumentError("body must be a string"), nil
+	}
+
+	// コメント追加の実行
+	result, err := operations.AddIssueComment(ctx, operations.AddIssueCommentOptions{
+		Owner:       owner,
+		Repo:        repo,
+		IssueNumber: number,
+		Body:        body,
+	}, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleListIssueComments はIssueコメント一覧の取得リクエストを処理します
+func handleListIssueComments(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := issueTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.ListIssueCommentsOptions{Owner: owner, Repo: repo, IssueNumber: number}
+	if options.Since, err = timeArgument(args, "since"); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if options.PageOptions, err = pageOptionsFromArguments(args); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// コメント一覧の取得の実行
+	result, err := operations.ListIssueComments(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
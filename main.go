@@ -3,9 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -15,11 +23,115 @@ import (
 
 // GitHubMCPServer はGitHub MCP Serverのラッパー構造体
 type GitHubMCPServer struct {
-	server *server.MCPServer
+	server  *server.MCPServer
+	opts    ServerOptions
+	drainer *drainer
+}
+
+// ServerOptions はGitHub MCP Serverの設定を表します
+type ServerOptions struct {
+	// ToolTimeout は各ツール呼び出しのデフォルトのタイムアウトです (0の場合は無制限)
+	ToolTimeout time.Duration
+	// ToolTimeouts はツール名ごとのタイムアウトで、ToolTimeoutより優先されます
+	ToolTimeouts map[string]time.Duration
+	// GitHubHost はGitHub APIの既定の接続先です (ゼロ値は github.com)
+	// SSEモードではAllowedGitHubHostsに含まれる接続先にリクエストヘッダーで上書きできます
+	GitHubHost common.GitHubHost
+	// AllowedGitHubHosts はリクエストヘッダーで指定できる接続先のホスト名です (空の場合は上書きできない)
+	AllowedGitHubHosts common.HostAllowlist
+	// ReadOnly が true の場合は変更を伴わないツールのみを登録します
+	ReadOnly bool
+	// Toolsets は登録するツールセットの一覧です (nilの場合はすべて)
+	// SSEモードではリクエストヘッダーでさらに絞り込むことができます
+	Toolsets []string
+	// Policy はツールが操作できるリポジトリを制限します (nilの場合は制限なし)
+	Policy *common.Policy
+	// ResourceRepositories はファイルツリーをresources/listに表示するリポジトリです
+	ResourceRepositories []ResourceRepository
+	// TokenValidator はセッション開始時に認証トークンを検証します (nilの場合は検証しない)
+	TokenValidator *common.TokenValidator
+	// GitHubApp が設定されている場合は、リクエストのトークンの代わりに
+	// 操作対象のオーナーにインストールされたGitHub Appのトークンを使用します
+	GitHubApp *operations.GitHubApp
+	// GitHubAppDefaultOwner は owner 引数のないツールで使用するインストールのオーナーです
+	GitHubAppDefaultOwner string
+	// InboundAuth はSSE/HTTPモードでMCPのセッションを作成する前に呼び出し元を認証します (nilの場合は認証しない)
+	InboundAuth *common.InboundAuth
+	// AllowAnonymousServerToken が true の場合、SSE/HTTPモードでAuthorizationヘッダーのない
+	// 呼び出し元にも環境変数GITHUB_TOKENのトークンを使用させます
+	AllowAnonymousServerToken bool
+	// ClientCache はGitHubクライアントの作成と再利用に使用するキャッシュです (nilの場合は既定のキャッシュ)
+	// HTTPキャッシュなど独自のトランスポートで接続する場合に設定します
+	ClientCache *operations.ClientCache
+}
+
+// toolConfig はサーバー全体のツールの選択条件を返します
+func (o ServerOptions) toolConfig() toolConfig {
+	return toolConfig{readOnly: o.ReadOnly, toolsets: o.Toolsets}
+}
+
+// toolTimeout は指定されたツールに適用するタイムアウトを返します
+func (o ServerOptions) toolTimeout(name string) time.Duration {
+	if timeout, ok := o.ToolTimeouts[name]; ok {
+		return timeout
+	}
+	return o.ToolTimeout
+}
+
+// withToolTimeout はツールハンドラーのコンテキストにタイムアウトを設定します
+func withToolTimeout(timeout time.Duration, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if timeout <= 0 {
+		return handler
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, request)
+	}
+}
+
+// withGitHubHost はリクエストで接続先が指定されていない場合に既定の接続先をコンテキストに設定します
+func withGitHubHost(host common.GitHubHost, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if _, ok := common.GitHubHostFromContext(ctx); !ok {
+			ctx = common.WithGitHubHost(ctx, host)
+		}
+		return handler(ctx, request)
+	}
+}
+
+// withRetryProgress はクライアントが進捗の通知を要求している場合に、GitHub APIへのリクエストの
+// 再試行で待っていることを進捗として通知します
+// レート制限の解除を待つ間もツールが停止していないことをクライアントに伝えます
+func withRetryProgress(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		srv := server.ServerFromContext(ctx)
+		if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil || srv == nil {
+			return handler(ctx, request)
+		}
+		token := request.Params.Meta.ProgressToken
+		notifyCtx := ctx
+		ctx = common.WithRetryNotify(ctx, func(attempt int, wait time.Duration, reason string) {
+			message := fmt.Sprintf("%sのため %s 後に再試行します (%d回目)", reason, wait.Round(time.Second), attempt)
+			if err := srv.SendNotificationToClient(notifyCtx, "notifications/progress", map[string]any{
+				"progressToken": token,
+				"progress":      attempt,
+				"message":       message,
+			}); err != nil {
+				log.Printf("進捗を通知できません: %v", err)
+			}
+		})
+		return handler(ctx, request)
+	}
 }
 
 // NewGitHubMCPServer は新しいGitHub MCP Serverを作成します
-func NewGitHubMCPServer() *GitHubMCPServer {
+func NewGitHubMCPServer(opts ServerOptions) *GitHubMCPServer {
+	return newGitHubMCPServer(opts, newDrainer())
+}
+
+// newGitHubMCPServer は停止処理を共有するGitHub MCP Serverを作成します
+func newGitHubMCPServer(opts ServerOptions, d *drainer) *GitHubMCPServer {
 	// MCPサーバーの作成
 	s := server.NewMCPServer(
 		"github-mcp-server",
@@ -27,19 +139,81 @@ func NewGitHubMCPServer() *GitHubMCPServer {
 	)
 
 	// リポジトリ検索ツール
-	searchReposTool := mcp.NewTool("search_repositories",
+	searchReposTool := mcp.NewTool("search_repositories", withPagination(
 		mcp.WithDescription("GitHub リポジトリを検索します"),
 		mcp.WithString("query",
 			mcp.Required(),
 			mcp.Description("検索クエリ"),
 		),
-		mcp.WithNumber("page",
-			mcp.Description("ページ番号"),
+	)...)
+
+	// コード検索ツール
+	searchCodeTool := mcp.NewTool("search_code", withPagination(
+		mcp.WithDescription("GitHub リポジトリ内のファイルの内容を検索し、一致した箇所の断片を返します"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("検索クエリ (例: 'func NewServer language:go org:octocat'、repo: / path: / extension: などの修飾子を使用できます)"),
 		),
-		mcp.WithNumber("per_page",
-			mcp.Description("1ページあたりの結果数"),
+		mcp.WithString("sort",
+			mcp.Description("並び順の基準 (省略時は一致度順)"),
+			mcp.Enum("indexed"),
 		),
-	)
+		mcp.WithString("order",
+			mcp.Description("並び順 (asc または desc)"),
+			mcp.Enum("asc", "desc"),
+		),
+	)...)
+
+	// Issue・Pull Request検索ツール
+	searchIssuesTool := mcp.NewTool("search_issues", withPagination(
+		mcp.WithDescription("GitHub のIssueとPull Requestを検索します"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("検索クエリ (例: 'is:pr is:open repo:octocat/hello parser'、Pull Requestのみは is:pr、Issueのみは is:issue)"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("並び順の基準 (省略時は一致度順)"),
+			mcp.Enum("comments", "reactions", "created", "updated"),
+		),
+		mcp.WithString("order",
+			mcp.Description("並び順 (asc または desc)"),
+			mcp.Enum("asc", "desc"),
+		),
+	)...)
+
+	// コミット検索ツール
+	searchCommitsTool := mcp.NewTool("search_commits", withPagination(
+		mcp.WithDescription("GitHub のコミットをメッセージや作成者で検索します"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("検索クエリ (例: 'fix race repo:octocat/hello author:octocat')"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("並び順の基準 (省略時は一致度順)"),
+			mcp.Enum("author-date", "committer-date"),
+		),
+		mcp.WithString("order",
+			mcp.Description("並び順 (asc または desc)"),
+			mcp.Enum("asc", "desc"),
+		),
+	)...)
+
+	// ユーザー検索ツール
+	searchUsersTool := mcp.NewTool("search_users", withPagination(
+		mcp.WithDescription("GitHub のユーザーとOrganizationを検索します"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("検索クエリ (例: 'octo type:org')"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("並び順の基準 (省略時は一致度順)"),
+			mcp.Enum("followers", "repositories", "joined"),
+		),
+		mcp.WithString("order",
+			mcp.Description("並び順 (asc または desc)"),
+			mcp.Enum("asc", "desc"),
+		),
+	)...)
 
 	// リポジトリ作成ツール
 	createRepoTool := mcp.NewTool("create_repository",
@@ -57,11 +231,14 @@ func NewGitHubMCPServer() *GitHubMCPServer {
 		mcp.WithBoolean("auto_init",
 			mcp.Description("READMEファイルを自動生成するかどうか"),
 		),
+		mcp.WithString("organization",
+			mcp.Description("作成先の組織名 (省略時は個人アカウント。GitHub Appで認証する場合は必須)"),
+		),
 	)
 
 	// ファイル取得ツール
 	getFileTool := mcp.NewTool("get_file_contents",
-		mcp.WithDescription("GitHubリポジトリからファイルの内容を取得します"),
+		mcp.WithDescription("GitHubリポジトリからファイルの内容を取得します。ディレクトリを指定した場合はエントリ一覧を返し、画像は画像として、その他のバイナリファイルはbase64の埋め込みリソースとして返します"),
 		mcp.WithString("owner",
 			mcp.Required(),
 			mcp.Description("リポジトリオーナー"),
@@ -72,7 +249,7 @@ func NewGitHubMCPServer() *GitHubMCPServer {
 		),
 		mcp.WithString("path",
 			mcp.Required(),
-			mcp.Description("ファイルパス"),
+			mcp.Description("ファイルまたはディレクトリのパス"),
 		),
 		mcp.WithString("branch",
 			mcp.Description("ブランチ名 (省略時はデフォルトブランチ)"),
@@ -127,7 +304,39 @@ func NewGitHubMCPServer() *GitHubMCPServer {
 		),
 		mcp.WithArray("files",
 			mcp.Required(),
-			mcp.Description("ファイル操作の配列"),
+			mcp.Description("ファイル操作の配列 (同じパスを複数の操作で指定することはできません)"),
+			mcp.Items(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "ファイルパス",
+					},
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "ファイルの内容 (deleteでは不要、renameでは省略すると内容を変更しません)",
+					},
+					"operation": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"add", "update", "delete", "rename"},
+						"description": "ファイル操作 (省略時は追加または更新)",
+					},
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"100644", "100755", "120000", "file", "executable", "symlink"},
+						"description": "ファイルモード (省略時は既存ファイルのモード、新規ファイルは100644)",
+					},
+					"sha": map[string]interface{}{
+						"type":        "string",
+						"description": "ブランチ上の既存ファイルのblob SHA (一致しない場合は競合エラー)",
+					},
+					"previous_path": map[string]interface{}{
+						"type":        "string",
+						"description": "renameの移動元パス (移動先に既存のファイルがある場合は競合エラー)",
+					},
+				},
+				"required": []string{"path"},
+			}),
 		),
 		mcp.WithString("message",
 			mcp.Required(),
@@ -147,7 +356,7 @@ func NewGitHubMCPServer() *GitHubMCPServer {
 			mcp.Description("元のリポジトリ名"),
 		),
 		mcp.WithString("organization",
-			mcp.Description("フォーク先の組織名 (省略時は個人アカウント)"),
+			mcp.Description("フォーク先の組織名 (省略時は個人アカウント。GitHub Appで認証する場合は必須)"),
 		),
 	)
 
@@ -168,6 +377,81 @@ func NewGitHubMCPServer() *GitHubMCPServer {
 		),
 	)
 
+	// Pull Request一覧ツール
+	listPRsTool := mcp.NewTool("list_pull_requests", withPagination(
+		mcp.WithDescription("GitHubリポジトリのPull Requestを一覧で取得し、概要を返します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithString("state",
+			mcp.Description("Pull Requestの状態 (省略時は open)"),
+			mcp.Enum("open", "closed", "all"),
+		),
+		mcp.WithString("head",
+			mcp.Description("ヘッドブランチで絞り込みます ('user:branch' 形式。ブランチ名のみの場合はリポジトリオーナーのブランチ)"),
+		),
+		mcp.WithString("base",
+			mcp.Description("ベースブランチで絞り込みます (例: 'main')"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("並び順の基準 (省略時は created)"),
+			mcp.Enum("created", "updated", "popularity", "long-running"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("並び順 (省略時は sort が created なら desc、それ以外は asc)"),
+			mcp.Enum("asc", "desc"),
+		),
+	)...)
+
+	// Pull Request変更ファイル一覧ツール
+	getPRFilesTool := mcp.NewTool("get_pull_request_files", withPagination(
+		mcp.WithDescription("Pull Requestで変更されたファイルを、状態、追加・削除行数、差分 (patch) とともに一覧で取得します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+	)...)
+
+	// Pull Request diff取得ツール
+	getPRDiffTool := mcp.NewTool("get_pull_request_diff",
+		mcp.WithDescription("Pull Requestのunified diffを取得します。大きなdiffは切り詰められ、next_cursor で続きを取得できます"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+		mcp.WithArray("files",
+			mcp.Description("指定したパスまたはglobパターン (例: 'src/*.go') に一致するファイルの差分のみを返します"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("max_bytes",
+			mcp.Description(fmt.Sprintf("1回で返す最大バイト数 (省略時は%d、最大%d)", operations.DefaultDiffBytes, operations.MaxDiffBytes)),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("前回の結果の next_cursor。指定すると続きを取得します (files は前回と同じ値を指定してください)"),
+		),
+	)
+
 	// Pull Request作成ツール
 	createPRTool := mcp.NewTool("create_pull_request",
 		mcp.WithDescription("GitHubリポジトリに新しいPull Requestを作成します"),
@@ -227,51 +511,737 @@ func NewGitHubMCPServer() *GitHubMCPServer {
 		mcp.WithString("commit_id",
 			mcp.Description("レビューする特定のコミットID（省略時は最新コミット）"),
 		),
+		mcp.WithArray("comments",
+			mcp.Description("行へのコメントの一覧。レビューとともにまとめて作成され、1件でも不正な場合はレビュー全体が作成されません"),
+			mcp.Items(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "コメントするファイルのパス",
+					},
+					"line": map[string]interface{}{
+						"type":        "number",
+						"description": "コメントする行番号 (複数行の場合は最後の行)。diffに含まれる行である必要があります",
+					},
+					"side": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"LEFT", "RIGHT"},
+						"description": "RIGHT は変更後、LEFT は変更前 (削除された行) の行番号 (省略時は RIGHT)",
+					},
+					"start_line": map[string]interface{}{
+						"type":        "number",
+						"description": "複数行にコメントする場合の最初の行番号",
+					},
+					"start_side": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"LEFT", "RIGHT"},
+						"description": "start_line の側 (省略時は side と同じ)",
+					},
+					"body": map[string]interface{}{
+						"type":        "string",
+						"description": "コメントの本文",
+					},
+					"suggestion": map[string]interface{}{
+						"type":        "string",
+						"description": "対象の行 (start_line から line まで) を置き換える内容。```suggestion ブロックとして本文に追加されます (空文字列は行の削除の提案)",
+					},
+				},
+				"required": []string{"path", "line"},
+			}),
+		),
 	)
 
+	// レビューコメント一覧ツール
+	listPRReviewCommentsTool := mcp.NewTool("list_pull_request_review_comments", withPagination(
+		mcp.WithDescription("Pull Requestの行へのレビューコメントを一覧で取得します。in_reply_to_id でスレッドを判別できます"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("並び順の基準 (省略時は created)"),
+			mcp.Enum("created", "updated"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("並び順 (省略時は asc)"),
+			mcp.Enum("asc", "desc"),
+		),
+		mcp.WithString("since",
+			mcp.Description("この日時以降に更新されたコメントに絞り込みます (RFC 3339形式)"),
+		),
+	)...)
+
+	// レビューコメント返信ツール
+	replyToPRReviewCommentTool := mcp.NewTool("reply_to_pull_request_review_comment",
+		mcp.WithDescription("Pull Requestのレビューコメントのスレッドに返信します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+		mcp.WithNumber("comment_id",
+			mcp.Required(),
+			mcp.Description("返信するレビューコメントのID"),
+		),
+		mcp.WithString("body",
+			mcp.Required(),
+			mcp.Description("返信の本文"),
+		),
+	)
+
+	// レビュースレッド解決ツール
+	resolvePRReviewThreadTool := mcp.NewTool("resolve_pull_request_review_thread",
+		mcp.WithDescription("Pull Requestのレビューコメントのスレッドを解決済みにします"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+		mcp.WithNumber("comment_id",
+			mcp.Required(),
+			mcp.Description("スレッド内のいずれかのレビューコメントのID"),
+		),
+	)
+
+	// レビュースレッド解決取り消しツール
+	unresolvePRReviewThreadTool := mcp.NewTool("unresolve_pull_request_review_thread",
+		mcp.WithDescription("Pull Requestのレビューコメントのスレッドを未解決に戻します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+		mcp.WithNumber("comment_id",
+			mcp.Required(),
+			mcp.Description("スレッド内のいずれかのレビューコメントのID"),
+		),
+	)
+
+	// Pull Requestマージツール
+	mergePRTool := mcp.NewTool("merge_pull_request",
+		mcp.WithDescription("Pull Requestをマージします。マージできない状態の場合は理由を含むエラーを返します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("マージするPull Requestの番号"),
+		),
+		mcp.WithString("merge_method",
+			mcp.Description("マージ方法 (省略時は merge)"),
+			mcp.Enum("merge", "squash", "rebase"),
+		),
+		mcp.WithString("commit_title",
+			mcp.Description("マージコミットのタイトル"),
+		),
+		mcp.WithString("commit_message",
+			mcp.Description("マージコミットのメッセージ"),
+		),
+		mcp.WithString("sha",
+			mcp.Description("ヘッドブランチの期待するSHA (一致しない場合はマージしません)"),
+		),
+	)
+
+	// Pull Request更新ツール
+	updatePRTool := mcp.NewTool("update_pull_request",
+		mcp.WithDescription("Pull Requestのタイトル、説明、ベースブランチ、状態、ドラフトを更新します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("更新するPull Requestの番号"),
+		),
+		mcp.WithString("title",
+			mcp.Description("新しいタイトル"),
+		),
+		mcp.WithString("body",
+			mcp.Description("新しい説明"),
+		),
+		mcp.WithString("base",
+			mcp.Description("新しいベースブランチ"),
+		),
+		mcp.WithString("state",
+			mcp.Description("Pull Requestの状態 (closed でクローズ、open で再オープン)"),
+			mcp.Enum("open", "closed"),
+		),
+		mcp.WithBoolean("draft",
+			mcp.Description("true でドラフトに変更、false でレビュー可能に変更します"),
+		),
+		mcp.WithBoolean("maintainer_can_modify",
+			mcp.Description("メンテナーが変更を加えられるようにするかどうか"),
+		),
+	)
+
+	// Pull Requestブランチ更新ツール
+	updatePRBranchTool := mcp.NewTool("update_pull_request_branch",
+		mcp.WithDescription("Pull Requestのヘッドブランチにベースブランチの最新の変更を取り込みます"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("更新するPull Requestの番号"),
+		),
+		mcp.WithString("expected_head_sha",
+			mcp.Description("ヘッドブランチの期待するSHA (一致しない場合は更新しません)"),
+		),
+	)
+
+	// Pull Request自動マージ有効化ツール
+	enablePRAutoMergeTool := mcp.NewTool("enable_pull_request_auto_merge",
+		mcp.WithDescription("Pull Requestの自動マージを有効にします。必須のチェックやレビューが完了すると自動でマージされます"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+		mcp.WithString("merge_method",
+			mcp.Description("マージ方法 (省略時は merge)"),
+			mcp.Enum("merge", "squash", "rebase"),
+		),
+	)
+
+	// Pull Request自動マージ無効化ツール
+	disablePRAutoMergeTool := mcp.NewTool("disable_pull_request_auto_merge",
+		mcp.WithDescription("Pull Requestの自動マージを無効にします"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("pull_number",
+			mcp.Required(),
+			mcp.Description("Pull Requestの番号"),
+		),
+	)
+
+	// Issue作成ツール
+	createIssueTool := mcp.NewTool("create_issue",
+		mcp.WithDescription("GitHubリポジトリに新しいIssueを作成します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithString("title",
+			mcp.Required(),
+			mcp.Description("Issueのタイトル"),
+		),
+		mcp.WithString("body",
+			mcp.Description("Issueの本文"),
+		),
+		mcp.WithArray("labels",
+			mcp.Description("付けるラベル名の一覧"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("assignees",
+			mcp.Description("担当者のユーザー名の一覧"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("milestone",
+			mcp.Description("マイルストーンの番号"),
+		),
+	)
+
+	// Issue取得ツール
+	getIssueTool := mcp.NewTool("get_issue",
+		mcp.WithDescription("GitHubリポジトリからIssueの詳細を取得します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("issue_number",
+			mcp.Required(),
+			mcp.Description("取得するIssueの番号"),
+		),
+	)
+
+	// Issue一覧ツール
+	listIssuesTool := mcp.NewTool("list_issues", withPagination(
+		mcp.WithDescription("GitHubリポジトリのIssueを一覧で取得します (Pull Requestも含まれ、is_pull_request で区別できます)"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithString("state",
+			mcp.Description("Issueの状態 (省略時は open)"),
+			mcp.Enum("open", "closed", "all"),
+		),
+		mcp.WithArray("labels",
+			mcp.Description("すべてのラベルが付いたIssueに絞り込みます"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithString("assignee",
+			mcp.Description("担当者のユーザー名 (none は未割り当て、* は割り当て済み)"),
+		),
+		mcp.WithString("since",
+			mcp.Description("この日時以降に更新されたIssueに絞り込みます (RFC 3339形式、例: 2024-01-02T15:04:05Z)"),
+		),
+		mcp.WithString("sort",
+			mcp.Description("並び順の基準 (省略時は created)"),
+			mcp.Enum("created", "updated", "comments"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("並び順 (省略時は desc)"),
+			mcp.Enum("asc", "desc"),
+		),
+	)...)
+
+	// Issue更新ツール
+	updateIssueTool := mcp.NewTool("update_issue",
+		mcp.WithDescription("GitHub Issueを更新します (指定した項目のみを変更します)"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("issue_number",
+			mcp.Required(),
+			mcp.Description("更新するIssueの番号"),
+		),
+		mcp.WithString("title",
+			mcp.Description("新しいタイトル"),
+		),
+		mcp.WithString("body",
+			mcp.Description("新しい本文"),
+		),
+		mcp.WithString("state",
+			mcp.Description("新しい状態"),
+			mcp.Enum("open", "closed"),
+		),
+		mcp.WithString("state_reason",
+			mcp.Description("状態を変更する理由"),
+			mcp.Enum("completed", "not_planned", "reopened"),
+		),
+		mcp.WithArray("labels",
+			mcp.Description("ラベル名の一覧 (既存のラベルを置き換えます。空の配列ですべて外します)"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("assignees",
+			mcp.Description("担当者のユーザー名の一覧 (既存の担当者を置き換えます。空の配列ですべて外します)"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("milestone",
+			mcp.Description("マイルストーンの番号 (0 で外します)"),
+		),
+	)
+
+	// Issueコメント追加ツール
+	addIssueCommentTool := mcp.NewTool("add_issue_comment",
+		mcp.WithDescription("GitHub IssueまたはPull Requestにコメントを追加します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("issue_number",
+			mcp.Required(),
+			mcp.Description("コメントするIssueまたはPull Requestの番号"),
+		),
+		mcp.WithString("body",
+			mcp.Required(),
+			mcp.Description("コメントの本文"),
+		),
+	)
+
+	// Issueコメント一覧ツール
+	listIssueCommentsTool := mcp.NewTool("list_issue_comments", withPagination(
+		mcp.WithDescription("GitHub IssueまたはPull Requestのコメントを古い順に一覧で取得します"),
+		mcp.WithString("owner",
+			mcp.Required(),
+			mcp.Description("リポジトリオーナー"),
+		),
+		mcp.WithString("repo",
+			mcp.Required(),
+			mcp.Description("リポジトリ名"),
+		),
+		mcp.WithNumber("issue_number",
+			mcp.Required(),
+			mcp.Description("IssueまたはPull Requestの番号"),
+		),
+		mcp.WithString("since",
+			mcp.Description("この日時以降に更新されたコメントに絞り込みます (RFC 3339形式)"),
+		),
+	)...)
+
 	// ツールハンドラーの登録
-	s.AddTool(searchReposTool, handleSearchRepositories)
-	s.AddTool(createRepoTool, handleCreateRepository)
-	s.AddTool(getFileTool, handleGetFileContents)
-	s.AddTool(createOrUpdateFileTool, handleCreateOrUpdateFile)
-	s.AddTool(pushFilesTool, handlePushFiles)
-	s.AddTool(forkRepoTool, handleForkRepository)
-	s.AddTool(getPRTool, handleGetPullRequest)
-	s.AddTool(createPRTool, handleCreatePullRequest)
-	s.AddTool(createPRReviewTool, handleCreatePullRequestReview)
+	// 選択条件に一致しないツールは登録しないため、クライアントからは存在しないものとして扱われます
+	config := opts.toolConfig()
+	addTool := func(toolset string, readOnly bool, tool mcp.Tool, check policyCheck, handler server.ToolHandlerFunc) {
+		if !config.enabled(toolset, readOnly) {
+			return
+		}
+		// ポリシーで許可されていないリポジトリについてGitHub AppのAPIを呼び出さないように、
+		// インストールトークンを取得する前にポリシーを確認する
+		handler = withInstallationToken(opts, tool.Name, handler)
+		handler = withPolicy(opts.Policy, check, handler)
+		handler = withRetryProgress(handler)
+		handler = withToolTimeout(opts.toolTimeout(tool.Name), handler)
+		handler = withGitHubHost(opts.GitHubHost, handler)
+		handler = withDrain(d, handler)
+		s.AddTool(tool, handler)
+	}
+	readRepo := requireRepoAccess(common.AccessRead)
+	writeRepo := requireRepoAccess(common.AccessWrite)
+	addTool(ToolsetRepos, true, searchReposTool, nil, handleSearchRepositories)
+	addTool(ToolsetRepos, true, searchCodeTool, nil, handleSearchCode)
+	addTool(ToolsetRepos, true, searchIssuesTool, nil, handleSearchIssues)
+	addTool(ToolsetRepos, true, searchCommitsTool, nil, handleSearchCommits)
+	addTool(ToolsetRepos, true, searchUsersTool, nil, handleSearchUsers)
+	addTool(ToolsetRepos, false, createRepoTool, checkCreateRepository(opts.GitHubApp != nil), handleCreateRepository)
+	addTool(ToolsetRepos, false, forkRepoTool, checkForkRepository(opts.GitHubApp != nil), handleForkRepository)
+	addTool(ToolsetFiles, true, getFileTool, readRepo, handleGetFileContents)
+	addTool(ToolsetFiles, false, createOrUpdateFileTool, writeRepo, handleCreateOrUpdateFile)
+	addTool(ToolsetFiles, false, pushFilesTool, writeRepo, handlePushFiles)
+	addTool(ToolsetPulls, true, getPRTool, readRepo, handleGetPullRequest)
+	addTool(ToolsetPulls, true, listPRsTool, readRepo, handleListPullRequests)
+	addTool(ToolsetPulls, true, getPRFilesTool, readRepo, handleGetPullRequestFiles)
+	addTool(ToolsetPulls, true, getPRDiffTool, readRepo, handleGetPullRequestDiff)
+	addTool(ToolsetPulls, false, createPRTool, writeRepo, handleCreatePullRequest)
+	addTool(ToolsetPulls, false, createPRReviewTool, writeRepo, handleCreatePullRequestReview)
+	addTool(ToolsetPulls, true, listPRReviewCommentsTool, readRepo, handleListPullRequestReviewComments)
+	addTool(ToolsetPulls, false, replyToPRReviewCommentTool, writeRepo, handleReplyToPullRequestReviewComment)
+	addTool(ToolsetPulls, false, resolvePRReviewThreadTool, writeRepo, handleResolvePullRequestReviewThread)
+	addTool(ToolsetPulls, false, unresolvePRReviewThreadTool, writeRepo, handleUnresolvePullRequestReviewThread)
+	addTool(ToolsetPulls, false, mergePRTool, writeRepo, handleMergePullRequest)
+	addTool(ToolsetPulls, false, updatePRTool, writeRepo, handleUpdatePullRequest)
+	addTool(ToolsetPulls, false, updatePRBranchTool, writeRepo, handleUpdatePullRequestBranch)
+	addTool(ToolsetPulls, false, enablePRAutoMergeTool, writeRepo, handleEnablePullRequestAutoMerge)
+	addTool(ToolsetPulls, false, disablePRAutoMergeTool, writeRepo, handleDisablePullRequestAutoMerge)
+	addTool(ToolsetIssues, true, getIssueTool, readRepo, handleGetIssue)
+	addTool(ToolsetIssues, true, listIssuesTool, readRepo, handleListIssues)
+	addTool(ToolsetIssues, true, listIssueCommentsTool, readRepo, handleListIssueComments)
+	addTool(ToolsetIssues, false, createIssueTool, writeRepo, handleCreateIssue)
+	addTool(ToolsetIssues, false, updateIssueTool, writeRepo, handleUpdateIssue)
+	addTool(ToolsetIssues, false, addIssueCommentTool, writeRepo, handleAddIssueComment)
+
+	// リソースの登録
+	registerResources(s, opts, config, d)
 
 	return &GitHubMCPServer{
-		server: s,
+		server:  s,
+		opts:    opts,
+		drainer: d,
 	}
 }
 
-// ServeSSE はSSEモードでサーバーを起動します
-func (s *GitHubMCPServer) ServeSSE(addr string) *server.SSEServer {
-	return server.NewSSEServer(s.server,
-		server.WithBaseURL(fmt.Sprintf("http://%s", addr)),
-		server.WithSSEContextFunc(common.AuthTokenFromRequest),
-	)
+// ServeSSE はSSEモードのHTTPハンドラーを作成します
+// 接続時のヘッダーでツールの選択条件が指定された場合は、その条件のサーバーにセッションを割り当てます
+// baseURL はクライアントにメッセージの送信先として通知するURLのベースです
+func (s *GitHubMCPServer) ServeSSE(baseURL string) http.Handler {
+	return s.withInboundAuth(s.withTokenValidation(newSSERouter(s, baseURL)))
+}
+
+// ServeStreamableHTTP はStreamable HTTPモードのHTTPハンドラーを作成します
+// リクエストのヘッダーでツールの選択条件が指定された場合は、その条件のサーバーで処理します
+func (s *GitHubMCPServer) ServeStreamableHTTP() http.Handler {
+	return s.withInboundAuth(s.withTokenValidation(newHTTPRouter(s)))
+}
+
+// withToolConfig は同じ設定でツールの選択条件のみを変更したサーバーを作成します
+// 作成したサーバーは停止処理を元のサーバーと共有します
+func (s *GitHubMCPServer) withToolConfig(config toolConfig) *GitHubMCPServer {
+	opts := s.opts
+	opts.ReadOnly = config.readOnly
+	opts.Toolsets = config.toolsets
+	return newGitHubMCPServer(opts, s.drainer)
+}
+
+// requestContext はHTTPリクエストから認証トークンとGitHub APIの接続先をコンテキストに追加します
+// 呼び出し元が認証されている場合は、呼び出し元に割り当てられたGitHubトークンを使用します
+// リクエストで指定できる接続先はAllowedGitHubHostsに含まれるものに限られます
+func (s *GitHubMCPServer) requestContext(ctx context.Context, r *http.Request) context.Context {
+	if identity := common.IdentityFromContext(ctx); identity != nil {
+		ctx = common.AuthTokenFromIdentity(ctx, r, identity)
+	} else {
+		ctx = common.AuthTokenFromRequest(ctx, r, s.opts.AllowAnonymousServerToken)
+	}
+	ctx = common.GitHubHostFromRequest(ctx, r, s.opts.AllowedGitHubHosts)
+	return s.withClientCache(ctx)
+}
+
+// withClientCache はサーバーに設定されたクライアントキャッシュをコンテキストに追加します
+func (s *GitHubMCPServer) withClientCache(ctx context.Context) context.Context {
+	if s.opts.ClientCache == nil {
+		return ctx
+	}
+	return operations.WithClientCache(ctx, s.opts.ClientCache)
 }
 
 // ServeStdio はStdioモードでサーバーを起動します
+// トークンの検証が有効な場合は、起動時に環境変数のトークンを検証します
 func (s *GitHubMCPServer) ServeStdio() error {
-	return server.ServeStdio(s.server, server.WithStdioContextFunc(common.AuthTokenFromEnv))
+	contextFunc := func(ctx context.Context) context.Context {
+		return s.withClientCache(common.AuthTokenFromEnv(ctx))
+	}
+	if s.opts.TokenValidator != nil {
+		info, err := s.validateToken(contextFunc(context.Background()))
+		if err != nil {
+			return fmt.Errorf("認証トークンを検証できません: %s", common.FormatGitHubError(err))
+		}
+		log.Printf("認証トークンを検証しました (ユーザー: %s)", info.Login)
+		contextFunc = func(ctx context.Context) context.Context {
+			return common.WithTokenInfo(s.withClientCache(common.AuthTokenFromEnv(ctx)), info)
+		}
+	}
+	return server.ServeStdio(s.server, server.WithStdioContextFunc(contextFunc))
 }
 
 func main() {
 	// コマンドライン引数の処理
 	var transport string
-	flag.StringVar(&transport, "t", "stdio", "トランスポートタイプ (stdio または sse)")
-	flag.StringVar(&transport, "transport", "stdio", "トランスポートタイプ (stdio または sse)")
+	flag.StringVar(&transport, "t", "stdio", "トランスポートタイプ (stdio, sse または http)")
+	flag.StringVar(&transport, "transport", "stdio", "トランスポートタイプ (stdio, sse または http)")
 
 	var port string
-	flag.StringVar(&port, "p", "8080", "SSEサーバーのポート番号")
-	flag.StringVar(&port, "port", "8080", "SSEサーバーのポート番号")
+	flag.StringVar(&port, "p", "8080", "SSE/HTTPサーバーのポート番号")
+	flag.StringVar(&port, "port", "8080", "SSE/HTTPサーバーのポート番号")
+
+	var listen string
+	flag.StringVar(&listen, "listen", "", "SSE/HTTPサーバーの待ち受けアドレス (例: 0.0.0.0:8080、省略時は localhost:<port>)")
+
+	var publicBaseURL string
+	flag.StringVar(&publicBaseURL, "public-base-url", "", "SSEモードでクライアントに通知する公開URL (例: https://mcp.example.com/github、省略時は待ち受けアドレスから導出)")
+
+	var tlsCert string
+	flag.StringVar(&tlsCert, "tls-cert", "", "HTTPSで待ち受ける場合の証明書ファイル")
+
+	var tlsKey string
+	flag.StringVar(&tlsKey, "tls-key", "", "HTTPSで待ち受ける場合の秘密鍵ファイル")
+
+	var shutdownTimeout time.Duration
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "停止時に実行中のツール呼び出しの完了を待つ最大時間 (0で無制限)")
+
+	var toolTimeout time.Duration
+	flag.DurationVar(&toolTimeout, "tool-timeout", 2*time.Minute, "ツール呼び出しごとのタイムアウト (0で無制限)")
+
+	var toolTimeouts string
+	flag.StringVar(&toolTimeouts, "tool-timeouts", "", "ツールごとのタイムアウト (例: push_files=5m,get_file_contents=30s)")
+
+	var githubHost string
+	flag.StringVar(&githubHost, "github-host", os.Getenv("GITHUB_API_URL"), "GitHub Enterprise ServerのホストまたはAPIのURL (環境変数GITHUB_API_URL)")
+
+	var allowedGitHubHosts string
+	flag.StringVar(&allowedGitHubHosts, "allowed-github-hosts", "", "X-GitHub-Hostヘッダーで指定できる接続先のホスト名 (例: ghe.example.com,*.ghe.com、--github-hostのホストは常に許可)")
+
+	var githubUploadURL string
+	flag.StringVar(&githubUploadURL, "github-upload-url", os.Getenv("GITHUB_UPLOAD_URL"), "GitHub Enterprise ServerのアップロードURL (省略時はホストから導出、環境変数GITHUB_UPLOAD_URL)")
+
+	var readOnly bool
+	flag.BoolVar(&readOnly, "read-only", false, "変更を伴わないツールのみを登録する")
+
+	var toolsets string
+	flag.StringVar(&toolsets, "toolsets", ToolsetAll, "登録するツールセット (repos, files, pulls, issues または all をカンマ区切りで指定)")
+
+	var policyFile string
+	flag.StringVar(&policyFile, "policy", os.Getenv("GITHUB_MCP_POLICY"), "操作できるリポジトリを制限するポリシーファイル (YAMLまたはJSON、環境変数GITHUB_MCP_POLICY)")
+
+	var resourceRepos string
+	flag.StringVar(&resourceRepos, "resource-repos", "", "ファイルツリーをresources/listに表示するリポジトリ (例: owner/repo,owner/other@develop)")
+
+	var validateToken bool
+	flag.BoolVar(&validateToken, "validate-token", false, "セッション開始時に認証トークンをGitHub APIで検証する")
+
+	var tokenCacheTTL time.Duration
+	flag.DurationVar(&tokenCacheTTL, "token-cache-ttl", 5*time.Minute, "トークンの検証結果をキャッシュする時間")
+
+	var githubAppID int64
+	defaultAppID, _ := strconv.ParseInt(os.Getenv("GITHUB_APP_ID"), 10, 64)
+	flag.Int64Var(&githubAppID, "github-app-id", defaultAppID, "GitHub AppとしてリクエストするときのApp ID (環境変数GITHUB_APP_ID)")
+
+	var githubAppKey string
+	flag.StringVar(&githubAppKey, "github-app-private-key", os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH"), "GitHub AppのPEM形式の秘密鍵ファイル (環境変数GITHUB_APP_PRIVATE_KEY_PATH)")
+
+	var inboundAuthFile string
+	flag.StringVar(&inboundAuthFile, "inbound-auth", os.Getenv("GITHUB_MCP_INBOUND_AUTH"), "SSE/HTTPモードで呼び出し元を認証する設定ファイル (YAMLまたはJSON、環境変数GITHUB_MCP_INBOUND_AUTH)")
+
+	var allowAnonymous bool
+	flag.BoolVar(&allowAnonymous, "allow-anonymous", false, "SSE/HTTPモードでAuthorizationヘッダーのない呼び出し元にもサーバーのトークンの使用を許可する (ループバックアドレスで待ち受ける場合は常に許可)")
+
+	var githubAppDefaultOwner string
+	flag.StringVar(&githubAppDefaultOwner, "github-app-default-owner", "", "owner引数のないツール (search_repositoriesなど) で使用するGitHub Appのインストール先")
+
+	var httpCache string
+	flag.StringVar(&httpCache, "http-cache", httpCacheMemory, "GitHub APIのレスポンスをETagで再検証するキャッシュの保存先 (memory, disk または off)")
+
+	var httpCacheDir string
+	flag.StringVar(&httpCacheDir, "http-cache-dir", "", "--http-cache=disk の場合のキャッシュディレクトリ (省略時はユーザーのキャッシュディレクトリ)")
+
+	var httpCacheSize int64
+	flag.Int64Var(&httpCacheSize, "http-cache-size", operations.DefaultHTTPCacheSize>>20, "キャッシュに保存するレスポンスの合計サイズの上限 (MB)")
 
 	flag.Parse()
 
+	host, err := common.ParseGitHubHost(githubHost, githubUploadURL)
+	if err != nil {
+		log.Fatalf("無効なGitHubホスト指定: %v", err)
+	}
+
+	hostAllowlist, err := common.ParseHostAllowlist(allowedGitHubHosts)
+	if err != nil {
+		log.Fatalf("無効な接続先の許可リスト指定: %v", err)
+	}
+	hostAllowlist = append(hostAllowlist, host.Hostnames()...)
+
+	perToolTimeouts, err := parseToolTimeouts(toolTimeouts)
+	if err != nil {
+		log.Fatalf("無効なツールタイムアウト指定: %v", err)
+	}
+
+	enabledToolsets, err := parseToolsets(toolsets)
+	if err != nil {
+		log.Fatalf("無効なツールセット指定: %v", err)
+	}
+
+	resourceRepositories, err := parseResourceRepositories(resourceRepos)
+	if err != nil {
+		log.Fatalf("無効なリソースリポジトリ指定: %v", err)
+	}
+
+	var policy *common.Policy
+	if policyFile != "" {
+		if policy, err = common.LoadPolicy(policyFile); err != nil {
+			log.Fatalf("無効なポリシー指定: %v", err)
+		}
+		log.Printf("ポリシーファイルを読み込みました: %s", policyFile)
+	}
+
+	githubApp, err := loadGitHubApp(githubAppID, githubAppKey)
+	if err != nil {
+		log.Fatalf("無効なGitHub App指定: %v", err)
+	}
+	if githubApp != nil && validateToken {
+		log.Fatalf("--validate-token はGitHub Appによる認証と併用できません")
+	}
+
+	if listen == "" {
+		listen = fmt.Sprintf("localhost:%s", port)
+	}
+
+	var inboundAuth *common.InboundAuth
+	if inboundAuthFile != "" {
+		if transport == "stdio" {
+			log.Fatalf("--inbound-auth はSSE/HTTPモードでのみ使用できます")
+		}
+		if inboundAuth, err = common.LoadInboundAuth(inboundAuthFile); err != nil {
+			log.Fatalf("無効な認証設定: %v", err)
+		}
+		log.Printf("認証設定ファイルを読み込みました: %s", inboundAuthFile)
+	}
+	// 呼び出し元を認証しない場合、サーバーの認証情報は同じホストからの接続に限り使用させる
+	anonymous := inboundAuth == nil && (allowAnonymous || isLoopback(listen))
+	if transport != "stdio" && githubApp != nil && inboundAuth == nil && !anonymous {
+		log.Fatalf("GitHub Appで認証する場合は --inbound-auth で呼び出し元を認証するか、--allow-anonymous を指定してください")
+	}
+
+	clientCache, err := newClientCache(httpCache, httpCacheDir, httpCacheSize)
+	if err != nil {
+		log.Fatalf("無効なHTTPキャッシュ指定: %v", err)
+	}
+
+	var tokenValidator *common.TokenValidator
+	if validateToken {
+		tokenValidator = common.NewTokenValidator(operations.GetTokenInfo, tokenCacheTTL)
+	}
+
 	// GitHubMCPServerの作成
-	s := NewGitHubMCPServer()
+	s := NewGitHubMCPServer(ServerOptions{
+		ToolTimeout:               toolTimeout,
+		ToolTimeouts:              perToolTimeouts,
+		GitHubHost:                host,
+		AllowedGitHubHosts:        hostAllowlist,
+		ReadOnly:                  readOnly,
+		Toolsets:                  enabledToolsets,
+		Policy:                    policy,
+		ResourceRepositories:      resourceRepositories,
+		TokenValidator:            tokenValidator,
+		GitHubApp:                 githubApp,
+		GitHubAppDefaultOwner:     githubAppDefaultOwner,
+		InboundAuth:               inboundAuth,
+		AllowAnonymousServerToken: anonymous,
+		ClientCache:               clientCache,
+	})
+
+	log.Printf("GitHub APIの接続先: %s", host)
+	if readOnly {
+		log.Printf("読み取り専用モードで起動します")
+	}
+	if githubApp != nil {
+		log.Printf("GitHub App (ID: %d) のインストールトークンで認証します", githubAppID)
+	}
 
 	// 指定されたトランスポートタイプでサーバーを起動
 	switch transport {
@@ -280,56 +1250,128 @@ func main() {
 		if err := s.ServeStdio(); err != nil {
 			log.Fatalf("サーバーエラー: %v", err)
 		}
-	case "sse":
-		addr := fmt.Sprintf("localhost:%s", port)
-		log.Printf("GitHub MCP Server をSSEモードで起動します (アドレス: %s)", addr)
-		sseServer := s.ServeSSE(addr)
-		if err := sseServer.Start(addr); err != nil {
+	case "sse", "http":
+		config := HTTPConfig{
+			Listen:          listen,
+			TLSCertFile:     tlsCert,
+			TLSKeyFile:      tlsKey,
+			ShutdownTimeout: shutdownTimeout,
+		}
+		if err := config.validate(); err != nil {
+			log.Fatalf("無効なHTTPサーバー設定: %v", err)
+		}
+
+		var handler http.Handler
+		if transport == "sse" {
+			baseURL := config.defaultBaseURL()
+			if publicBaseURL != "" {
+				if baseURL, err = parseBaseURL(publicBaseURL); err != nil {
+					log.Fatalf("無効な公開URL指定: %v", err)
+				}
+			}
+			log.Printf("GitHub MCP Server をSSEモードで起動します (アドレス: %s、公開URL: %s)", config.Listen, baseURL)
+			handler = s.ServeSSE(baseURL)
+		} else {
+			log.Printf("GitHub MCP Server をStreamable HTTPモードで起動します (アドレス: %s、パス: %s)", config.Listen, httpEndpoint)
+			handler = s.ServeStreamableHTTP()
+		}
+
+		// SIGINT/SIGTERMを受け取ったら実行中のツール呼び出しの完了を待ってから停止する
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := s.ListenAndServe(ctx, config, handler); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("サーバーエラー: %v", err)
 		}
+		log.Printf("GitHub MCP Server を停止しました")
 	default:
-		log.Fatalf("無効なトランスポートタイプ: %s (stdio または sse を指定してください)", transport)
+		log.Fatalf("無効なトランスポートタイプ: %s (stdio, sse または http を指定してください)", transport)
 	}
 }
 
+// parseToolTimeouts は "name=duration,..." 形式のツールごとのタイムアウト指定を解析します
+func parseToolTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, durationStr, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q は name=duration の形式ではありません", item)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(durationStr))
+		if err != nil {
+			return nil, fmt.Errorf("%s のタイムアウトが不正です: %v", name, err)
+		}
+		timeouts[strings.TrimSpace(name)] = duration
+	}
+	return timeouts, nil
+}
+
+// toolErrorResult はツールのエラー結果としてクライアントに返す内容を表します
+type toolErrorResult struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newToolErrorResult はIsErrorを設定したツール結果を作成します
+// JSON-RPCのエラーではなくツール結果として返すことで、LLMがエラー内容を確認して自己修正できるようにします
+func newToolErrorResult(code, message string) *mcp.CallToolResult {
+	body, err := json.MarshalIndent(toolErrorResult{Code: code, Message: message}, "", "  ")
+	if err != nil {
+		body = []byte(message)
+	}
+	result := mcp.NewToolResultText(string(body))
+	result.IsError = true
+	return result
+}
+
+// toolResultError はエラーをツールのエラー結果に変換します
+func toolResultError(err error) *mcp.CallToolResult {
+	return newToolErrorResult(common.ErrorCode(err), common.FormatGitHubError(err))
+}
+
+// toolResultArgumentError は引数の検証エラーをツールのエラー結果に変換します
+func toolResultArgumentError(message string) *mcp.CallToolResult {
+	return newToolErrorResult(common.ErrorCodeInvalidArgument, fmt.Sprintf("Invalid Argument: %s", message))
+}
+
 // handleSearchRepositories はリポジトリ検索リクエストを処理します
 func handleSearchRepositories(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	query, ok := request.Params.Arguments["query"].(string)
+	query, ok := request.GetArguments()["query"].(string)
 	if !ok {
-		return nil, fmt.Errorf("query must be a string")
-	}
-
-	page := 1
-	if p, ok := request.Params.Arguments["page"].(float64); ok {
-		page = int(p)
+		return toolResultArgumentError("query must be a string"), nil
 	}
 
-	perPage := 30
-	if pp, ok := request.Params.Arguments["per_page"].(float64); ok {
-		perPage = int(pp)
+	pageOpts, err := pageOptionsFromArguments(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
 	}
 
 	// リポジトリ検索の実行
-	result, err := operations.SearchRepositories(operations.SearchRepositoriesOptions{
-		Query:   query,
-		Page:    page,
-		PerPage: perPage,
+	result, err := operations.SearchRepositories(ctx, operations.SearchRepositoriesOptions{
+		Query:       query,
+		PageOptions: pageOpts,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
+	// ポリシーで読み取りが許可されていないリポジトリは結果から除外する
+	result.Items = filterReadableRepositories(common.PolicyFromContext(ctx), result.Items)
+
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -340,45 +1382,51 @@ func handleCreateRepository(ctx context.Context, request mcp.CallToolRequest) (*
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	name, ok := request.Params.Arguments["name"].(string)
+	name, ok := request.GetArguments()["name"].(string)
 	if !ok {
-		return nil, fmt.Errorf("name must be a string")
+		return toolResultArgumentError("name must be a string"), nil
 	}
 
 	description := ""
-	if desc, ok := request.Params.Arguments["description"].(string); ok {
+	if desc, ok := request.GetArguments()["description"].(string); ok {
 		description = desc
 	}
 
 	private := false
-	if priv, ok := request.Params.Arguments["private"].(bool); ok {
+	if priv, ok := request.GetArguments()["private"].(bool); ok {
 		private = priv
 	}
 
 	autoInit := false
-	if ai, ok := request.Params.Arguments["auto_init"].(bool); ok {
+	if ai, ok := request.GetArguments()["auto_init"].(bool); ok {
 		autoInit = ai
 	}
 
+	organization := ""
+	if org, ok := request.GetArguments()["organization"].(string); ok {
+		organization = org
+	}
+
 	// リポジトリ作成の実行
-	result, err := operations.CreateRepository(operations.CreateRepositoryOptions{
-		Name:        name,
-		Description: description,
-		Private:     private,
-		AutoInit:    autoInit,
+	result, err := operations.CreateRepository(ctx, operations.CreateRepositoryOptions{
+		Name:         name,
+		Description:  description,
+		Private:      private,
+		AutoInit:     autoInit,
+		Organization: organization,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -389,48 +1437,66 @@ func handleGetFileContents(ctx context.Context, request mcp.CallToolRequest) (*m
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	owner, ok := request.Params.Arguments["owner"].(string)
+	owner, ok := request.GetArguments()["owner"].(string)
 	if !ok {
-		return nil, fmt.Errorf("owner must be a string")
+		return toolResultArgumentError("owner must be a string"), nil
 	}
 
-	repo, ok := request.Params.Arguments["repo"].(string)
+	repo, ok := request.GetArguments()["repo"].(string)
 	if !ok {
-		return nil, fmt.Errorf("repo must be a string")
+		return toolResultArgumentError("repo must be a string"), nil
 	}
 
-	path, ok := request.Params.Arguments["path"].(string)
+	path, ok := request.GetArguments()["path"].(string)
 	if !ok {
-		return nil, fmt.Errorf("path must be a string")
+		return toolResultArgumentError("path must be a string"), nil
 	}
 
 	branch := ""
-	if b, ok := request.Params.Arguments["branch"].(string); ok {
+	if b, ok := request.GetArguments()["branch"].(string); ok {
 		branch = b
 	}
 
 	// ファイル取得の実行
-	result, err := operations.GetFileContents(operations.GetFileContentOptions{
+	result, err := operations.GetFileContents(ctx, operations.GetFileContentOptions{
 		Owner:  owner,
 		Repo:   repo,
 		Path:   path,
 		Branch: branch,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
-	// JSON形式で結果を返す
-	jsonResult, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return nil, err
+	if !result.IsBinary() {
+		// JSON形式で結果を返す
+		jsonResult, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return toolResultError(err), nil
+		}
+		return mcp.NewToolResultText(string(jsonResult)), nil
 	}
 
-	return mcp.NewToolResultText(string(jsonResult)), nil
+	// バイナリファイルは内容をメタデータと分けて、画像または埋め込みリソースとして返す
+	data := result.Content
+	metadata := *result
+	metadata.Content = ""
+	jsonResult, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+	if strings.HasPrefix(result.MIMEType, "image/") {
+		return mcp.NewToolResultImage(string(jsonResult), data, result.MIMEType), nil
+	}
+	return mcp.NewToolResultResource(string(jsonResult), mcp.BlobResourceContents{
+		URI:      contentsResourceURI(owner, repo, result.Path, branch),
+		MIMEType: result.MIMEType,
+		Blob:     data,
+	}), nil
 }
 
 // handleCreateOrUpdateFile はファイル作成・更新リクエストを処理します
@@ -438,47 +1504,47 @@ func handleCreateOrUpdateFile(ctx context.Context, request mcp.CallToolRequest)
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	owner, ok := request.Params.Arguments["owner"].(string)
+	owner, ok := request.GetArguments()["owner"].(string)
 	if !ok {
-		return nil, fmt.Errorf("owner must be a string")
+		return toolResultArgumentError("owner must be a string"), nil
 	}
 
-	repo, ok := request.Params.Arguments["repo"].(string)
+	repo, ok := request.GetArguments()["repo"].(string)
 	if !ok {
-		return nil, fmt.Errorf("repo must be a string")
+		return toolResultArgumentError("repo must be a string"), nil
 	}
 
-	path, ok := request.Params.Arguments["path"].(string)
+	path, ok := request.GetArguments()["path"].(string)
 	if !ok {
-		return nil, fmt.Errorf("path must be a string")
+		return toolResultArgumentError("path must be a string"), nil
 	}
 
-	content, ok := request.Params.Arguments["content"].(string)
+	content, ok := request.GetArguments()["content"].(string)
 	if !ok {
-		return nil, fmt.Errorf("content must be a string")
+		return toolResultArgumentError("content must be a string"), nil
 	}
 
-	message, ok := request.Params.Arguments["message"].(string)
+	message, ok := request.GetArguments()["message"].(string)
 	if !ok {
-		return nil, fmt.Errorf("message must be a string")
+		return toolResultArgumentError("message must be a string"), nil
 	}
 
 	branch := ""
-	if b, ok := request.Params.Arguments["branch"].(string); ok {
+	if b, ok := request.GetArguments()["branch"].(string); ok {
 		branch = b
 	}
 
 	sha := ""
-	if s, ok := request.Params.Arguments["sha"].(string); ok {
+	if s, ok := request.GetArguments()["sha"].(string); ok {
 		sha = s
 	}
 
 	// ファイル作成・更新の実行
-	result, err := operations.CreateOrUpdateFile(operations.CreateOrUpdateFileOptions{
+	result, err := operations.CreateOrUpdateFile(ctx, operations.CreateOrUpdateFileOptions{
 		Owner:   owner,
 		Repo:    repo,
 		Path:    path,
@@ -488,13 +1554,13 @@ func handleCreateOrUpdateFile(ctx context.Context, request mcp.CallToolRequest)
 		SHA:     sha,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -505,33 +1571,33 @@ func handlePushFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	owner, ok := request.Params.Arguments["owner"].(string)
+	owner, ok := request.GetArguments()["owner"].(string)
 	if !ok {
-		return nil, fmt.Errorf("owner must be a string")
+		return toolResultArgumentError("owner must be a string"), nil
 	}
 
-	repo, ok := request.Params.Arguments["repo"].(string)
+	repo, ok := request.GetArguments()["repo"].(string)
 	if !ok {
-		return nil, fmt.Errorf("repo must be a string")
+		return toolResultArgumentError("repo must be a string"), nil
 	}
 
-	branch, ok := request.Params.Arguments["branch"].(string)
+	branch, ok := request.GetArguments()["branch"].(string)
 	if !ok {
-		return nil, fmt.Errorf("branch must be a string")
+		return toolResultArgumentError("branch must be a string"), nil
 	}
 
-	filesRaw, ok := request.Params.Arguments["files"].([]interface{})
+	filesRaw, ok := request.GetArguments()["files"].([]interface{})
 	if !ok {
-		return nil, fmt.Errorf("files must be an array")
+		return toolResultArgumentError("files must be an array"), nil
 	}
 
-	message, ok := request.Params.Arguments["message"].(string)
+	message, ok := request.GetArguments()["message"].(string)
 	if !ok {
-		return nil, fmt.Errorf("message must be a string")
+		return toolResultArgumentError("message must be a string"), nil
 	}
 
 	// ファイル操作の変換
@@ -539,17 +1605,25 @@ func handlePushFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 	for _, f := range filesRaw {
 		fileMap, ok := f.(map[string]interface{})
 		if !ok {
-			return nil, fmt.Errorf("each file must be an object")
+			return toolResultArgumentError("each file must be an object"), nil
 		}
 
 		path, ok := fileMap["path"].(string)
 		if !ok {
-			return nil, fmt.Errorf("file path must be a string")
+			return toolResultArgumentError("file path must be a string"), nil
 		}
 
-		content, ok := fileMap["content"].(string)
-		if !ok {
-			return nil, fmt.Errorf("file content must be a string")
+		operation := ""
+		if op, ok := fileMap["operation"].(string); ok {
+			operation = op
+		}
+
+		// renameでは内容の省略と空文字列を区別する (省略時は移動元の内容を使用する)
+		var content *string
+		if c, ok := fileMap["content"].(string); ok {
+			content = &c
+		} else if operation != operations.FileOperationDelete && operation != operations.FileOperationRename {
+			return toolResultArgumentError("file content must be a string"), nil
 		}
 
 		sha := ""
@@ -557,15 +1631,28 @@ func handlePushFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 			sha = s
 		}
 
+		mode := ""
+		if m, ok := fileMap["mode"].(string); ok {
+			mode = m
+		}
+
+		previousPath := ""
+		if pp, ok := fileMap["previous_path"].(string); ok {
+			previousPath = pp
+		}
+
 		files = append(files, operations.FileOperation{
-			Path:    path,
-			Content: content,
-			SHA:     sha,
+			Path:         path,
+			Content:      content,
+			SHA:          sha,
+			Operation:    operation,
+			Mode:         mode,
+			PreviousPath: previousPath,
 		})
 	}
 
 	// ファイル更新の実行
-	result, err := operations.PushFiles(operations.PushFilesOptions{
+	result, err := operations.PushFiles(ctx, operations.PushFilesOptions{
 		Owner:   owner,
 		Repo:    repo,
 		Branch:  branch,
@@ -573,13 +1660,13 @@ func handlePushFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		Message: message,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -590,39 +1677,39 @@ func handleForkRepository(ctx context.Context, request mcp.CallToolRequest) (*mc
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	owner, ok := request.Params.Arguments["owner"].(string)
+	owner, ok := request.GetArguments()["owner"].(string)
 	if !ok {
-		return nil, fmt.Errorf("owner must be a string")
+		return toolResultArgumentError("owner must be a string"), nil
 	}
 
-	repo, ok := request.Params.Arguments["repo"].(string)
+	repo, ok := request.GetArguments()["repo"].(string)
 	if !ok {
-		return nil, fmt.Errorf("repo must be a string")
+		return toolResultArgumentError("repo must be a string"), nil
 	}
 
 	organization := ""
-	if org, ok := request.Params.Arguments["organization"].(string); ok {
+	if org, ok := request.GetArguments()["organization"].(string); ok {
 		organization = org
 	}
 
 	// リポジトリフォークの実行
-	result, err := operations.ForkRepository(operations.ForkRepositoryOptions{
+	result, err := operations.ForkRepository(ctx, operations.ForkRepositoryOptions{
 		Owner:        owner,
 		Repo:         repo,
 		Organization: organization,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -633,40 +1720,101 @@ func handleGetPullRequest(ctx context.Context, request mcp.CallToolRequest) (*mc
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	owner, ok := request.Params.Arguments["owner"].(string)
+	owner, ok := request.GetArguments()["owner"].(string)
 	if !ok {
-		return nil, fmt.Errorf("owner must be a string")
+		return toolResultArgumentError("owner must be a string"), nil
 	}
 
-	repo, ok := request.Params.Arguments["repo"].(string)
+	repo, ok := request.GetArguments()["repo"].(string)
 	if !ok {
-		return nil, fmt.Errorf("repo must be a string")
+		return toolResultArgumentError("repo must be a string"), nil
 	}
 
-	pullNumberFloat, ok := request.Params.Arguments["pull_number"].(float64)
+	pullNumberFloat, ok := request.GetArguments()["pull_number"].(float64)
 	if !ok {
-		return nil, fmt.Errorf("pull_number must be a number")
+		return toolResultArgumentError("pull_number must be a number"), nil
 	}
 	pullNumber := int(pullNumberFloat)
 
 	// Pull Request取得の実行
-	result, err := operations.GetPullRequest(operations.GetPullRequestOptions{
+	result, err := operations.GetPullRequest(ctx, operations.GetPullRequestOptions{
 		Owner:      owner,
 		Repo:       repo,
 		PullNumber: pullNumber,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleListPullRequests はPull Request一覧の取得リクエストを処理します
+func handleListPullRequests(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, ok := args["owner"].(string)
+	if !ok {
+		return toolResultArgumentError("owner must be a string"), nil
+	}
+
+	repo, ok := args["repo"].(string)
+	if !ok {
+		return toolResultArgumentError("repo must be a string"), nil
+	}
+
+	options := operations.ListPullRequestsOptions{Owner: owner, Repo: repo}
+	if state, ok := args["state"].(string); ok {
+		if state != "open" && state != "closed" && state != "all" {
+			return toolResultArgumentError("state must be open, closed or all"), nil
+		}
+		options.State = state
+	}
+	if head, ok := args["head"].(string); ok {
+		options.Head = head
+	}
+	if base, ok := args["base"].(string); ok {
+		options.Base = base
+	}
+	if sort, ok := args["sort"].(string); ok {
+		options.Sort = sort
+	}
+	if direction, ok := args["direction"].(string); ok {
+		if direction != "asc" && direction != "desc" {
+			return toolResultArgumentError("direction must be asc or desc"), nil
+		}
+		options.Direction = direction
+	}
+	if options.PageOptions, err = pageOptionsFromArguments(args); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// Pull Request一覧の取得の実行
+	result, err := operations.ListPullRequests(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -677,52 +1825,52 @@ func handleCreatePullRequest(ctx context.Context, request mcp.CallToolRequest) (
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	owner, ok := request.Params.Arguments["owner"].(string)
+	owner, ok := request.GetArguments()["owner"].(string)
 	if !ok {
-		return nil, fmt.Errorf("owner must be a string")
+		return toolResultArgumentError("owner must be a string"), nil
 	}
 
-	repo, ok := request.Params.Arguments["repo"].(string)
+	repo, ok := request.GetArguments()["repo"].(string)
 	if !ok {
-		return nil, fmt.Errorf("repo must be a string")
+		return toolResultArgumentError("repo must be a string"), nil
 	}
 
-	title, ok := request.Params.Arguments["title"].(string)
+	title, ok := request.GetArguments()["title"].(string)
 	if !ok {
-		return nil, fmt.Errorf("title must be a string")
+		return toolResultArgumentError("title must be a string"), nil
 	}
 
-	head, ok := request.Params.Arguments["head"].(string)
+	head, ok := request.GetArguments()["head"].(string)
 	if !ok {
-		return nil, fmt.Errorf("head must be a string")
+		return toolResultArgumentError("head must be a string"), nil
 	}
 
-	base, ok := request.Params.Arguments["base"].(string)
+	base, ok := request.GetArguments()["base"].(string)
 	if !ok {
-		return nil, fmt.Errorf("base must be a string")
+		return toolResultArgumentError("base must be a string"), nil
 	}
 
 	body := ""
-	if b, ok := request.Params.Arguments["body"].(string); ok {
+	if b, ok := request.GetArguments()["body"].(string); ok {
 		body = b
 	}
 
 	draft := false
-	if d, ok := request.Params.Arguments["draft"].(bool); ok {
+	if d, ok := request.GetArguments()["draft"].(bool); ok {
 		draft = d
 	}
 
 	maintainerCanModify := false
-	if m, ok := request.Params.Arguments["maintainer_can_modify"].(bool); ok {
+	if m, ok := request.GetArguments()["maintainer_can_modify"].(bool); ok {
 		maintainerCanModify = m
 	}
 
 	// Pull Request作成の実行
-	result, err := operations.CreatePullRequest(operations.CreatePullRequestOptions{
+	result, err := operations.CreatePullRequest(ctx, operations.CreatePullRequestOptions{
 		Owner:               owner,
 		Repo:                repo,
 		Title:               title,
@@ -733,13 +1881,13 @@ func handleCreatePullRequest(ctx context.Context, request mcp.CallToolRequest) (
 		MaintainerCanModify: maintainerCanModify,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -750,58 +1898,64 @@ func handleCreatePullRequestReview(ctx context.Context, request mcp.CallToolRequ
 	// GitHubトークンの取得
 	token, err := common.GetAuthTokenFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// パラメータの解析
-	owner, ok := request.Params.Arguments["owner"].(string)
+	owner, ok := request.GetArguments()["owner"].(string)
 	if !ok {
-		return nil, fmt.Errorf("owner must be a string")
+		return toolResultArgumentError("owner must be a string"), nil
 	}
 
-	repo, ok := request.Params.Arguments["repo"].(string)
+	repo, ok := request.GetArguments()["repo"].(string)
 	if !ok {
-		return nil, fmt.Errorf("repo must be a string")
+		return toolResultArgumentError("repo must be a string"), nil
 	}
 
-	pullNumberFloat, ok := request.Params.Arguments["pull_number"].(float64)
+	pullNumberFloat, ok := request.GetArguments()["pull_number"].(float64)
 	if !ok {
-		return nil, fmt.Errorf("pull_number must be a number")
+		return toolResultArgumentError("pull_number must be a number"), nil
 	}
 	pullNumber := int(pullNumberFloat)
 
-	event, ok := request.Params.Arguments["event"].(string)
+	event, ok := request.GetArguments()["event"].(string)
 	if !ok {
-		return nil, fmt.Errorf("event must be a string")
+		return toolResultArgumentError("event must be a string"), nil
 	}
 
 	body := ""
-	if b, ok := request.Params.Arguments["body"].(string); ok {
+	if b, ok := request.GetArguments()["body"].(string); ok {
 		body = b
 	}
 
 	commitID := ""
-	if c, ok := request.Params.Arguments["commit_id"].(string); ok {
+	if c, ok := request.GetArguments()["commit_id"].(string); ok {
 		commitID = c
 	}
 
+	comments, err := reviewCommentsArgument(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
 	// Pull Requestレビュー作成の実行
-	result, err := operations.CreatePullRequestReview(operations.PullRequestReviewOptions{
+	result, err := operations.CreatePullRequestReview(ctx, operations.PullRequestReviewOptions{
 		Owner:      owner,
 		Repo:       repo,
 		PullNumber: pullNumber,
 		Body:       body,
 		Event:      event,
 		CommitID:   commitID,
+		Comments:   comments,
 	}, token)
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	// JSON形式で結果を返す
 	jsonResult, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
-		return nil, err
+		return toolResultError(err), nil
 	}
 
 	return mcp.NewToolResultText(string(jsonResult)), nil
@@ -0,0 +1,3649 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"github.com/yamagai/github-mcp-server-sse/internal/fakegithub"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+	"golang.org/x/oauth2"
+)
+
+// testEnv はフェイクGitHubサーバーとMCPサーバーを組み合わせたテスト環境です
+type testEnv struct {
+	t      *testing.T
+	gh     *fakegithub.Server
+	server *GitHubMCPServer
+	ctx    context.Context
+	nextID int
+}
+
+// newTestEnv はテスト環境を作成します
+// 各テストは octocat/hello リポジトリを持つフェイクサーバーに対して実行されます
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	return newTestEnvWithOptions(t, ServerOptions{})
+}
+
+// newTestEnvWithOptions は指定したサーバーオプションでテスト環境を作成します
+func newTestEnvWithOptions(t *testing.T, opts ServerOptions) *testEnv {
+	t.Helper()
+	gh := fakegithub.New()
+	t.Cleanup(gh.Close)
+	gh.CreateRepo(fakegithub.Login, "hello")
+
+	// 本番と同じくクライアントキャッシュを経由してフェイクサーバーに接続する
+	ctx := operations.WithClientCache(context.Background(), operations.NewClientCache(operations.NewClientFactory(gh.HTTPClient().Transport), 0, 0))
+	ctx = common.WithAuthToken(ctx, "test-token")
+
+	return &testEnv{
+		t:      t,
+		gh:     gh,
+		server: NewGitHubMCPServer(opts),
+		ctx:    ctx,
+	}
+}
+
+// request はJSON-RPCリクエストをインメモリでMCPサーバーに送信し、結果を返します
+func (e *testEnv) request(ctx context.Context, method string, params interface{}) json.RawMessage {
+	e.t.Helper()
+	e.nextID++
+	message, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      e.nextID,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		e.t.Fatalf("failed to marshal request: %v", err)
+	}
+
+	response := e.server.server.HandleMessage(ctx, message)
+	raw, err := json.Marshal(response)
+	if err != nil {
+		e.t.Fatalf("failed to marshal response: %v", err)
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		e.t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if envelope.Error != nil {
+		e.t.Fatalf("%s returned JSON-RPC error %d: %s", method, envelope.Error.Code, envelope.Error.Message)
+	}
+	return envelope.Result
+}
+
+// callTool はツールを呼び出して結果を返します
+func (e *testEnv) callTool(name string, args map[string]interface{}) *mcp.CallToolResult {
+	e.t.Helper()
+	return e.callToolWithContext(e.ctx, name, args)
+}
+
+// callToolWithContext は指定したコンテキストでツールを呼び出します
+func (e *testEnv) callToolWithContext(ctx context.Context, name string, args map[string]interface{}) *mcp.CallToolResult {
+	e.t.Helper()
+	raw := e.request(ctx, "tools/call", map[string]interface{}{
+		"name":      name,
+		"arguments": args,
+	})
+	result, err := mcp.ParseCallToolResult(&raw)
+	if err != nil {
+		e.t.Fatalf("failed to parse tool result: %v", err)
+	}
+	return result
+}
+
+// listTools は登録されているツール名を返します
+func (e *testEnv) listTools() []string {
+	e.t.Helper()
+	raw := e.request(e.ctx, "tools/list", map[string]interface{}{})
+	var result mcp.ListToolsResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		e.t.Fatalf("failed to unmarshal tools: %v", err)
+	}
+	names := make([]string, 0, len(result.Tools))
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resultText はツール結果のテキストを連結して返します
+func resultText(result *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// decodeResult は成功したツール結果のJSONをデコードします
+func decodeResult(t *testing.T, result *mcp.CallToolResult, v interface{}) {
+	t.Helper()
+	if result.IsError {
+		t.Fatalf("tool returned error: %s", resultText(result))
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), v); err != nil {
+		t.Fatalf("failed to decode tool result %q: %v", resultText(result), err)
+	}
+}
+
+// expectToolError はツール結果が指定したコードのエラーであることを確認します
+func expectToolError(t *testing.T, result *mcp.CallToolResult, code string) toolErrorResult {
+	t.Helper()
+	if !result.IsError {
+		t.Fatalf("expected error %q but got success: %s", code, resultText(result))
+	}
+	var body toolErrorResult
+	if err := json.Unmarshal([]byte(resultText(result)), &body); err != nil {
+		t.Fatalf("failed to decode error result %q: %v", resultText(result), err)
+	}
+	if body.Code != code {
+		t.Fatalf("expected error code %q but got %q (%s)", code, body.Code, body.Message)
+	}
+	return body
+}
+
+func TestListTools(t *testing.T) {
+	env := newTestEnv(t)
+	want := []string{
+		"add_issue_comment",
+		"create_issue",
+		"create_or_update_file",
+		"create_pull_request",
+		"create_pull_request_review",
+		"create_repository",
+		"disable_pull_request_auto_merge",
+		"enable_pull_request_auto_merge",
+		"fork_repository",
+		"get_file_contents",
+		"get_issue",
+		"get_pull_request",
+		"get_pull_request_diff",
+		"get_pull_request_files",
+		"list_issue_comments",
+		"list_issues",
+		"list_pull_request_review_comments",
+		"list_pull_requests",
+		"merge_pull_request",
+		"push_files",
+		"reply_to_pull_request_review_comment",
+		"resolve_pull_request_review_thread",
+		"search_code",
+		"search_commits",
+		"search_issues",
+		"search_repositories",
+		"search_users",
+		"unresolve_pull_request_review_thread",
+		"update_issue",
+		"update_pull_request",
+		"update_pull_request_branch",
+	}
+	if got := env.listTools(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tools = %v, want %v", got, want)
+	}
+}
+
+func TestSearchRepositories(t *testing.T) {
+	env := newTestEnv(t)
+	env.gh.CreateRepo(fakegithub.Login, "hello-go")
+	env.gh.CreateRepo("someone", "hello-rust")
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantTotal int
+		wantNames []string
+		wantCode  string
+	}{
+		{
+			name:      "matches by name",
+			args:      map[string]interface{}{"query": "hello"},
+			wantTotal: 3,
+			wantNames: []string{"octocat/hello", "octocat/hello-go", "someone/hello-rust"},
+		},
+		{
+			name:      "user qualifier",
+			args:      map[string]interface{}{"query": "hello user:someone"},
+			wantTotal: 1,
+			wantNames: []string{"someone/hello-rust"},
+		},
+		{
+			name:      "second page",
+			args:      map[string]interface{}{"query": "hello", "page": 2, "per_page": 2},
+			wantTotal: 3,
+			wantNames: []string{"someone/hello-rust"},
+		},
+		{
+			name:      "max items across pages",
+			args:      map[string]interface{}{"query": "hello", "per_page": 2, "max_items": 3},
+			wantTotal: 3,
+			wantNames: []string{"octocat/hello", "octocat/hello-go", "someone/hello-rust"},
+		},
+		{
+			name:     "missing query",
+			args:     map[string]interface{}{},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+		{
+			name:     "invalid cursor",
+			args:     map[string]interface{}{"query": "hello", "cursor": "bogus"},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+		{
+			name:     "per page too large",
+			args:     map[string]interface{}{"query": "hello", "per_page": 500},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.callTool("search_repositories", tt.args)
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+
+			var got struct {
+				TotalCount int `json:"total_count"`
+				Items      []struct {
+					FullName string `json:"full_name"`
+				} `json:"items"`
+			}
+			decodeResult(t, result, &got)
+			if got.TotalCount != tt.wantTotal {
+				t.Errorf("total_count = %d, want %d", got.TotalCount, tt.wantTotal)
+			}
+			var names []string
+			for _, item := range got.Items {
+				names = append(names, item.FullName)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("items = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+
+	t.Run("next cursor", func(t *testing.T) {
+		var names []string
+		args := map[string]interface{}{"query": "hello", "per_page": 2}
+		for calls := 0; ; calls++ {
+			if calls > 2 {
+				t.Fatalf("too many pages: %v", names)
+			}
+			var got struct {
+				Items []struct {
+					FullName string `json:"full_name"`
+				} `json:"items"`
+				NextCursor string `json:"next_cursor"`
+			}
+			decodeResult(t, env.callTool("search_repositories", args), &got)
+			for _, item := range got.Items {
+				names = append(names, item.FullName)
+			}
+			if got.NextCursor == "" {
+				break
+			}
+			args = map[string]interface{}{"query": "hello", "cursor": got.NextCursor}
+		}
+		if want := []string{"octocat/hello", "octocat/hello-go", "someone/hello-rust"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("items = %v, want %v", names, want)
+		}
+	})
+}
+
+func TestSearch(t *testing.T) {
+	env := newTestEnv(t)
+	env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{
+		"main.go":     "package main\n\nfunc Greet() string { return \"hello\" }\n",
+		"docs/api.md": "Greet returns a greeting\n",
+	})
+	env.gh.CreateRepo("someone", "tools")
+	env.gh.SetFiles("someone", "tools", "main", map[string]string{"greet.go": "// Greet greets\n"})
+	env.gh.CreateBranch(fakegithub.Login, "hello", "feature", "main")
+	env.gh.SetFiles(fakegithub.Login, "hello", "feature", map[string]string{"feature.txt": "feature"})
+	env.gh.CreatePull(fakegithub.Login, "hello", "feature", "main", "Add greeting feature")
+
+	t.Run("code", func(t *testing.T) {
+		var got struct {
+			TotalCount int `json:"total_count"`
+			Items      []struct {
+				Repository  string `json:"repository"`
+				Path        string `json:"path"`
+				TextMatches []struct {
+					Fragment string   `json:"fragment"`
+					Matches  []string `json:"matches"`
+				} `json:"text_matches"`
+			} `json:"items"`
+		}
+		decodeResult(t, env.callTool("search_code", map[string]interface{}{"query": "greet extension:go"}), &got)
+		if got.TotalCount != 2 || len(got.Items) != 2 {
+			t.Fatalf("result = %+v, want 2 items", got)
+		}
+		first := got.Items[0]
+		if first.Repository != "octocat/hello" || first.Path != "main.go" {
+			t.Errorf("first item = %+v", first)
+		}
+		if len(first.TextMatches) != 1 || first.TextMatches[0].Fragment != `func Greet() string { return "hello" }` ||
+			!reflect.DeepEqual(first.TextMatches[0].Matches, []string{"Greet"}) {
+			t.Errorf("text_matches = %+v", first.TextMatches)
+		}
+	})
+
+	t.Run("code requires a search term", func(t *testing.T) {
+		expectToolError(t, env.callTool("search_code", map[string]interface{}{"query": "repo:octocat/hello"}), common.ErrorCodeValidation)
+	})
+
+	t.Run("issues", func(t *testing.T) {
+		var got struct {
+			TotalCount int `json:"total_count"`
+			Items      []struct {
+				Repository    string `json:"repository"`
+				Number        int    `json:"number"`
+				Title         string `json:"title"`
+				State         string `json:"state"`
+				IsPullRequest bool   `json:"is_pull_request"`
+				Author        string `json:"author"`
+			} `json:"items"`
+		}
+		decodeResult(t, env.callTool("search_issues", map[string]interface{}{"query": "greeting is:pr is:open"}), &got)
+		if got.TotalCount != 1 || len(got.Items) != 1 {
+			t.Fatalf("result = %+v, want 1 item", got)
+		}
+		item := got.Items[0]
+		if item.Repository != "octocat/hello" || item.Number != 1 || item.Title != "Add greeting feature" ||
+			item.State != "open" || !item.IsPullRequest || item.Author != fakegithub.Login {
+			t.Errorf("item = %+v", item)
+		}
+
+		decodeResult(t, env.callTool("search_issues", map[string]interface{}{"query": "greeting is:issue"}), &got)
+		if got.TotalCount != 0 {
+			t.Errorf("is:issue should not match pull requests: %+v", got)
+		}
+	})
+
+	t.Run("commits", func(t *testing.T) {
+		var got struct {
+			TotalCount int `json:"total_count"`
+			Items      []struct {
+				Repository string `json:"repository"`
+				SHA        string `json:"sha"`
+				Message    string `json:"message"`
+			} `json:"items"`
+		}
+		decodeResult(t, env.callTool("search_commits", map[string]interface{}{"query": "update repo:octocat/hello"}), &got)
+		if got.TotalCount != 2 || len(got.Items) != 2 {
+			t.Fatalf("result = %+v, want 2 items", got)
+		}
+		for _, item := range got.Items {
+			if item.Repository != "octocat/hello" || item.Message != "Update files" || item.SHA == "" {
+				t.Errorf("item = %+v", item)
+			}
+		}
+	})
+
+	t.Run("users", func(t *testing.T) {
+		var got struct {
+			TotalCount int `json:"total_count"`
+			Items      []struct {
+				Login string `json:"login"`
+			} `json:"items"`
+		}
+		decodeResult(t, env.callTool("search_users", map[string]interface{}{"query": "some"}), &got)
+		if got.TotalCount != 1 || len(got.Items) != 1 || got.Items[0].Login != "someone" {
+			t.Errorf("result = %+v", got)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		var got struct {
+			TotalCount int `json:"total_count"`
+			Items      []struct {
+				Path string `json:"path"`
+			} `json:"items"`
+			NextCursor string `json:"next_cursor"`
+		}
+		decodeResult(t, env.callTool("search_code", map[string]interface{}{"query": "greet", "per_page": 2}), &got)
+		if got.TotalCount != 3 || len(got.Items) != 2 || got.NextCursor == "" {
+			t.Fatalf("first page = %+v", got)
+		}
+		cursor := got.NextCursor
+		got.NextCursor = ""
+		decodeResult(t, env.callTool("search_code", map[string]interface{}{"query": "greet", "cursor": cursor}), &got)
+		if len(got.Items) != 1 || got.Items[0].Path != "greet.go" || got.NextCursor != "" {
+			t.Errorf("second page = %+v", got)
+		}
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		for _, tool := range []string{"search_code", "search_issues", "search_commits", "search_users"} {
+			expectToolError(t, env.callTool(tool, map[string]interface{}{}), common.ErrorCodeInvalidArgument)
+			expectToolError(t, env.callTool(tool, map[string]interface{}{"query": "a", "order": "up"}), common.ErrorCodeInvalidArgument)
+		}
+	})
+}
+
+func TestCreateRepository(t *testing.T) {
+	env := newTestEnv(t)
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantCode string
+	}{
+		{
+			name: "creates repository",
+			args: map[string]interface{}{"name": "new-repo", "description": "desc", "private": true, "auto_init": true},
+		},
+		{
+			name:     "name already exists",
+			args:     map[string]interface{}{"name": "hello"},
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name:     "missing name",
+			args:     map[string]interface{}{},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.callTool("create_repository", tt.args)
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+
+			var repo struct {
+				FullName    string `json:"full_name"`
+				Description string `json:"description"`
+				Private     bool   `json:"private"`
+			}
+			decodeResult(t, result, &repo)
+			if repo.FullName != "octocat/new-repo" || repo.Description != "desc" || !repo.Private {
+				t.Errorf("unexpected repository: %+v", repo)
+			}
+			if _, ok := env.gh.File(fakegithub.Login, "new-repo", "main", "README.md"); !ok {
+				t.Errorf("auto_init did not create README.md")
+			}
+		})
+	}
+}
+
+func TestGetFileContents(t *testing.T) {
+	env := newTestEnv(t)
+	env.gh.CreateBranch(fakegithub.Login, "hello", "feature", "main")
+	env.gh.SetFiles(fakegithub.Login, "hello", "feature", map[string]string{"docs/guide.md": "guide"})
+
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		wantContent string
+		wantCode    string
+	}{
+		{
+			name:        "default branch",
+			args:        map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "README.md"},
+			wantContent: "# hello\n",
+		},
+		{
+			name:        "specific branch",
+			args:        map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "docs/guide.md", "branch": "feature"},
+			wantContent: "guide",
+		},
+		{
+			name:     "missing file",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "nope.txt"},
+			wantCode: common.ErrorCodeNotFound,
+		},
+		{
+			name:     "missing repository",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "nope", "path": "README.md"},
+			wantCode: common.ErrorCodeNotFound,
+		},
+		{
+			name:     "missing path",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello"},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.callTool("get_file_contents", tt.args)
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+
+			var file struct {
+				Content string `json:"content"`
+				SHA     string `json:"sha"`
+			}
+			decodeResult(t, result, &file)
+			if file.Content != tt.wantContent {
+				t.Errorf("content = %q, want %q", file.Content, tt.wantContent)
+			}
+			if file.SHA == "" {
+				t.Errorf("sha is empty")
+			}
+		})
+	}
+}
+
+func TestGetFileContentsKinds(t *testing.T) {
+	env := newTestEnv(t)
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01"
+	binary := "\x00\x01\x02\xff"
+	large := strings.Repeat("a", 1024*1024+10)
+	env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{
+		"docs/guide.md":  "guide",
+		"docs/logo.png":  png,
+		"data/blob.bin":  binary,
+		"data/large.txt": large,
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		result := env.callTool("get_file_contents", map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "docs"})
+		var dir operations.FileContent
+		decodeResult(t, result, &dir)
+		if dir.Type != "dir" || dir.Path != "docs" {
+			t.Errorf("type = %s, path = %s, want dir docs", dir.Type, dir.Path)
+		}
+		var names []string
+		for _, entry := range dir.Entries {
+			names = append(names, entry.Name+":"+entry.Type)
+			if entry.SHA == "" {
+				t.Errorf("entry %s has no sha", entry.Name)
+			}
+		}
+		if want := []string{"guide.md:file", "logo.png:file"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("entries = %v, want %v", names, want)
+		}
+	})
+
+	t.Run("root directory", func(t *testing.T) {
+		result := env.callTool("get_file_contents", map[string]interface{}{"owner": "octocat", "repo": "hello", "path": ""})
+		var dir operations.FileContent
+		decodeResult(t, result, &dir)
+		var names []string
+		for _, entry := range dir.Entries {
+			names = append(names, entry.Name+":"+entry.Type)
+		}
+		if want := []string{"README.md:file", "data:dir", "docs:dir"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("entries = %v, want %v", names, want)
+		}
+	})
+
+	t.Run("image", func(t *testing.T) {
+		result := env.callTool("get_file_contents", map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "docs/logo.png"})
+		if result.IsError || len(result.Content) != 2 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		image, ok := result.Content[1].(mcp.ImageContent)
+		if !ok {
+			t.Fatalf("content[1] = %T, want image", result.Content[1])
+		}
+		if image.MIMEType != "image/png" || image.Data != base64.StdEncoding.EncodeToString([]byte(png)) {
+			t.Errorf("image = %s %q", image.MIMEType, image.Data)
+		}
+		var meta operations.FileContent
+		decodeResult(t, &mcp.CallToolResult{Content: result.Content[:1]}, &meta)
+		if meta.Encoding != operations.ContentEncodingBase64 || meta.Content != "" {
+			t.Errorf("metadata = %+v, want base64 without content", meta)
+		}
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		result := env.callTool("get_file_contents", map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "data/blob.bin"})
+		if result.IsError || len(result.Content) != 2 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		embedded, ok := result.Content[1].(mcp.EmbeddedResource)
+		if !ok {
+			t.Fatalf("content[1] = %T, want embedded resource", result.Content[1])
+		}
+		blob, ok := embedded.Resource.(mcp.BlobResourceContents)
+		if !ok {
+			t.Fatalf("resource = %T, want blob", embedded.Resource)
+		}
+		if blob.URI != "github://octocat/hello/contents/data/blob.bin" || blob.MIMEType != "application/octet-stream" {
+			t.Errorf("blob uri = %s, mime = %s", blob.URI, blob.MIMEType)
+		}
+		if blob.Blob != base64.StdEncoding.EncodeToString([]byte(binary)) {
+			t.Errorf("blob = %q", blob.Blob)
+		}
+	})
+
+	t.Run("large file", func(t *testing.T) {
+		env.gh.ResetRequests()
+		result := env.callTool("get_file_contents", map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "data/large.txt"})
+		var file operations.FileContent
+		decodeResult(t, result, &file)
+		if file.Content != large || file.Encoding != operations.ContentEncodingText {
+			t.Errorf("content length = %d, encoding = %s", len(file.Content), file.Encoding)
+		}
+		want := []string{
+			"GET /repos/octocat/hello/contents/data/large.txt",
+			"GET /repos/octocat/hello/git/blobs/" + file.SHA,
+		}
+		if got := env.gh.Requests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("requests = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestCreateOrUpdateFile(t *testing.T) {
+	env := newTestEnv(t)
+	readmeSHA := env.gh.FileSHA(fakegithub.Login, "hello", "main", "README.md")
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantCode string
+		wantFile string
+	}{
+		{
+			name:     "creates new file",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "new.txt", "content": "new", "message": "add"},
+			wantFile: "new.txt",
+		},
+		{
+			name:     "updates with sha",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "README.md", "content": "updated", "message": "update", "sha": readmeSHA},
+			wantFile: "README.md",
+		},
+		{
+			name:     "update without sha",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "new.txt", "content": "again", "message": "update"},
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name:     "stale sha",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "README.md", "content": "stale", "message": "update", "sha": readmeSHA},
+			wantCode: common.ErrorCodeConflict,
+		},
+		{
+			name:     "missing message",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "x.txt", "content": "x"},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.callTool("create_or_update_file", tt.args)
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+
+			var commit struct {
+				SHA     string `json:"sha"`
+				Message string `json:"message"`
+			}
+			decodeResult(t, result, &commit)
+			if commit.SHA != env.gh.BranchSHA(fakegithub.Login, "hello", "main") {
+				t.Errorf("commit sha %s is not the branch head", commit.SHA)
+			}
+			content, _ := env.gh.File(fakegithub.Login, "hello", "main", tt.wantFile)
+			if content != tt.args["content"] {
+				t.Errorf("%s = %q, want %q", tt.wantFile, content, tt.args["content"])
+			}
+		})
+	}
+}
+
+func TestPushFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(env *testEnv)
+		files     func(env *testEnv) []interface{}
+		wantCode  string
+		wantFiles map[string]string
+		wantGone  []string
+		wantModes map[string]string
+	}{
+		{
+			name: "adds and updates files in a single commit",
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "README.md", "content": "updated"},
+					map[string]interface{}{"path": "src/main.go", "content": "package main"},
+				}
+			},
+			wantFiles: map[string]string{"README.md": "updated", "src/main.go": "package main"},
+		},
+		{
+			name: "delete, rename and modes",
+			setup: func(env *testEnv) {
+				env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{
+					"old.txt":    "old",
+					"remove.txt": "remove",
+					"run.sh":     "echo hi",
+				})
+				env.gh.SetFileMode(fakegithub.Login, "hello", "main", "run.sh", "100755")
+			},
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "remove.txt", "operation": "delete"},
+					map[string]interface{}{"path": "new.txt", "operation": "rename", "previous_path": "old.txt"},
+					map[string]interface{}{"path": "run.sh", "operation": "update", "content": "echo bye"},
+					map[string]interface{}{"path": "tool.sh", "operation": "add", "content": "#!/bin/sh", "mode": "executable"},
+					map[string]interface{}{"path": "link", "content": "README.md", "mode": "symlink"},
+				}
+			},
+			wantFiles: map[string]string{"new.txt": "old", "run.sh": "echo bye", "tool.sh": "#!/bin/sh", "link": "README.md"},
+			wantGone:  []string{"remove.txt", "old.txt"},
+			wantModes: map[string]string{"run.sh": "100755", "tool.sh": "100755", "link": "120000", "new.txt": "100644"},
+		},
+		{
+			name: "rename to an empty file",
+			setup: func(env *testEnv) {
+				env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"old.txt": "old"})
+			},
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "empty.txt", "operation": "rename", "previous_path": "old.txt", "content": ""},
+				}
+			},
+			wantFiles: map[string]string{"empty.txt": ""},
+			wantGone:  []string{"old.txt"},
+		},
+		{
+			name: "matching sha is accepted",
+			files: func(env *testEnv) []interface{} {
+				sha := env.gh.FileSHA(fakegithub.Login, "hello", "main", "README.md")
+				return []interface{}{
+					map[string]interface{}{"path": "README.md", "content": "checked", "sha": sha},
+				}
+			},
+			wantFiles: map[string]string{"README.md": "checked"},
+		},
+		{
+			name: "stale sha is a conflict",
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "README.md", "content": "stale", "sha": "0000000000000000000000000000000000000000"},
+				}
+			},
+			wantCode: common.ErrorCodeConflict,
+		},
+		{
+			name: "add of existing file is a conflict",
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "README.md", "operation": "add", "content": "dup"},
+				}
+			},
+			wantCode: common.ErrorCodeConflict,
+		},
+		{
+			name: "rename onto existing file is a conflict",
+			setup: func(env *testEnv) {
+				env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"old.txt": "old"})
+			},
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "README.md", "operation": "rename", "previous_path": "old.txt"},
+				}
+			},
+			wantCode: common.ErrorCodeConflict,
+		},
+		{
+			name: "multiple operations on the same path",
+			setup: func(env *testEnv) {
+				env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"old.txt": "old"})
+			},
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "new.txt", "operation": "rename", "previous_path": "old.txt"},
+					map[string]interface{}{"path": "old.txt", "content": "recreated"},
+				}
+			},
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name: "delete of missing file",
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "missing.txt", "operation": "delete"},
+				}
+			},
+			wantCode: common.ErrorCodeNotFound,
+		},
+		{
+			name: "unknown operation",
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "README.md", "operation": "copy", "content": "x"},
+				}
+			},
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name: "branch moved while pushing",
+			setup: func(env *testEnv) {
+				var once sync.Once
+				env.gh.BeforeRequest = func(r *http.Request) {
+					if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/git/commits") {
+						once.Do(func() {
+							env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"other.txt": "concurrent"})
+						})
+					}
+				}
+			},
+			files: func(env *testEnv) []interface{} {
+				return []interface{}{
+					map[string]interface{}{"path": "README.md", "content": "mine"},
+				}
+			},
+			wantCode: common.ErrorCodeConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			if tt.setup != nil {
+				tt.setup(env)
+			}
+			headBefore := env.gh.BranchSHA(fakegithub.Login, "hello", "main")
+			files := tt.files(env)
+			env.gh.ResetRequests()
+
+			result := env.callTool("push_files", map[string]interface{}{
+				"owner":   "octocat",
+				"repo":    "hello",
+				"branch":  "main",
+				"message": "push",
+				"files":   files,
+			})
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+
+			var commit struct {
+				SHA string `json:"sha"`
+			}
+			decodeResult(t, result, &commit)
+
+			wantRequests := []string{
+				"GET /repos/octocat/hello/git/ref/heads/main",
+				"GET /repos/octocat/hello/git/commits/" + headBefore,
+				"GET /repos/octocat/hello/git/trees/" + mustCommit(t, env, headBefore).Tree,
+				"POST /repos/octocat/hello/git/trees",
+				"POST /repos/octocat/hello/git/commits",
+				"GET /repos/octocat/hello/git/ref/heads/main",
+				"PATCH /repos/octocat/hello/git/refs/heads/main",
+			}
+			if got := env.gh.Requests(); !reflect.DeepEqual(got, wantRequests) {
+				t.Errorf("requests =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(wantRequests, "\n"))
+			}
+
+			if head := env.gh.BranchSHA(fakegithub.Login, "hello", "main"); head != commit.SHA {
+				t.Errorf("branch head = %s, want %s", head, commit.SHA)
+			}
+			if parents := mustCommit(t, env, commit.SHA).Parents; !reflect.DeepEqual(parents, []string{headBefore}) {
+				t.Errorf("parents = %v, want [%s]", parents, headBefore)
+			}
+			for path, want := range tt.wantFiles {
+				if got, _ := env.gh.File(fakegithub.Login, "hello", "main", path); got != want {
+					t.Errorf("%s = %q, want %q", path, got, want)
+				}
+			}
+			for _, path := range tt.wantGone {
+				if _, ok := env.gh.File(fakegithub.Login, "hello", "main", path); ok {
+					t.Errorf("%s should have been removed", path)
+				}
+			}
+			for path, want := range tt.wantModes {
+				if got := env.gh.FileMode(fakegithub.Login, "hello", "main", path); got != want {
+					t.Errorf("mode of %s = %s, want %s", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+// mustCommit はフェイクサーバーからコミットを取得します
+func mustCommit(t *testing.T, env *testEnv, sha string) fakegithub.Commit {
+	t.Helper()
+	c, ok := env.gh.GetCommit(fakegithub.Login, "hello", sha)
+	if !ok {
+		t.Fatalf("commit %s not found", sha)
+	}
+	return c
+}
+
+func TestForkRepository(t *testing.T) {
+	env := newTestEnv(t)
+	env.gh.CreateRepo("upstream", "project")
+
+	tests := []struct {
+		name         string
+		args         map[string]interface{}
+		wantFullName string
+		wantCode     string
+	}{
+		{
+			name:         "fork to user",
+			args:         map[string]interface{}{"owner": "upstream", "repo": "project"},
+			wantFullName: "octocat/project",
+		},
+		{
+			name:         "fork to organization",
+			args:         map[string]interface{}{"owner": "upstream", "repo": "project", "organization": "my-org"},
+			wantFullName: "my-org/project",
+		},
+		{
+			name:     "missing source",
+			args:     map[string]interface{}{"owner": "upstream", "repo": "nope"},
+			wantCode: common.ErrorCodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.callTool("fork_repository", tt.args)
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+
+			var repo struct {
+				FullName string `json:"full_name"`
+				Fork     bool   `json:"fork"`
+			}
+			decodeResult(t, result, &repo)
+			if repo.FullName != tt.wantFullName || !repo.Fork {
+				t.Errorf("unexpected fork: %+v", repo)
+			}
+		})
+	}
+}
+
+func TestPullRequests(t *testing.T) {
+	env := newTestEnv(t)
+	env.gh.CreateBranch(fakegithub.Login, "hello", "feature", "main")
+	featureHead := env.gh.SetFiles(fakegithub.Login, "hello", "feature", map[string]string{"feature.txt": "feature"})
+
+	result := env.callTool("create_pull_request", map[string]interface{}{
+		"owner": "octocat",
+		"repo":  "hello",
+		"title": "Add feature",
+		"body":  "details",
+		"head":  "feature",
+		"base":  "main",
+		"draft": true,
+	})
+	var created struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Head   struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	decodeResult(t, result, &created)
+	if created.Number != 1 || created.Title != "Add feature" || !created.Draft || created.Head.SHA != featureHead {
+		t.Fatalf("unexpected pull request: %+v", created)
+	}
+
+	tests := []struct {
+		name     string
+		tool     string
+		args     map[string]interface{}
+		wantCode string
+		check    func(t *testing.T, result *mcp.CallToolResult)
+	}{
+		{
+			name: "get pull request",
+			tool: "get_pull_request",
+			args: map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": 1},
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				var pr struct {
+					Number int    `json:"number"`
+					Body   string `json:"body"`
+					Base   struct {
+						Ref string `json:"ref"`
+					} `json:"base"`
+				}
+				decodeResult(t, result, &pr)
+				if pr.Number != 1 || pr.Body != "details" || pr.Base.Ref != "main" {
+					t.Errorf("unexpected pull request: %+v", pr)
+				}
+			},
+		},
+		{
+			name:     "get missing pull request",
+			tool:     "get_pull_request",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": 99},
+			wantCode: common.ErrorCodeNotFound,
+		},
+		{
+			name:     "create with unknown head",
+			tool:     "create_pull_request",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "title": "x", "head": "nope", "base": "main"},
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name: "approve",
+			tool: "create_pull_request_review",
+			args: map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": 1, "event": "APPROVE"},
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				var review struct {
+					State    string `json:"state"`
+					CommitID string `json:"commit_id"`
+				}
+				decodeResult(t, result, &review)
+				if review.State != "APPROVED" || review.CommitID != featureHead {
+					t.Errorf("unexpected review: %+v", review)
+				}
+			},
+		},
+		{
+			name:     "request changes without body",
+			tool:     "create_pull_request_review",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": 1, "event": "REQUEST_CHANGES"},
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name:     "review without event",
+			tool:     "create_pull_request_review",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": 1},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.callTool(tt.tool, tt.args)
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+			tt.check(t, result)
+		})
+	}
+}
+
+func TestListPullRequests(t *testing.T) {
+	env := newTestEnv(t)
+	for _, branch := range []string{"feature-a", "feature-b", "develop", "feature-c"} {
+		env.gh.CreateBranch(fakegithub.Login, "hello", branch, "main")
+	}
+	env.gh.CreatePull(fakegithub.Login, "hello", "feature-a", "main", "Old change")
+	env.gh.Advance(8 * 24 * time.Hour)
+	env.gh.CreatePull(fakegithub.Login, "hello", "feature-b", "main", "New change")
+	env.gh.CreatePull(fakegithub.Login, "hello", "feature-c", "develop", "Develop change")
+
+	tests := []struct {
+		name        string
+		args        map[string]interface{}
+		want        []int
+		wantCursor  bool
+		wantCode    string
+		wantRequest string
+	}{
+		{
+			name: "newest first by default",
+			args: map[string]interface{}{},
+			want: []int{3, 2, 1},
+		},
+		{
+			name: "oldest updated against main",
+			args: map[string]interface{}{"base": "main", "sort": "updated", "direction": "asc"},
+			want: []int{1, 2},
+		},
+		{
+			name:        "head branch without owner",
+			args:        map[string]interface{}{"head": "feature-b"},
+			want:        []int{2},
+			wantRequest: "head=octocat%3Afeature-b",
+		},
+		{
+			name: "head with owner",
+			args: map[string]interface{}{"head": "octocat:feature-c"},
+			want: []int{3},
+		},
+		{
+			name: "closed",
+			args: map[string]interface{}{"state": "closed"},
+			want: []int{},
+		},
+		{
+			name:       "first page",
+			args:       map[string]interface{}{"per_page": 2},
+			want:       []int{3, 2},
+			wantCursor: true,
+		},
+		{
+			name:     "invalid state",
+			args:     map[string]interface{}{"state": "merged"},
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.gh.ResetRequests()
+			tt.args["owner"], tt.args["repo"] = "octocat", "hello"
+			result := env.callTool("list_pull_requests", tt.args)
+			if tt.wantCode != "" {
+				expectToolError(t, result, tt.wantCode)
+				return
+			}
+
+			var got struct {
+				Items []struct {
+					Number int `json:"number"`
+				} `json:"items"`
+				NextCursor string `json:"next_cursor"`
+			}
+			decodeResult(t, result, &got)
+			numbers := []int{}
+			for _, item := range got.Items {
+				numbers = append(numbers, item.Number)
+			}
+			if !reflect.DeepEqual(numbers, tt.want) {
+				t.Errorf("pull requests = %v, want %v", numbers, tt.want)
+			}
+			if (got.NextCursor != "") != tt.wantCursor {
+				t.Errorf("next_cursor = %q", got.NextCursor)
+			}
+			if tt.wantRequest != "" {
+				if urls := env.gh.RequestURLs(); len(urls) != 1 || !strings.Contains(urls[0], tt.wantRequest) {
+					t.Errorf("request URLs = %v, want a query containing %q", urls, tt.wantRequest)
+				}
+			}
+		})
+	}
+
+	t.Run("summary", func(t *testing.T) {
+		var got struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		decodeResult(t, env.callTool("list_pull_requests", map[string]interface{}{"owner": "octocat", "repo": "hello", "head": "feature-a"}), &got)
+		if len(got.Items) != 1 {
+			t.Fatalf("items = %v", got.Items)
+		}
+		item := got.Items[0]
+		if item["title"] != "Old change" || item["author"] != fakegithub.Login || item["head"] != "octocat:feature-a" ||
+			item["base"] != "main" || item["draft"] != false || item["updated_at"] == nil {
+			t.Errorf("summary = %v", item)
+		}
+		// 詳細なPull Requestの項目は含めない
+		for _, key := range []string{"body", "requested_reviewers", "mergeable"} {
+			if _, ok := item[key]; ok {
+				t.Errorf("summary should not include %s", key)
+			}
+		}
+	})
+}
+
+func TestPullRequestActions(t *testing.T) {
+	env := newTestEnv(t)
+	for i, branch := range []string{"clean", "dirty", "behind", "edit", "blocked", "ready"} {
+		env.gh.CreateBranch(fakegithub.Login, "hello", branch, "main")
+		env.gh.SetFiles(fakegithub.Login, "hello", branch, map[string]string{branch + ".txt": branch})
+		env.gh.CreatePull(fakegithub.Login, "hello", branch, "main", fmt.Sprintf("Change %d", i+1))
+	}
+	env.gh.SetMergeableState(fakegithub.Login, "hello", 2, "dirty")
+	env.gh.SetMergeableState(fakegithub.Login, "hello", 3, "behind")
+	env.gh.SetMergeableState(fakegithub.Login, "hello", 5, "blocked")
+	env.gh.SetAllowedMergeMethods(fakegithub.Login, "hello", "merge", "squash")
+	behindHead := env.gh.BranchSHA(fakegithub.Login, "hello", "behind")
+
+	target := func(number int, extra map[string]interface{}) map[string]interface{} {
+		args := map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": number}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return args
+	}
+	type pullRequest struct {
+		Title     string `json:"title"`
+		State     string `json:"state"`
+		Draft     bool   `json:"draft"`
+		AutoMerge *struct {
+			MergeMethod string `json:"merge_method"`
+		} `json:"auto_merge"`
+	}
+	getPull := func(t *testing.T, number int) pullRequest {
+		t.Helper()
+		var pr pullRequest
+		decodeResult(t, env.callTool("get_pull_request", target(number, nil)), &pr)
+		return pr
+	}
+
+	tests := []struct {
+		name        string
+		tool        string
+		args        map[string]interface{}
+		wantCode    string
+		wantMessage string
+		check       func(t *testing.T, result *mcp.CallToolResult)
+	}{
+		{
+			name:     "merge with stale head sha",
+			tool:     "merge_pull_request",
+			args:     target(1, map[string]interface{}{"sha": behindHead}),
+			wantCode: common.ErrorCodeConflict,
+		},
+		{
+			name:        "merge with disallowed method",
+			tool:        "merge_pull_request",
+			args:        target(1, map[string]interface{}{"merge_method": "rebase"}),
+			wantCode:    common.ErrorCodeValidation,
+			wantMessage: "Rebase merges are not allowed on this repository",
+		},
+		{
+			name: "squash merge",
+			tool: "merge_pull_request",
+			args: target(1, map[string]interface{}{"merge_method": "squash", "commit_title": "Squashed change"}),
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				var merged struct {
+					Merged bool   `json:"merged"`
+					SHA    string `json:"sha"`
+				}
+				decodeResult(t, result, &merged)
+				if !merged.Merged || merged.SHA != env.gh.BranchSHA(fakegithub.Login, "hello", "main") {
+					t.Errorf("unexpected merge result: %+v", merged)
+				}
+				if _, ok := env.gh.File(fakegithub.Login, "hello", "main", "clean.txt"); !ok {
+					t.Error("merged file is missing from main")
+				}
+				if pr := getPull(t, 1); pr.State != "closed" {
+					t.Errorf("state = %q, want closed", pr.State)
+				}
+			},
+		},
+		{
+			name:        "merge already merged",
+			tool:        "merge_pull_request",
+			args:        target(1, nil),
+			wantCode:    common.ErrorCodeValidation,
+			wantMessage: "already merged",
+		},
+		{
+			name:        "merge with conflicts",
+			tool:        "merge_pull_request",
+			args:        target(2, nil),
+			wantCode:    common.ErrorCodeConflict,
+			wantMessage: "conflicts",
+		},
+		{
+			name:        "merge behind base",
+			tool:        "merge_pull_request",
+			args:        target(3, nil),
+			wantCode:    common.ErrorCodeConflict,
+			wantMessage: "update_pull_request_branch",
+		},
+		{
+			name:     "update branch with stale head sha",
+			tool:     "update_pull_request_branch",
+			args:     target(3, map[string]interface{}{"expected_head_sha": "0000000000000000000000000000000000000000"}),
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name: "update branch",
+			tool: "update_pull_request_branch",
+			args: target(3, map[string]interface{}{"expected_head_sha": behindHead}),
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				var updated struct {
+					Message string `json:"message"`
+				}
+				decodeResult(t, result, &updated)
+				if updated.Message != "Updating pull request branch." {
+					t.Errorf("message = %q", updated.Message)
+				}
+				if _, ok := env.gh.File(fakegithub.Login, "hello", "behind", "clean.txt"); !ok {
+					t.Error("base changes were not merged into the head branch")
+				}
+			},
+		},
+		{
+			name: "merge after updating branch",
+			tool: "merge_pull_request",
+			args: target(3, map[string]interface{}{"merge_method": "merge"}),
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				if pr := getPull(t, 3); pr.State != "closed" {
+					t.Errorf("state = %q, want closed", pr.State)
+				}
+			},
+		},
+		{
+			name: "update title and convert to draft",
+			tool: "update_pull_request",
+			args: target(4, map[string]interface{}{"title": "Renamed", "draft": true}),
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				var pr pullRequest
+				decodeResult(t, result, &pr)
+				if pr.Title != "Renamed" || !pr.Draft {
+					t.Errorf("unexpected pull request: %+v", pr)
+				}
+				if pr := getPull(t, 4); !pr.Draft {
+					t.Error("pull request was not converted to draft")
+				}
+			},
+		},
+		{
+			name:        "merge draft",
+			tool:        "merge_pull_request",
+			args:        target(4, nil),
+			wantCode:    common.ErrorCodeValidation,
+			wantMessage: "draft",
+		},
+		{
+			name: "mark ready and close",
+			tool: "update_pull_request",
+			args: target(4, map[string]interface{}{"draft": false, "state": "closed"}),
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				if pr := getPull(t, 4); pr.Draft || pr.State != "closed" {
+					t.Errorf("unexpected pull request: %+v", pr)
+				}
+			},
+		},
+		{
+			name:     "update with unknown base",
+			tool:     "update_pull_request",
+			args:     target(6, map[string]interface{}{"base": "nope"}),
+			wantCode: common.ErrorCodeValidation,
+		},
+		{
+			name:     "update with invalid draft",
+			tool:     "update_pull_request",
+			args:     target(6, map[string]interface{}{"draft": "yes"}),
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+		{
+			name: "enable auto-merge",
+			tool: "enable_pull_request_auto_merge",
+			args: target(5, map[string]interface{}{"merge_method": "squash"}),
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				var got struct {
+					Enabled     bool   `json:"enabled"`
+					MergeMethod string `json:"merge_method"`
+				}
+				decodeResult(t, result, &got)
+				if !got.Enabled || got.MergeMethod != "squash" {
+					t.Errorf("unexpected result: %+v", got)
+				}
+				if pr := getPull(t, 5); pr.AutoMerge == nil || pr.AutoMerge.MergeMethod != "squash" {
+					t.Errorf("auto-merge was not enabled: %+v", pr.AutoMerge)
+				}
+			},
+		},
+		{
+			name: "disable auto-merge",
+			tool: "disable_pull_request_auto_merge",
+			args: target(5, nil),
+			check: func(t *testing.T, result *mcp.CallToolResult) {
+				if pr := getPull(t, 5); pr.AutoMerge != nil {
+					t.Errorf("auto-merge is still enabled: %+v", pr.AutoMerge)
+				}
+			},
+		},
+		{
+			name:        "enable auto-merge on a mergeable pull request",
+			tool:        "enable_pull_request_auto_merge",
+			args:        target(6, nil),
+			wantCode:    common.ErrorCodeValidation,
+			wantMessage: "clean status",
+		},
+		{
+			name:     "invalid merge method",
+			tool:     "merge_pull_request",
+			args:     target(6, map[string]interface{}{"merge_method": "fast-forward"}),
+			wantCode: common.ErrorCodeInvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := env.callTool(tt.tool, tt.args)
+			if tt.wantCode != "" {
+				body := expectToolError(t, result, tt.wantCode)
+				if !strings.Contains(body.Message, tt.wantMessage) {
+					t.Errorf("message %q does not contain %q", body.Message, tt.wantMessage)
+				}
+				return
+			}
+			tt.check(t, result)
+		})
+	}
+}
+
+func TestPullRequestChanges(t *testing.T) {
+	env := newTestEnv(t)
+	env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{
+		"lib.go":   "package lib\n\nfunc A() {}\n",
+		"old.txt":  "keep\n",
+		"gone.txt": "bye\n",
+	})
+	env.gh.CreateBranch(fakegithub.Login, "hello", "feature", "main")
+	env.gh.SetFiles(fakegithub.Login, "hello", "feature", map[string]string{
+		"lib.go":      "package lib\n\nfunc A() {}\n\nfunc B() {}\n",
+		"new.txt":     "hello\n",
+		"renamed.txt": "keep\n",
+	})
+	env.gh.DeleteFiles(fakegithub.Login, "hello", "feature", "old.txt", "gone.txt")
+	// ベースブランチだけの変更はPull Requestの差分に含まれない
+	env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"main-only.txt": "main\n"})
+	env.gh.CreatePull(fakegithub.Login, "hello", "feature", "main", "Add B")
+	pull := map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": 1}
+	withArgs := func(extra map[string]interface{}) map[string]interface{} {
+		args := map[string]interface{}{}
+		for k, v := range pull {
+			args[k] = v
+		}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return args
+	}
+
+	type diffResult struct {
+		Diff       string   `json:"diff"`
+		Files      []string `json:"files"`
+		TotalBytes int      `json:"total_bytes"`
+		Truncated  bool     `json:"truncated"`
+		NextCursor string   `json:"next_cursor"`
+	}
+	var full diffResult
+	decodeResult(t, env.callTool("get_pull_request_diff", pull), &full)
+
+	t.Run("files", func(t *testing.T) {
+		var got struct {
+			Items []struct {
+				Filename         string `json:"filename"`
+				Status           string `json:"status"`
+				Additions        int    `json:"additions"`
+				Deletions        int    `json:"deletions"`
+				Patch            string `json:"patch"`
+				PreviousFilename string `json:"previous_filename"`
+			} `json:"items"`
+			NextCursor string `json:"next_cursor"`
+		}
+		decodeResult(t, env.callTool("get_pull_request_files", pull), &got)
+		var summary []string
+		for _, f := range got.Items {
+			summary = append(summary, fmt.Sprintf("%s %s +%d -%d %s", f.Status, f.Filename, f.Additions, f.Deletions, f.PreviousFilename))
+		}
+		want := []string{
+			"removed gone.txt +0 -1 ",
+			"modified lib.go +2 -0 ",
+			"added new.txt +1 -0 ",
+			"renamed renamed.txt +0 -0 old.txt",
+		}
+		if !reflect.DeepEqual(summary, want) {
+			t.Fatalf("files = %q, want %q", summary, want)
+		}
+		if patch := got.Items[1].Patch; patch != "@@ -1,3 +1,5 @@\n package lib\n \n func A() {}\n+\n+func B() {}" {
+			t.Errorf("unexpected patch:\n%s", patch)
+		}
+		if got.NextCursor != "" {
+			t.Errorf("unexpected next_cursor %q", got.NextCursor)
+		}
+
+		got.Items = nil
+		decodeResult(t, env.callTool("get_pull_request_files", withArgs(map[string]interface{}{"per_page": 3})), &got)
+		if len(got.Items) != 3 || got.NextCursor == "" {
+			t.Errorf("expected 3 files and a cursor, got %d files and %q", len(got.Items), got.NextCursor)
+		}
+	})
+
+	t.Run("full diff", func(t *testing.T) {
+		if full.Truncated || full.NextCursor != "" || full.TotalBytes != len(full.Diff) {
+			t.Errorf("unexpected truncation: %+v", full)
+		}
+		if !reflect.DeepEqual(full.Files, []string{"gone.txt", "lib.go", "new.txt", "renamed.txt"}) {
+			t.Errorf("files = %v", full.Files)
+		}
+		for _, want := range []string{"diff --git a/lib.go b/lib.go\n", "+func B() {}\n", "rename from old.txt\n", "--- a/gone.txt\n+++ /dev/null\n"} {
+			if !strings.Contains(full.Diff, want) {
+				t.Errorf("diff does not contain %q:\n%s", want, full.Diff)
+			}
+		}
+		if strings.Contains(full.Diff, "main-only.txt") {
+			t.Error("diff contains changes only on the base branch")
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		var got diffResult
+		decodeResult(t, env.callTool("get_pull_request_diff", withArgs(map[string]interface{}{"files": []interface{}{"*.go", "old.txt"}})), &got)
+		if !reflect.DeepEqual(got.Files, []string{"lib.go", "renamed.txt"}) || !strings.HasPrefix(got.Diff, "diff --git a/lib.go b/lib.go\n") {
+			t.Errorf("unexpected filtered diff: %+v", got)
+		}
+	})
+
+	t.Run("continuation", func(t *testing.T) {
+		var joined strings.Builder
+		args := withArgs(map[string]interface{}{"max_bytes": 100})
+		for i := 0; ; i++ {
+			if i > len(full.Diff) {
+				t.Fatal("too many pages")
+			}
+			var got diffResult
+			decodeResult(t, env.callTool("get_pull_request_diff", args), &got)
+			if len(got.Diff) > 100 || (got.NextCursor != "" && !strings.HasSuffix(got.Diff, "\n")) {
+				t.Fatalf("unexpected chunk %q", got.Diff)
+			}
+			joined.WriteString(got.Diff)
+			if got.NextCursor == "" {
+				break
+			}
+			if !got.Truncated {
+				t.Error("truncated must be true when next_cursor is set")
+			}
+			args["cursor"] = got.NextCursor
+		}
+		if joined.String() != full.Diff {
+			t.Errorf("joined chunks do not match the full diff:\n%s", joined.String())
+		}
+	})
+
+	t.Run("stale cursor", func(t *testing.T) {
+		var got diffResult
+		decodeResult(t, env.callTool("get_pull_request_diff", withArgs(map[string]interface{}{"max_bytes": 100})), &got)
+		env.gh.SetFiles(fakegithub.Login, "hello", "feature", map[string]string{"new.txt": "changed\n"})
+		result := env.callTool("get_pull_request_diff", withArgs(map[string]interface{}{"max_bytes": 100, "cursor": got.NextCursor}))
+		expectToolError(t, result, common.ErrorCodeConflict)
+	})
+
+	for name, args := range map[string]map[string]interface{}{
+		"invalid cursor":    {"cursor": "not-a-cursor"},
+		"invalid pattern":   {"files": []interface{}{"["}},
+		"invalid max_bytes": {"max_bytes": 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			expectToolError(t, env.callTool("get_pull_request_diff", withArgs(args)), common.ErrorCodeInvalidArgument)
+		})
+	}
+}
+
+func TestPullRequestReviewComments(t *testing.T) {
+	env := newTestEnv(t)
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line%d", i))
+	}
+	env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"lib.go": strings.Join(lines, "\n") + "\n"})
+	env.gh.CreateBranch(fakegithub.Login, "hello", "feature", "main")
+	lines[4] = "LINE5"
+	env.gh.SetFiles(fakegithub.Login, "hello", "feature", map[string]string{"lib.go": strings.Join(append(lines, "line11"), "\n") + "\n"})
+	env.gh.CreatePull(fakegithub.Login, "hello", "feature", "main", "Rename")
+
+	withArgs := func(extra map[string]interface{}) map[string]interface{} {
+		args := map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": 1}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return args
+	}
+	type comment struct {
+		ID          int64  `json:"id"`
+		InReplyToID int64  `json:"in_reply_to_id"`
+		Body        string `json:"body"`
+		Path        string `json:"path"`
+		Line        int    `json:"line"`
+		Side        string `json:"side"`
+		StartLine   int    `json:"start_line"`
+		DiffHunk    string `json:"diff_hunk"`
+	}
+	listComments := func(t *testing.T, extra map[string]interface{}) []comment {
+		t.Helper()
+		var got struct {
+			Items []comment `json:"items"`
+		}
+		decodeResult(t, env.callTool("list_pull_request_review_comments", withArgs(extra)), &got)
+		return got.Items
+	}
+
+	var comments []comment
+	t.Run("review with line comments", func(t *testing.T) {
+		result := env.callTool("create_pull_request_review", withArgs(map[string]interface{}{
+			"event": "COMMENT",
+			"comments": []interface{}{
+				map[string]interface{}{"path": "lib.go", "line": 5, "body": "Why upper case?"},
+				map[string]interface{}{"path": "lib.go", "start_line": 4, "line": 6, "body": "Try this:", "suggestion": "line4\nline5\nline6"},
+				map[string]interface{}{"path": "lib.go", "line": 5, "side": "LEFT", "body": "The old name was fine"},
+			},
+		}))
+		var review struct {
+			State string `json:"state"`
+		}
+		decodeResult(t, result, &review)
+		if review.State != "COMMENTED" {
+			t.Errorf("state = %q, want COMMENTED", review.State)
+		}
+
+		comments = listComments(t, nil)
+		if len(comments) != 3 {
+			t.Fatalf("got %d comments, want 3", len(comments))
+		}
+		if c := comments[0]; c.Path != "lib.go" || c.Line != 5 || c.Side != "RIGHT" || !strings.HasSuffix(c.DiffHunk, "\n+LINE5") {
+			t.Errorf("unexpected comment: %+v", c)
+		}
+		if c := comments[1]; c.StartLine != 4 || c.Line != 6 || c.Body != "Try this:\n\n```suggestion\nline4\nline5\nline6\n```" {
+			t.Errorf("unexpected suggestion comment: %+v", c)
+		}
+		if c := comments[2]; c.Side != "LEFT" || !strings.HasSuffix(c.DiffHunk, "\n-line5") {
+			t.Errorf("unexpected comment on the base side: %+v", c)
+		}
+	})
+
+	t.Run("review is created atomically", func(t *testing.T) {
+		result := env.callTool("create_pull_request_review", withArgs(map[string]interface{}{
+			"event": "REQUEST_CHANGES",
+			"body":  "Please fix",
+			"comments": []interface{}{
+				map[string]interface{}{"path": "lib.go", "line": 5, "body": "ok"},
+				map[string]interface{}{"path": "lib.go", "line": 1, "body": "outside of the diff"},
+			},
+		}))
+		expectToolError(t, result, common.ErrorCodeValidation)
+		if got := len(env.gh.Reviews(fakegithub.Login, "hello", 1)); got != 1 {
+			t.Errorf("got %d reviews, want 1", got)
+		}
+		if got := len(listComments(t, nil)); got != 3 {
+			t.Errorf("got %d comments, want 3", got)
+		}
+	})
+
+	for name, c := range map[string]map[string]interface{}{
+		"comment without line":      {"path": "lib.go", "body": "x"},
+		"start line after line":     {"path": "lib.go", "start_line": 6, "line": 5, "body": "x"},
+		"suggestion on the base":    {"path": "lib.go", "line": 5, "side": "LEFT", "suggestion": "x"},
+		"comment without body":      {"path": "lib.go", "line": 5},
+		"comment with invalid side": {"path": "lib.go", "line": 5, "side": "BOTH", "body": "x"},
+		"fractional line":           {"path": "lib.go", "line": 5.5, "body": "x"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := env.callTool("create_pull_request_review", withArgs(map[string]interface{}{"event": "COMMENT", "comments": []interface{}{c}}))
+			expectToolError(t, result, common.ErrorCodeInvalidArgument)
+		})
+	}
+
+	var reply comment
+	t.Run("reply", func(t *testing.T) {
+		decodeResult(t, env.callTool("reply_to_pull_request_review_comment", withArgs(map[string]interface{}{
+			"comment_id": comments[0].ID,
+			"body":       "It is a constant",
+		})), &reply)
+		if reply.InReplyToID != comments[0].ID || reply.Path != "lib.go" || reply.Line != 5 {
+			t.Errorf("unexpected reply: %+v", reply)
+		}
+
+		// 返信への返信はスレッドの最初のコメントへの返信になる
+		var nested comment
+		decodeResult(t, env.callTool("reply_to_pull_request_review_comment", withArgs(map[string]interface{}{
+			"comment_id": reply.ID,
+			"body":       "Thanks",
+		})), &nested)
+		if nested.InReplyToID != comments[0].ID {
+			t.Errorf("in_reply_to_id = %d, want %d", nested.InReplyToID, comments[0].ID)
+		}
+	})
+
+	t.Run("list newest first", func(t *testing.T) {
+		got := listComments(t, map[string]interface{}{"direction": "desc", "per_page": 2})
+		if len(got) != 2 || got[0].Body != "Thanks" || got[1].ID != reply.ID {
+			t.Errorf("unexpected comments: %+v", got)
+		}
+	})
+
+	type thread struct {
+		ThreadID   string `json:"thread_id"`
+		IsResolved bool   `json:"is_resolved"`
+	}
+	for _, step := range []struct {
+		tool      string
+		commentID int64
+		want      bool
+	}{
+		{"resolve_pull_request_review_thread", reply.ID, true},
+		{"resolve_pull_request_review_thread", comments[0].ID, true},
+		{"unresolve_pull_request_review_thread", comments[0].ID, false},
+	} {
+		t.Run(step.tool, func(t *testing.T) {
+			var got thread
+			decodeResult(t, env.callTool(step.tool, withArgs(map[string]interface{}{"comment_id": step.commentID})), &got)
+			if want := fmt.Sprintf("PRRT_%d", comments[0].ID); got.ThreadID != want || got.IsResolved != step.want {
+				t.Errorf("thread = %+v, want %s resolved=%v", got, want, step.want)
+			}
+		})
+	}
+
+	t.Run("unknown comment", func(t *testing.T) {
+		expectToolError(t, env.callTool("resolve_pull_request_review_thread", withArgs(map[string]interface{}{"comment_id": 1})), common.ErrorCodeNotFound)
+		expectToolError(t, env.callTool("reply_to_pull_request_review_comment", withArgs(map[string]interface{}{"comment_id": 1, "body": "x"})), common.ErrorCodeNotFound)
+	})
+}
+
+func TestIssues(t *testing.T) {
+	env := newTestEnv(t)
+	milestone := env.gh.CreateMilestone(fakegithub.Login, "hello", "v1.0")
+	env.gh.CreateIssue(fakegithub.Login, "hello", "Old issue")
+
+	result := env.callTool("create_issue", map[string]interface{}{
+		"owner":     "octocat",
+		"repo":      "hello",
+		"title":     "Crash on start",
+		"body":      "stack trace",
+		"labels":    []interface{}{"bug", "p1"},
+		"assignees": []interface{}{"octocat"},
+		"milestone": milestone,
+	})
+	var created struct {
+		Number    int      `json:"number"`
+		State     string   `json:"state"`
+		Labels    []string `json:"labels"`
+		Milestone string   `json:"milestone"`
+		Assignees []struct {
+			Login string `json:"login"`
+		} `json:"assignees"`
+		User struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	decodeResult(t, result, &created)
+	if created.Number != 2 || created.State != "open" || !reflect.DeepEqual(created.Labels, []string{"bug", "p1"}) ||
+		created.Milestone != "v1.0" || len(created.Assignees) != 1 || created.User.Login != fakegithub.Login {
+		t.Fatalf("unexpected issue: %+v", created)
+	}
+
+	// listNumbers はlist_issuesの結果のIssue番号を返します
+	listNumbers := func(t *testing.T, args map[string]interface{}) []int {
+		t.Helper()
+		args["owner"], args["repo"] = "octocat", "hello"
+		var got struct {
+			Items []struct {
+				Number int `json:"number"`
+			} `json:"items"`
+		}
+		decodeResult(t, env.callTool("list_issues", args), &got)
+		numbers := []int{}
+		for _, item := range got.Items {
+			numbers = append(numbers, item.Number)
+		}
+		return numbers
+	}
+
+	t.Run("get issue", func(t *testing.T) {
+		var got struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		decodeResult(t, env.callTool("get_issue", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 2}), &got)
+		if got.Title != "Crash on start" || got.Body != "stack trace" {
+			t.Errorf("unexpected issue: %+v", got)
+		}
+		expectToolError(t, env.callTool("get_issue", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 99}), common.ErrorCodeNotFound)
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		if got := listNumbers(t, map[string]interface{}{}); !reflect.DeepEqual(got, []int{2, 1}) {
+			t.Errorf("open issues = %v, want [2 1]", got)
+		}
+		if got := listNumbers(t, map[string]interface{}{"labels": []interface{}{"bug"}}); !reflect.DeepEqual(got, []int{2}) {
+			t.Errorf("labeled issues = %v, want [2]", got)
+		}
+		if got := listNumbers(t, map[string]interface{}{"assignee": "none"}); !reflect.DeepEqual(got, []int{1}) {
+			t.Errorf("unassigned issues = %v, want [1]", got)
+		}
+		if got := listNumbers(t, map[string]interface{}{"direction": "asc", "per_page": 1}); !reflect.DeepEqual(got, []int{1}) {
+			t.Errorf("first page = %v, want [1]", got)
+		}
+		if got := listNumbers(t, map[string]interface{}{"since": "2030-01-01T00:00:00Z"}); len(got) != 0 {
+			t.Errorf("issues updated since 2030 = %v, want none", got)
+		}
+		expectToolError(t, env.callTool("list_issues", map[string]interface{}{"owner": "octocat", "repo": "hello", "since": "yesterday"}), common.ErrorCodeInvalidArgument)
+		expectToolError(t, env.callTool("list_issues", map[string]interface{}{"owner": "octocat", "repo": "hello", "state": "merged"}), common.ErrorCodeInvalidArgument)
+	})
+
+	t.Run("update issue", func(t *testing.T) {
+		var got struct {
+			State       string   `json:"state"`
+			StateReason string   `json:"state_reason"`
+			Title       string   `json:"title"`
+			Body        string   `json:"body"`
+			Labels      []string `json:"labels"`
+			Milestone   string   `json:"milestone"`
+			ClosedAt    string   `json:"closed_at"`
+		}
+		decodeResult(t, env.callTool("update_issue", map[string]interface{}{
+			"owner":        "octocat",
+			"repo":         "hello",
+			"issue_number": 2,
+			"state":        "closed",
+			"state_reason": "not_planned",
+			"labels":       []interface{}{},
+		}), &got)
+		if got.State != "closed" || got.StateReason != "not_planned" || got.Title != "Crash on start" ||
+			got.Body != "stack trace" || len(got.Labels) != 0 || got.Milestone != "v1.0" {
+			t.Errorf("unexpected issue: %+v", got)
+		}
+		if got := listNumbers(t, map[string]interface{}{"state": "closed"}); !reflect.DeepEqual(got, []int{2}) {
+			t.Errorf("closed issues = %v, want [2]", got)
+		}
+
+		// milestone に 0 を指定するとマイルストーンを外す
+		env.gh.ResetRequests()
+		got.Milestone = ""
+		decodeResult(t, env.callTool("update_issue", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 2, "milestone": 0}), &got)
+		if got.Milestone != "" || got.State != "closed" {
+			t.Errorf("unexpected issue: %+v", got)
+		}
+		if want := []string{"PATCH /repos/octocat/hello/issues/2"}; !reflect.DeepEqual(env.gh.Requests(), want) {
+			t.Errorf("requests = %v, want %v", env.gh.Requests(), want)
+		}
+
+		expectToolError(t, env.callTool("update_issue", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 2, "milestone": 42}), common.ErrorCodeValidation)
+		expectToolError(t, env.callTool("update_issue", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 2, "labels": "bug"}), common.ErrorCodeInvalidArgument)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		for _, body := range []string{"first", "second", "third"} {
+			result := env.callTool("add_issue_comment", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 1, "body": body})
+			if result.IsError {
+				t.Fatalf("tool returned error: %s", resultText(result))
+			}
+		}
+		expectToolError(t, env.callTool("add_issue_comment", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 1, "body": ""}), common.ErrorCodeValidation)
+
+		var got struct {
+			Items []struct {
+				Body string `json:"body"`
+				User struct {
+					Login string `json:"login"`
+				} `json:"user"`
+			} `json:"items"`
+			NextCursor string `json:"next_cursor"`
+		}
+		decodeResult(t, env.callTool("list_issue_comments", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 1, "per_page": 2}), &got)
+		if len(got.Items) != 2 || got.Items[0].Body != "first" || got.Items[0].User.Login != fakegithub.Login || got.NextCursor == "" {
+			t.Fatalf("first page = %+v", got)
+		}
+		cursor := got.NextCursor
+		got.NextCursor = ""
+		decodeResult(t, env.callTool("list_issue_comments", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 1, "cursor": cursor}), &got)
+		if len(got.Items) != 1 || got.Items[0].Body != "third" || got.NextCursor != "" {
+			t.Errorf("second page = %+v", got)
+		}
+
+		var issue struct {
+			Comments int `json:"comments"`
+		}
+		decodeResult(t, env.callTool("get_issue", map[string]interface{}{"owner": "octocat", "repo": "hello", "issue_number": 1}), &issue)
+		if issue.Comments != 3 {
+			t.Errorf("comments = %d, want 3", issue.Comments)
+		}
+	})
+
+	t.Run("search includes issues", func(t *testing.T) {
+		var got struct {
+			Items []struct {
+				Number        int  `json:"number"`
+				IsPullRequest bool `json:"is_pull_request"`
+			} `json:"items"`
+		}
+		decodeResult(t, env.callTool("search_issues", map[string]interface{}{"query": "crash is:issue is:closed"}), &got)
+		if len(got.Items) != 1 || got.Items[0].Number != 2 || got.Items[0].IsPullRequest {
+			t.Errorf("items = %+v", got.Items)
+		}
+	})
+}
+
+func TestErrorMapping(t *testing.T) {
+	const path = "/repos/octocat/hello/pulls/1"
+
+	tests := []struct {
+		name        string
+		fault       fakegithub.Fault
+		wantCode    string
+		wantMessage string
+	}{
+		{
+			name:        "unauthorized",
+			fault:       fakegithub.Fault{Status: http.StatusUnauthorized, Message: "Bad credentials"},
+			wantCode:    common.ErrorCodeAuthentication,
+			wantMessage: "Authentication Failed: GET " + path + ": Bad credentials",
+		},
+		{
+			name:        "forbidden",
+			fault:       fakegithub.Fault{Status: http.StatusForbidden, Message: "Resource not accessible by integration"},
+			wantCode:    common.ErrorCodePermission,
+			wantMessage: "Permission Denied",
+		},
+		{
+			name: "primary rate limit",
+			fault: fakegithub.Fault{
+				Status:  http.StatusForbidden,
+				Message: "API rate limit exceeded",
+				Header: http.Header{
+					"X-Ratelimit-Limit":     {"5000"},
+					"X-Ratelimit-Remaining": {"0"},
+					"X-Ratelimit-Reset":     {"1893456000"},
+				},
+			},
+			wantCode:    common.ErrorCodeRateLimit,
+			wantMessage: "Resets at: 2030-01-01T00:00:00Z",
+		},
+		{
+			name: "secondary rate limit",
+			fault: fakegithub.Fault{
+				Status: http.StatusForbidden,
+				Body: map[string]string{
+					"message":           "You have exceeded a secondary rate limit",
+					"documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits",
+				},
+				Header: http.Header{"Retry-After": {"60"}},
+			},
+			wantCode:    common.ErrorCodeRateLimit,
+			wantMessage: "Rate Limit Exceeded",
+		},
+		{
+			name:        "too many requests",
+			fault:       fakegithub.Fault{Status: http.StatusTooManyRequests, Message: "slow down"},
+			wantCode:    common.ErrorCodeRateLimit,
+			wantMessage: "Rate Limit Exceeded",
+		},
+		{
+			name:        "not found",
+			fault:       fakegithub.Fault{Status: http.StatusNotFound, Message: "Not Found"},
+			wantCode:    common.ErrorCodeNotFound,
+			wantMessage: "Not Found: GET " + path,
+		},
+		{
+			name:        "conflict",
+			fault:       fakegithub.Fault{Status: http.StatusConflict, Message: "sha mismatch"},
+			wantCode:    common.ErrorCodeConflict,
+			wantMessage: "Conflict: GET " + path + ": sha mismatch",
+		},
+		{
+			name: "validation with details",
+			fault: fakegithub.Fault{
+				Status: http.StatusUnprocessableEntity,
+				Body: map[string]interface{}{
+					"message": "Validation Failed",
+					"errors":  []map[string]string{{"resource": "PullRequest", "field": "base", "code": "invalid"}},
+				},
+			},
+			wantCode:    common.ErrorCodeValidation,
+			wantMessage: "Details:",
+		},
+		{
+			name:        "server error",
+			fault:       fakegithub.Fault{Status: http.StatusInternalServerError, Message: "boom"},
+			wantCode:    common.ErrorCodeGitHub,
+			wantMessage: "GitHub API Error: GET " + path + ": boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			fault := tt.fault
+			fault.Method = http.MethodGet
+			fault.Path = path
+			env.gh.AddFault(fault)
+
+			result := env.callTool("get_pull_request", map[string]interface{}{
+				"owner":       "octocat",
+				"repo":        "hello",
+				"pull_number": 1,
+			})
+			body := expectToolError(t, result, tt.wantCode)
+			if !strings.Contains(body.Message, tt.wantMessage) {
+				t.Errorf("message %q does not contain %q", body.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestMissingToken(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, env.gh.HTTPClient())
+
+	for _, tool := range env.listTools() {
+		t.Run(tool, func(t *testing.T) {
+			result := env.callToolWithContext(ctx, tool, map[string]interface{}{})
+			expectToolError(t, result, common.ErrorCodeAuthentication)
+		})
+	}
+}
+
+func TestGitHubEnterpriseHost(t *testing.T) {
+	serverHost, err := common.ParseGitHubHost("ghe.example.com", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     ServerOptions
+		header   string
+		wantURL  string
+		wantHTML string
+	}{
+		{
+			name:     "default host",
+			wantURL:  "https://api.github.com/repos/octocat/hello/pulls/1",
+			wantHTML: "https://github.com/",
+		},
+		{
+			name:     "server option",
+			opts:     ServerOptions{GitHubHost: serverHost},
+			wantURL:  "https://ghe.example.com/api/v3/repos/octocat/hello/pulls/1",
+			wantHTML: "https://ghe.example.com/",
+		},
+		{
+			name:     "request header overrides server option",
+			opts:     ServerOptions{GitHubHost: serverHost, AllowedGitHubHosts: common.HostAllowlist{"other.example.com"}},
+			header:   "https://other.example.com/api/v3",
+			wantURL:  "https://other.example.com/api/v3/repos/octocat/hello/pulls/1",
+			wantHTML: "https://other.example.com/",
+		},
+		{
+			name:     "request header for a host outside the allowlist is ignored",
+			opts:     ServerOptions{GitHubHost: serverHost, AllowedGitHubHosts: common.HostAllowlist{"other.example.com"}},
+			header:   "https://attacker.example.com/api/v3",
+			wantURL:  "https://ghe.example.com/api/v3/repos/octocat/hello/pulls/1",
+			wantHTML: "https://ghe.example.com/",
+		},
+		{
+			name:     "plain http header is ignored",
+			opts:     ServerOptions{GitHubHost: serverHost, AllowedGitHubHosts: common.HostAllowlist{"other.example.com"}},
+			header:   "http://other.example.com/api/v3",
+			wantURL:  "https://ghe.example.com/api/v3/repos/octocat/hello/pulls/1",
+			wantHTML: "https://ghe.example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnvWithOptions(t, tt.opts)
+			env.gh.CreateBranch(fakegithub.Login, "hello", "feature", "main")
+			env.gh.SetFiles(fakegithub.Login, "hello", "feature", map[string]string{"f.txt": "f"})
+			env.gh.CreatePull(fakegithub.Login, "hello", "feature", "main", "PR")
+			env.gh.ResetRequests()
+
+			ctx := env.ctx
+			if tt.header != "" {
+				r := httptest.NewRequest(http.MethodPost, "/message", nil)
+				r.Header.Set("Authorization", "test-token")
+				r.Header.Set(common.GitHubHostHeader, tt.header)
+				ctx = env.server.requestContext(ctx, r)
+			}
+
+			result := env.callToolWithContext(ctx, "get_pull_request", map[string]interface{}{
+				"owner":       "octocat",
+				"repo":        "hello",
+				"pull_number": 1,
+			})
+			var pr struct {
+				HTMLURL string `json:"html_url"`
+			}
+			decodeResult(t, result, &pr)
+			if urls := env.gh.RequestURLs(); len(urls) != 1 || urls[0] != tt.wantURL {
+				t.Errorf("request urls = %v, want [%s]", urls, tt.wantURL)
+			}
+			if !strings.HasPrefix(pr.HTMLURL, tt.wantHTML) {
+				t.Errorf("html_url = %s, want prefix %s", pr.HTMLURL, tt.wantHTML)
+			}
+		})
+	}
+
+	t.Run("error message includes host", func(t *testing.T) {
+		env := newTestEnvWithOptions(t, ServerOptions{GitHubHost: serverHost})
+		result := env.callTool("get_pull_request", map[string]interface{}{
+			"owner":       "octocat",
+			"repo":        "hello",
+			"pull_number": 99,
+		})
+		body := expectToolError(t, result, common.ErrorCodeNotFound)
+		if !strings.Contains(body.Message, "ghe.example.com/api/v3/repos/octocat/hello/pulls/99") {
+			t.Errorf("message %q does not mention the host", body.Message)
+		}
+	})
+}
+
+func TestParseToolTimeouts(t *testing.T) {
+	got, err := parseToolTimeouts("push_files=5m, get_file_contents=30s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := fmt.Sprint(map[string]string{"push_files": "5m0s", "get_file_contents": "30s"})
+	gotStrings := map[string]string{}
+	for k, v := range got {
+		gotStrings[k] = v.String()
+	}
+	if fmt.Sprint(gotStrings) != want {
+		t.Errorf("timeouts = %v, want %v", gotStrings, want)
+	}
+
+	if _, err := parseToolTimeouts("push_files"); err == nil {
+		t.Errorf("expected error for missing duration")
+	}
+}
+
+func TestPushFilesCanceled(t *testing.T) {
+	env := newTestEnv(t)
+	headBefore := env.gh.BranchSHA(fakegithub.Login, "hello", "main")
+
+	// コミットの作成中にクライアントがキャンセルした場合、ブランチは更新されない
+	ctx, cancel := context.WithCancel(env.ctx)
+	defer cancel()
+	env.gh.BeforeRequest = func(r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/git/commits") {
+			cancel()
+		}
+	}
+	env.gh.ResetRequests()
+
+	result := env.callToolWithContext(ctx, "push_files", map[string]interface{}{
+		"owner":   "octocat",
+		"repo":    "hello",
+		"branch":  "main",
+		"message": "push",
+		"files": []interface{}{
+			map[string]interface{}{"path": "README.md", "content": "canceled"},
+		},
+	})
+	expectToolError(t, result, common.ErrorCodeCanceled)
+
+	for _, req := range env.gh.Requests() {
+		if strings.HasPrefix(req, "PATCH ") {
+			t.Errorf("ref should not be updated after cancellation: %s", req)
+		}
+	}
+	if head := env.gh.BranchSHA(fakegithub.Login, "hello", "main"); head != headBefore {
+		t.Errorf("branch head = %s, want %s", head, headBefore)
+	}
+}
+
+func TestPerToolTimeout(t *testing.T) {
+	env := newTestEnvWithOptions(t, ServerOptions{
+		ToolTimeout:  time.Minute,
+		ToolTimeouts: map[string]time.Duration{"get_file_contents": 20 * time.Millisecond},
+	})
+	env.gh.BeforeRequest = func(r *http.Request) {
+		if strings.Contains(r.URL.Path, "/contents/") {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+
+	result := env.callTool("get_file_contents", map[string]interface{}{
+		"owner": "octocat",
+		"repo":  "hello",
+		"path":  "README.md",
+	})
+	expectToolError(t, result, common.ErrorCodeTimeout)
+
+	// ツールごとのタイムアウトは他のツールに影響しない
+	result = env.callTool("get_pull_request", map[string]interface{}{
+		"owner":       "octocat",
+		"repo":        "hello",
+		"pull_number": 999,
+	})
+	expectToolError(t, result, common.ErrorCodeNotFound)
+}
+
+// readOnlySearchTools は repos ツールセットの読み取り専用ツールです
+var readOnlySearchTools = []string{"search_code", "search_commits", "search_issues", "search_repositories", "search_users"}
+
+func TestToolSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ServerOptions
+		want []string
+	}{
+		{
+			name: "read only",
+			opts: ServerOptions{ReadOnly: true},
+			want: append([]string{"get_file_contents", "get_issue", "get_pull_request", "get_pull_request_diff", "get_pull_request_files", "list_issue_comments", "list_issues", "list_pull_request_review_comments", "list_pull_requests"}, readOnlySearchTools...),
+		},
+		{
+			name: "toolsets",
+			opts: ServerOptions{Toolsets: []string{ToolsetFiles, ToolsetPulls}},
+			want: []string{
+				"create_or_update_file",
+				"create_pull_request",
+				"create_pull_request_review",
+				"disable_pull_request_auto_merge",
+				"enable_pull_request_auto_merge",
+				"get_file_contents",
+				"get_pull_request",
+				"get_pull_request_diff",
+				"get_pull_request_files",
+				"list_pull_request_review_comments",
+				"list_pull_requests",
+				"merge_pull_request",
+				"push_files",
+				"reply_to_pull_request_review_comment",
+				"resolve_pull_request_review_thread",
+				"unresolve_pull_request_review_thread",
+				"update_pull_request",
+				"update_pull_request_branch",
+			},
+		},
+		{
+			name: "read only issues",
+			opts: ServerOptions{ReadOnly: true, Toolsets: []string{ToolsetIssues}},
+			want: []string{"get_issue", "list_issue_comments", "list_issues"},
+		},
+		{
+			name: "read only toolset",
+			opts: ServerOptions{ReadOnly: true, Toolsets: []string{ToolsetRepos}},
+			want: readOnlySearchTools,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnvWithOptions(t, tt.opts)
+			if got := env.listTools(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tools = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("disabled tool cannot be called", func(t *testing.T) {
+		env := newTestEnvWithOptions(t, ServerOptions{ReadOnly: true})
+		message, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": mcp.JSONRPC_VERSION,
+			"id":      1,
+			"method":  "tools/call",
+			"params":  map[string]interface{}{"name": "create_repository", "arguments": map[string]interface{}{"name": "x"}},
+		})
+		response := env.server.server.HandleMessage(env.ctx, message)
+		if _, ok := response.(mcp.JSONRPCError); !ok {
+			t.Fatalf("expected JSON-RPC error, got %#v", response)
+		}
+		if len(env.gh.Requests()) != 0 {
+			t.Errorf("unexpected GitHub requests: %v", env.gh.Requests())
+		}
+	})
+}
+
+// sseListTools はSSEで接続し、セッションのツール一覧を取得します
+func sseListTools(t *testing.T, baseURL string, header http.Header) (int, []string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/sse", nil)
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	var endpoint string
+	reader := bufio.NewReader(resp.Body)
+	for endpoint == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read endpoint event: %v", err)
+		}
+		if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+			endpoint = data
+		}
+	}
+
+	// メッセージの送信にも接続時と同じヘッダー (認証情報など) を付ける
+	msgReq, _ := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{}}`))
+	msgReq.Header = header.Clone()
+	msgReq.Header.Set("Content-Type", "application/json")
+	msgResp, err := http.DefaultClient.Do(msgReq)
+	if err != nil {
+		t.Fatalf("failed to post message: %v", err)
+	}
+	msgResp.Body.Close()
+
+	// レスポンスはSSEストリームで返される
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read message event: %v", err)
+		}
+		if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+			return resp.StatusCode, toolNames(t, strings.NewReader(data))
+		}
+	}
+}
+
+// httpListTools はStreamable HTTPでセッションを初期化し、tools/listの結果のツール名を返します
+func httpListTools(t *testing.T, baseURL string, header http.Header) (int, []string) {
+	t.Helper()
+	post := func(body, sessionID string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, baseURL+httpEndpoint, strings.NewReader(body))
+		req.Header = header.Clone()
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if sessionID != "" {
+			req.Header.Set(server.HeaderKeySessionID, sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to post message: %v", err)
+		}
+		return resp
+	}
+
+	initResp := post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`, "")
+	initResp.Body.Close()
+	if initResp.StatusCode != http.StatusOK {
+		return initResp.StatusCode, nil
+	}
+	sessionID := initResp.Header.Get(server.HeaderKeySessionID)
+	if sessionID == "" {
+		t.Fatalf("missing %s header", server.HeaderKeySessionID)
+	}
+
+	resp := post(`{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{}}`, sessionID)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, toolNames(t, resp.Body)
+}
+
+// toolNames はtools/listのレスポンスからソート済みのツール名を取り出します
+func toolNames(t *testing.T, r io.Reader) []string {
+	t.Helper()
+	var envelope struct {
+		Result mcp.ListToolsResult `json:"result"`
+	}
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	var names []string
+	for _, tool := range envelope.Result.Tools {
+		names = append(names, tool.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestToolSelectionHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       ServerOptions
+		header     map[string]string
+		wantStatus int
+		want       []string
+	}{
+		{
+			name:       "read only header",
+			header:     map[string]string{ReadOnlyHeader: "true"},
+			wantStatus: http.StatusOK,
+			want:       append([]string{"get_file_contents", "get_issue", "get_pull_request", "get_pull_request_diff", "get_pull_request_files", "list_issue_comments", "list_issues", "list_pull_request_review_comments", "list_pull_requests"}, readOnlySearchTools...),
+		},
+		{
+			name:       "toolsets header",
+			header:     map[string]string{ToolsetsHeader: "pulls"},
+			wantStatus: http.StatusOK,
+			want: []string{
+				"create_pull_request",
+				"create_pull_request_review",
+				"disable_pull_request_auto_merge",
+				"enable_pull_request_auto_merge",
+				"get_pull_request",
+				"get_pull_request_diff",
+				"get_pull_request_files",
+				"list_pull_request_review_comments",
+				"list_pull_requests",
+				"merge_pull_request",
+				"reply_to_pull_request_review_comment",
+				"resolve_pull_request_review_thread",
+				"unresolve_pull_request_review_thread",
+				"update_pull_request",
+				"update_pull_request_branch",
+			},
+		},
+		{
+			name:       "header cannot widen server options",
+			opts:       ServerOptions{ReadOnly: true, Toolsets: []string{ToolsetRepos}},
+			header:     map[string]string{ReadOnlyHeader: "false", ToolsetsHeader: "repos,pulls"},
+			wantStatus: http.StatusOK,
+			want:       readOnlySearchTools,
+		},
+		{
+			name:       "invalid toolset",
+			header:     map[string]string{ToolsetsHeader: "wiki"},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		for k, v := range tt.header {
+			header.Set(k, v)
+		}
+		check := func(t *testing.T, status int, got []string) {
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tools = %v, want %v", got, tt.want)
+			}
+		}
+
+		t.Run(tt.name+"/sse", func(t *testing.T) {
+			ts := httptest.NewUnstartedServer(nil)
+			ts.Config.Handler = newSSERouter(NewGitHubMCPServer(tt.opts), "http://"+ts.Listener.Addr().String())
+			ts.Start()
+			defer ts.Close()
+
+			status, got := sseListTools(t, ts.URL, header)
+			check(t, status, got)
+		})
+		t.Run(tt.name+"/http", func(t *testing.T) {
+			ts := httptest.NewServer(NewGitHubMCPServer(tt.opts).ServeStreamableHTTP())
+			defer ts.Close()
+
+			status, got := httpListTools(t, ts.URL, header)
+			check(t, status, got)
+		})
+	}
+}
+
+func TestParseToolsets(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "all", want: nil},
+		{value: "pulls, Repos,pulls", want: []string{"pulls", "repos"}},
+		{value: "issues", want: []string{"issues"}},
+		{value: "repos,unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseToolsets(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseToolsets(%q): expected error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseToolsets(%q): unexpected error: %v", tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseToolsets(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRepositoryPolicy(t *testing.T) {
+	policy, err := common.ParsePolicy([]byte(`
+readable: [octocat]
+writable: [octocat/hello, octocat/new-*]
+forbidden: [octocat/secret]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env := newTestEnvWithOptions(t, ServerOptions{Policy: policy})
+	env.gh.CreateRepo(fakegithub.Login, "secret")
+	env.gh.CreateRepo(fakegithub.Login, "docs")
+	env.gh.CreateRepo("someone", "hello")
+
+	tests := []struct {
+		name         string
+		tool         string
+		args         map[string]interface{}
+		wantCode     string
+		wantRequests []string
+	}{
+		{
+			name:         "read allowed",
+			tool:         "get_file_contents",
+			args:         map[string]interface{}{"owner": "octocat", "repo": "docs", "path": "README.md"},
+			wantRequests: []string{"GET /repos/octocat/docs/contents/README.md"},
+		},
+		{
+			name:     "read forbidden",
+			tool:     "get_file_contents",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "secret", "path": "README.md"},
+			wantCode: common.ErrorCodePermission,
+		},
+		{
+			name:     "write to read only repository",
+			tool:     "create_or_update_file",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "docs", "path": "a.txt", "content": "a", "message": "add", "branch": "main"},
+			wantCode: common.ErrorCodePermission,
+		},
+		{
+			name:         "list issues on read only repository",
+			tool:         "list_issues",
+			args:         map[string]interface{}{"owner": "octocat", "repo": "docs"},
+			wantRequests: []string{"GET /repos/octocat/docs/issues"},
+		},
+		{
+			name:     "create issue on read only repository",
+			tool:     "create_issue",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "docs", "title": "typo"},
+			wantCode: common.ErrorCodePermission,
+		},
+		{
+			name:     "resolve review thread on read only repository",
+			tool:     "resolve_pull_request_review_thread",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "docs", "pull_number": 1, "comment_id": 1},
+			wantCode: common.ErrorCodePermission,
+		},
+		{
+			name:     "merge pull request on read only repository",
+			tool:     "merge_pull_request",
+			args:     map[string]interface{}{"owner": "octocat", "repo": "docs", "pull_number": 1},
+			wantCode: common.ErrorCodePermission,
+		},
+		{
+			name:     "unlisted owner",
+			tool:     "get_pull_request",
+			args:     map[string]interface{}{"owner": "someone", "repo": "hello", "pull_number": 1},
+			wantCode: common.ErrorCodePermission,
+		},
+		{
+			name:         "create repository resolves owner",
+			tool:         "create_repository",
+			args:         map[string]interface{}{"name": "new-repo"},
+			wantRequests: []string{"GET /user", "POST /user/repos"},
+		},
+		{
+			name:     "create repository in organization outside writable",
+			tool:     "create_repository",
+			args:     map[string]interface{}{"name": "new-repo", "organization": "someone"},
+			wantCode: common.ErrorCodePermission,
+		},
+		{
+			name:         "create repository outside writable",
+			tool:         "create_repository",
+			args:         map[string]interface{}{"name": "other"},
+			wantCode:     common.ErrorCodePermission,
+			wantRequests: []string{"GET /user"},
+		},
+		{
+			name:         "fork into own account is not writable",
+			tool:         "fork_repository",
+			args:         map[string]interface{}{"owner": "octocat", "repo": "docs"},
+			wantCode:     common.ErrorCodePermission,
+			wantRequests: []string{"GET /user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.gh.ResetRequests()
+			result := env.callTool(tt.tool, tt.args)
+			if tt.wantCode != "" {
+				body := expectToolError(t, result, tt.wantCode)
+				if !strings.Contains(body.Message, "ポリシー") {
+					t.Errorf("message %q does not mention the policy", body.Message)
+				}
+			} else if result.IsError {
+				t.Fatalf("tool returned error: %s", resultText(result))
+			}
+			if got := env.gh.Requests(); !reflect.DeepEqual(got, tt.wantRequests) {
+				t.Errorf("requests = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+
+	t.Run("search results are filtered", func(t *testing.T) {
+		result := env.callTool("search_repositories", map[string]interface{}{"query": "e"})
+		var got struct {
+			Items []struct {
+				FullName string `json:"full_name"`
+			} `json:"items"`
+		}
+		decodeResult(t, result, &got)
+		var names []string
+		for _, item := range got.Items {
+			names = append(names, item.FullName)
+		}
+		sort.Strings(names)
+		want := []string{"octocat/hello", "octocat/new-repo"}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("items = %v, want %v", names, want)
+		}
+	})
+
+	t.Run("code search results are filtered", func(t *testing.T) {
+		result := env.callTool("search_code", map[string]interface{}{"query": "# extension:md"})
+		var got struct {
+			Items []struct {
+				Repository string `json:"repository"`
+			} `json:"items"`
+		}
+		decodeResult(t, result, &got)
+		var names []string
+		for _, item := range got.Items {
+			names = append(names, item.Repository)
+		}
+		sort.Strings(names)
+		want := []string{"octocat/docs", "octocat/hello"}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("repositories = %v, want %v", names, want)
+		}
+	})
+}
+
+// readResource はリソースを読み取り、結果またはJSON-RPCのエラーメッセージを返します
+func (e *testEnv) readResource(uri string) (*mcp.ReadResourceResult, string) {
+	e.t.Helper()
+	e.nextID++
+	message, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      e.nextID,
+		"method":  "resources/read",
+		"params":  map[string]interface{}{"uri": uri},
+	})
+	switch response := e.server.server.HandleMessage(e.ctx, message).(type) {
+	case mcp.JSONRPCError:
+		return nil, response.Error.Message
+	case mcp.JSONRPCResponse:
+		raw, _ := json.Marshal(response.Result)
+		var result struct {
+			Contents []mcp.TextResourceContents `json:"contents"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			e.t.Fatalf("failed to unmarshal resource: %v", err)
+		}
+		contents := make([]mcp.ResourceContents, 0, len(result.Contents))
+		for _, c := range result.Contents {
+			contents = append(contents, c)
+		}
+		return &mcp.ReadResourceResult{Contents: contents}, ""
+	default:
+		e.t.Fatalf("unexpected response %#v", response)
+		return nil, ""
+	}
+}
+
+func TestResources(t *testing.T) {
+	policy, err := common.ParsePolicy([]byte(`readable: [octocat]
+forbidden: [octocat/secret]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env := newTestEnvWithOptions(t, ServerOptions{
+		Policy: policy,
+		ResourceRepositories: []ResourceRepository{
+			{Owner: "octocat", Repo: "hello"},
+			{Owner: "octocat", Repo: "secret"},
+		},
+	})
+	env.gh.CreateRepo(fakegithub.Login, "secret")
+	env.gh.CreateBranch(fakegithub.Login, "hello", "dev", "main")
+	env.gh.SetFiles(fakegithub.Login, "hello", "dev", map[string]string{"src/a b.json": "{}\n"})
+	env.gh.CreatePull(fakegithub.Login, "hello", "dev", "main", "Add a")
+
+	t.Run("list", func(t *testing.T) {
+		var templates mcp.ListResourceTemplatesResult
+		if err := json.Unmarshal(env.request(env.ctx, "resources/templates/list", map[string]interface{}{}), &templates); err != nil {
+			t.Fatalf("failed to unmarshal templates: %v", err)
+		}
+		var raws []string
+		for _, tpl := range templates.ResourceTemplates {
+			raws = append(raws, tpl.URITemplate.Raw())
+		}
+		sort.Strings(raws)
+		wantTemplates := []string{contentsResourceTemplate, pullResourceTemplate, treeResourceTemplate}
+		if !reflect.DeepEqual(raws, wantTemplates) {
+			t.Errorf("templates = %v, want %v", raws, wantTemplates)
+		}
+
+		var resources mcp.ListResourcesResult
+		if err := json.Unmarshal(env.request(env.ctx, "resources/list", map[string]interface{}{}), &resources); err != nil {
+			t.Fatalf("failed to unmarshal resources: %v", err)
+		}
+		if len(resources.Resources) != 1 || resources.Resources[0].URI != "github://octocat/hello/tree" {
+			t.Errorf("resources = %+v, want only github://octocat/hello/tree", resources.Resources)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		uri      string
+		wantText string
+		wantMIME string
+		wantErr  string
+	}{
+		{
+			name:     "file on default branch",
+			uri:      "github://octocat/hello/contents/README.md",
+			wantText: "# hello\n",
+		},
+		{
+			name:     "nested file on branch",
+			uri:      "github://octocat/hello/contents/src/a%20b.json?ref=dev",
+			wantText: "{}\n",
+			wantMIME: "application/json",
+		},
+		{
+			name:     "directory",
+			uri:      "github://octocat/hello/contents/src?ref=dev",
+			wantText: `"uri": "github://octocat/hello/contents/src/a%20b.json?ref=dev"`,
+			wantMIME: "application/json",
+		},
+		{
+			name:     "tree",
+			uri:      "github://octocat/hello/tree?ref=dev",
+			wantText: `"uri": "github://octocat/hello/contents/src/a%20b.json?ref=dev"`,
+			wantMIME: "application/json",
+		},
+		{
+			name:     "pull request",
+			uri:      "github://octocat/hello/pulls/1",
+			wantText: `"title": "Add a"`,
+			wantMIME: "application/json",
+		},
+		{
+			name:    "missing file",
+			uri:     "github://octocat/hello/contents/missing.txt",
+			wantErr: common.ErrorCodeNotFound,
+		},
+		{
+			name:    "forbidden by policy",
+			uri:     "github://octocat/secret/contents/README.md",
+			wantErr: common.ErrorCodePermission,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, errMessage := env.readResource(tt.uri)
+			if tt.wantErr != "" {
+				if !strings.HasPrefix(errMessage, tt.wantErr+":") {
+					t.Fatalf("error = %q, want prefix %q", errMessage, tt.wantErr)
+				}
+				return
+			}
+			if errMessage != "" {
+				t.Fatalf("unexpected error: %s", errMessage)
+			}
+			if len(result.Contents) != 1 {
+				t.Fatalf("contents = %+v, want one entry", result.Contents)
+			}
+			content := result.Contents[0].(mcp.TextResourceContents)
+			// 拡張子からのMIMEタイプは実行環境に依存するため、指定したものだけを確認する
+			if content.URI != tt.uri || (tt.wantMIME != "" && content.MIMEType != tt.wantMIME) {
+				t.Errorf("uri = %s, mime = %s, want %s, %s", content.URI, content.MIMEType, tt.uri, tt.wantMIME)
+			}
+			if !strings.Contains(content.Text, tt.wantText) {
+				t.Errorf("text = %q, want to contain %q", content.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestDrainer(t *testing.T) {
+	d := newDrainer()
+	if !d.acquire() {
+		t.Fatal("acquire before shutdown should succeed")
+	}
+
+	streamDone := make(chan struct{})
+	handler := d.track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(streamDone)
+	}))
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, sseEndpoint, nil))
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- d.shutdown(context.Background())
+	}()
+
+	// 実行中のツール呼び出しが完了するまでストリームは閉じられない
+	select {
+	case <-shutdownDone:
+		t.Fatal("shutdown returned before the tool call finished")
+	case <-streamDone:
+		t.Fatal("stream closed before the tool call finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	result, err := withDrain(d, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		t.Fatal("handler should not be called while draining")
+		return nil, nil
+	})(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].(mcp.TextContent).Text, common.ErrorCodeUnavailable) {
+		t.Errorf("expected %s error, got %+v", common.ErrorCodeUnavailable, result)
+	}
+
+	d.release()
+	select {
+	case err := <-shutdownDone:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not return")
+	}
+	select {
+	case <-streamDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not closed")
+	}
+}
+
+func TestDrainerRejectsResourceReads(t *testing.T) {
+	env := newTestEnv(t)
+	uri := contentsResourceURI("octocat", "hello", "README.md", "")
+	if _, errMessage := env.readResource(uri); errMessage != "" {
+		t.Fatalf("unexpected error: %s", errMessage)
+	}
+
+	if err := env.server.drainer.shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, errMessage := env.readResource(uri); !strings.Contains(errMessage, common.ErrorCodeUnavailable) {
+		t.Errorf("error = %q, want %s", errMessage, common.ErrorCodeUnavailable)
+	}
+}
+
+func TestDrainerShutdownTimeout(t *testing.T) {
+	d := newDrainer()
+	d.acquire()
+	defer d.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestListenAndServeClosesSSEStreams(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	s := NewGitHubMCPServer(ServerOptions{})
+	config := HTTPConfig{Listen: addr, ShutdownTimeout: 5 * time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- s.ListenAndServe(ctx, config, s.ServeSSE(config.defaultBaseURL()))
+	}()
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Get("http://" + addr + sseEndpoint); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-serveDone:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe did not return")
+	}
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Errorf("stream should end cleanly: %v", err)
+	}
+}
+
+func TestHTTPConfig(t *testing.T) {
+	tests := []struct {
+		config  HTTPConfig
+		want    string
+		wantErr bool
+	}{
+		{config: HTTPConfig{Listen: "localhost:8080"}, want: "http://localhost:8080"},
+		{config: HTTPConfig{Listen: "0.0.0.0:8080"}, want: "http://localhost:8080"},
+		{config: HTTPConfig{Listen: ":8080"}, want: "http://localhost:8080"},
+		{config: HTTPConfig{Listen: "[::]:8443", TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}, want: "https://localhost:8443"},
+		{config: HTTPConfig{Listen: "10.0.0.1:8080"}, want: "http://10.0.0.1:8080"},
+		{config: HTTPConfig{Listen: "localhost:8080", TLSCertFile: "cert.pem"}, wantErr: true},
+		{config: HTTPConfig{Listen: "localhost"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := tt.config.validate()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("validate(%+v): expected error", tt.config)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("validate(%+v): unexpected error: %v", tt.config, err)
+			continue
+		}
+		if got := tt.config.defaultBaseURL(); got != tt.want {
+			t.Errorf("defaultBaseURL(%+v) = %q, want %q", tt.config, got, tt.want)
+		}
+	}
+}
+
+func TestParseBaseURL(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "https://mcp.example.com", want: "https://mcp.example.com"},
+		{value: "https://mcp.example.com/github/", want: "https://mcp.example.com/github"},
+		{value: "ftp://mcp.example.com", wantErr: true},
+		{value: "mcp.example.com:8080", wantErr: true},
+		{value: "https://mcp.example.com?x=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseBaseURL(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseBaseURL(%q): expected error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBaseURL(%q): unexpected error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseBaseURL(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTokenValidation(t *testing.T) {
+	gh := fakegithub.New()
+	t.Cleanup(gh.Close)
+	validator := common.NewTokenValidator(func(ctx context.Context, token string) (*common.TokenInfo, error) {
+		return operations.GetTokenInfo(context.WithValue(ctx, oauth2.HTTPClient, gh.HTTPClient()), token)
+	}, time.Minute)
+	s := NewGitHubMCPServer(ServerOptions{TokenValidator: validator})
+
+	sse := httptest.NewServer(s.ServeSSE("http://localhost"))
+	defer sse.Close()
+	streamable := httptest.NewServer(s.ServeStreamableHTTP())
+	defer streamable.Close()
+
+	// SSEのストリームは開いたサブテストの終了時に閉じる (サーバーのCloseは開いているストリームを待つため)
+	get := func(t *testing.T, url, authorization string) (*http.Response, toolErrorResult) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		var body toolErrorResult
+		if resp.StatusCode != http.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&body)
+		}
+		return resp, body
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		gh.ResetRequests()
+		for i := 0; i < 2; i++ {
+			resp, _ := get(t, sse.URL+sseEndpoint, "Bearer valid-token")
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+		}
+		if got := len(gh.Requests()); got != 1 {
+			t.Errorf("GitHub API requests = %d, want 1 (cached)", got)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		for _, url := range []string{sse.URL + sseEndpoint, streamable.URL + httpEndpoint} {
+			gh.AddFault(fakegithub.Fault{Path: "/user", Status: http.StatusUnauthorized, Message: "Bad credentials"})
+			resp, body := get(t, url, "token invalid-token")
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("%s: status = %d, want %d", url, resp.StatusCode, http.StatusUnauthorized)
+			}
+			if body.Code != common.ErrorCodeAuthentication || resp.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("%s: unexpected response: %+v", url, body)
+			}
+		}
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		resp, body := get(t, streamable.URL+httpEndpoint, "Basic dXNlcjpwYXNz")
+		if resp.StatusCode != http.StatusUnauthorized || body.Code != common.ErrorCodeAuthentication {
+			t.Errorf("status = %d, body = %+v", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("host override does not use server token", func(t *testing.T) {
+		t.Setenv("GITHUB_TOKEN", "server-token")
+		gh.ResetRequests()
+		req, _ := http.NewRequest(http.MethodPost, streamable.URL+httpEndpoint, strings.NewReader(`{}`))
+		req.Header.Set(common.GitHubHostHeader, gh.URL)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+		}
+		if got := gh.Requests(); len(got) != 0 {
+			t.Errorf("server token should not be sent: %v", got)
+		}
+	})
+
+	t.Run("github unavailable", func(t *testing.T) {
+		gh.AddFault(fakegithub.Fault{Path: "/user", Status: http.StatusServiceUnavailable, Message: "unavailable"})
+		resp, body := get(t, sse.URL+sseEndpoint, "Bearer another-token")
+		if resp.StatusCode != http.StatusBadGateway || body.Code != common.ErrorCodeGitHub {
+			t.Errorf("status = %d, body = %+v", resp.StatusCode, body)
+		}
+	})
+}
+
+func TestGetTokenInfo(t *testing.T) {
+	env := newTestEnv(t)
+	info, err := operations.GetTokenInfo(env.ctx, "test-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Login != fakegithub.Login || !reflect.DeepEqual(info.Scopes, []string{"repo", "read:org"}) || !info.ExpiresAt.IsZero() {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	env.gh.AddFault(fakegithub.Fault{
+		Path:   "/user",
+		Status: http.StatusOK,
+		Body:   map[string]interface{}{"login": fakegithub.Login},
+		Header: http.Header{"Github-Authentication-Token-Expiration": {"2030-01-02 03:04:05 UTC"}},
+	})
+	info, err = operations.GetTokenInfo(env.ctx, "test-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC); !info.ExpiresAt.Equal(want) || info.Scopes != nil {
+		t.Errorf("unexpected info: %+v", info)
+	}
+}
+
+// newGitHubAppTestEnv はGitHub Appのインストールトークンで認証するテスト環境を作成します
+// コンテキストには認証トークンを設定しないため、ツールはインストールトークンのみで動作する必要があります
+func newGitHubAppTestEnv(t *testing.T, key *rsa.PrivateKey, opts ServerOptions) *testEnv {
+	t.Helper()
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	app, err := operations.NewGitHubApp(42, pemData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts.GitHubApp = app
+	env := newTestEnvWithOptions(t, opts)
+	env.ctx = context.WithValue(context.Background(), oauth2.HTTPClient, env.gh.HTTPClient())
+	return env
+}
+
+func TestGitHubApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	getFile := map[string]interface{}{"owner": fakegithub.Login, "repo": "hello", "path": "README.md"}
+
+	t.Run("installation token is cached", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		env.gh.EnableApp(42, &key.PublicKey)
+		id := env.gh.AddInstallation(fakegithub.Login)
+		env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"README.md": "hello"})
+
+		for i := 0; i < 2; i++ {
+			if result := env.callTool("get_file_contents", getFile); result.IsError {
+				t.Fatalf("call %d failed: %s", i, resultText(result))
+			}
+		}
+		want := []string{
+			"GET /repos/octocat/hello/installation",
+			fmt.Sprintf("POST /app/installations/%d/access_tokens", id),
+			"GET /repos/octocat/hello/contents/README.md",
+			"GET /repos/octocat/hello/contents/README.md",
+		}
+		if got := env.gh.Requests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("requests = %v, want %v", got, want)
+		}
+		auths := env.gh.Authorizations()
+		if !strings.HasPrefix(auths[2], "Bearer ghs_") || auths[3] != auths[2] {
+			t.Errorf("tools should use the installation token: %v", auths)
+		}
+	})
+
+	t.Run("token is refreshed before expiry", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		env.gh.EnableApp(42, &key.PublicKey)
+		env.gh.AddInstallation(fakegithub.Login)
+		env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"README.md": "hello"})
+		// 更新の猶予期間より短い有効期間のトークンは毎回発行し直す
+		env.gh.AppTokenLifetime = 2 * time.Minute
+
+		for i := 0; i < 2; i++ {
+			if result := env.callTool("get_file_contents", getFile); result.IsError {
+				t.Fatalf("call %d failed: %s", i, resultText(result))
+			}
+		}
+		issued := 0
+		for _, req := range env.gh.Requests() {
+			if strings.HasSuffix(req, "/access_tokens") {
+				issued++
+			}
+		}
+		if issued != 2 {
+			t.Errorf("issued %d tokens, want 2: %v", issued, env.gh.Requests())
+		}
+	})
+
+	t.Run("default owner for tools without owner", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{GitHubAppDefaultOwner: "my-org"})
+		env.gh.EnableApp(42, &key.PublicKey)
+		env.gh.AddInstallation("my-org")
+
+		if result := env.callTool("search_repositories", map[string]interface{}{"query": "hello"}); result.IsError {
+			t.Fatalf("search failed: %s", resultText(result))
+		}
+		if got := env.gh.Requests()[0]; got != "GET /orgs/my-org/installation" {
+			t.Errorf("first request = %q", got)
+		}
+	})
+
+	t.Run("user installation", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{GitHubAppDefaultOwner: fakegithub.Login})
+		env.gh.EnableApp(42, &key.PublicKey)
+		env.gh.AddInstallation(fakegithub.Login)
+		env.gh.AddFault(fakegithub.Fault{Path: "/orgs/octocat/installation", Status: http.StatusNotFound, Message: "Not Found"})
+
+		if result := env.callTool("search_repositories", map[string]interface{}{"query": "hello"}); result.IsError {
+			t.Fatalf("search failed: %s", resultText(result))
+		}
+	})
+
+	t.Run("owner is required without default owner", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		env.gh.EnableApp(42, &key.PublicKey)
+		expectToolError(t, env.callTool("search_repositories", map[string]interface{}{"query": "hello"}), common.ErrorCodeInvalidArgument)
+	})
+
+	t.Run("not installed", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		env.gh.EnableApp(42, &key.PublicKey)
+		body := expectToolError(t, env.callTool("get_file_contents", getFile), common.ErrorCodeNotFound)
+		if !strings.Contains(body.Message, "octocat/hello") {
+			t.Errorf("message should name the repository: %s", body.Message)
+		}
+	})
+
+	t.Run("policy is checked before installation lookup", func(t *testing.T) {
+		policy, err := common.ParsePolicy([]byte(`readable: [octocat]
+forbidden: [octocat/secret]`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		env := newGitHubAppTestEnv(t, key, ServerOptions{Policy: policy})
+		env.gh.EnableApp(42, &key.PublicKey)
+
+		// インストールされていないことが分かるエラーではなく、ポリシーのエラーになる
+		args := map[string]interface{}{"owner": fakegithub.Login, "repo": "secret", "path": "README.md"}
+		expectToolError(t, env.callTool("get_file_contents", args), common.ErrorCodePermission)
+		if got := env.gh.Requests(); len(got) != 0 {
+			t.Errorf("forbidden repository should not reach GitHub: %v", got)
+		}
+	})
+
+	t.Run("create and fork use the organization installation", func(t *testing.T) {
+		policy, err := common.ParsePolicy([]byte(`readable: [octocat]
+writable: [my-org]`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		env := newGitHubAppTestEnv(t, key, ServerOptions{Policy: policy, GitHubAppDefaultOwner: fakegithub.Login})
+		env.gh.EnableApp(42, &key.PublicKey)
+		id := env.gh.AddInstallation("my-org")
+
+		var repo struct {
+			FullName string `json:"full_name"`
+		}
+		decodeResult(t, env.callTool("create_repository", map[string]interface{}{"name": "tools", "organization": "my-org"}), &repo)
+		if repo.FullName != "my-org/tools" {
+			t.Errorf("created %q, want my-org/tools", repo.FullName)
+		}
+		decodeResult(t, env.callTool("fork_repository", map[string]interface{}{"owner": fakegithub.Login, "repo": "hello", "organization": "my-org"}), &repo)
+		if repo.FullName != "my-org/hello" {
+			t.Errorf("forked to %q, want my-org/hello", repo.FullName)
+		}
+		want := []string{
+			"GET /orgs/my-org/installation",
+			fmt.Sprintf("POST /app/installations/%d/access_tokens", id),
+			"POST /orgs/my-org/repos",
+			"POST /repos/octocat/hello/forks",
+		}
+		if got := env.gh.Requests(); !reflect.DeepEqual(got, want) {
+			t.Errorf("requests = %v, want %v", got, want)
+		}
+
+		// インストールトークンは個人アカウントに作成できないため organization が必須で、/user も呼び出さない
+		env.gh.ResetRequests()
+		expectToolError(t, env.callTool("create_repository", map[string]interface{}{"name": "tools"}), common.ErrorCodeInvalidArgument)
+		expectToolError(t, env.callTool("fork_repository", map[string]interface{}{"owner": fakegithub.Login, "repo": "hello"}), common.ErrorCodeInvalidArgument)
+		expectToolError(t, env.callTool("create_repository", map[string]interface{}{"name": "tools", "organization": "other-org"}), common.ErrorCodePermission)
+		if got := env.gh.Requests(); len(got) != 0 {
+			t.Errorf("requests = %v, want none", got)
+		}
+	})
+
+	t.Run("wrong private key", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		env.gh.EnableApp(42, &other.PublicKey)
+		env.gh.AddInstallation(fakegithub.Login)
+		expectToolError(t, env.callTool("get_file_contents", getFile), common.ErrorCodeAuthentication)
+	})
+
+	t.Run("concurrent calls mint one token", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		env.gh.EnableApp(42, &key.PublicKey)
+		env.gh.AddInstallation(fakegithub.Login)
+		app := env.server.opts.GitHubApp
+
+		var wg sync.WaitGroup
+		tokens := make([]string, 5)
+		errs := make([]error, len(tokens))
+		for i := range tokens {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tokens[i], errs[i] = app.InstallationToken(env.ctx, fakegithub.Login, "hello")
+			}()
+		}
+		wg.Wait()
+		for i := range tokens {
+			if errs[i] != nil || tokens[i] != tokens[0] {
+				t.Fatalf("call %d: token %q, error %v", i, tokens[i], errs[i])
+			}
+		}
+		if got := env.gh.Requests(); len(got) != 2 {
+			t.Errorf("requests = %v, want one lookup and one token", got)
+		}
+	})
+
+	t.Run("slow owner does not block other owners", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		env.gh.EnableApp(42, &key.PublicKey)
+		env.gh.AddInstallation(fakegithub.Login)
+		env.gh.AddInstallation("slow-org")
+		app := env.server.opts.GitHubApp
+
+		blocked, release := make(chan struct{}), make(chan struct{})
+		env.gh.BeforeRequest = func(r *http.Request) {
+			if r.URL.Path == "/orgs/slow-org/installation" {
+				close(blocked)
+				<-release
+			}
+		}
+		done := make(chan error)
+		go func() {
+			_, err := app.InstallationToken(env.ctx, "slow-org", "")
+			done <- err
+		}()
+		<-blocked
+
+		if _, err := app.InstallationToken(env.ctx, fakegithub.Login, "hello"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		close(release)
+		if err := <-done; err != nil {
+			t.Errorf("slow owner: unexpected error: %v", err)
+		}
+	})
+
+	t.Run("resources use installation token", func(t *testing.T) {
+		env := newGitHubAppTestEnv(t, key, ServerOptions{})
+		env.gh.EnableApp(42, &key.PublicKey)
+		env.gh.AddInstallation(fakegithub.Login)
+		env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"README.md": "hello"})
+
+		result, errMessage := env.readResource("github://octocat/hello/contents/README.md")
+		if errMessage != "" {
+			t.Fatalf("read failed: %s", errMessage)
+		}
+		if text := result.Contents[0].(mcp.TextResourceContents).Text; text != "hello" {
+			t.Errorf("resource text = %q", text)
+		}
+	})
+}
+
+func TestNewGitHubApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	if _, err := operations.NewGitHubApp(1, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})); err != nil {
+		t.Errorf("PKCS#8 key should be accepted: %v", err)
+	}
+	if _, err := operations.NewGitHubApp(1, []byte("not a key")); err == nil {
+		t.Error("expected error for invalid key")
+	}
+	if _, err := operations.NewGitHubApp(0, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})); err == nil {
+		t.Error("expected error for invalid app id")
+	}
+}
+
+func TestInboundAuth(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "server-token")
+	t.Setenv("CI_BOT_TOKEN", "ci-bot-token")
+	hash := sha256.Sum256([]byte("secret-key"))
+	inbound, err := common.NewInboundAuth(common.InboundAuthConfig{
+		APIKeys: []common.APIKeyConfig{
+			{Name: "ci-bot", KeySHA256: hex.EncodeToString(hash[:]), GitHubTokenEnv: "CI_BOT_TOKEN"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := NewGitHubMCPServer(ServerOptions{InboundAuth: inbound})
+
+	sse := httptest.NewUnstartedServer(nil)
+	sse.Config.Handler = s.ServeSSE("http://" + sse.Listener.Addr().String())
+	sse.Start()
+	defer sse.Close()
+	streamable := httptest.NewServer(s.ServeStreamableHTTP())
+	defer streamable.Close()
+
+	t.Run("unauthenticated requests are rejected", func(t *testing.T) {
+		for _, url := range []string{sse.URL + sseEndpoint, streamable.URL + httpEndpoint} {
+			for _, authorization := range []string{"", "Bearer wrong-key"} {
+				req, _ := http.NewRequest(http.MethodGet, url, nil)
+				if authorization != "" {
+					req.Header.Set("Authorization", authorization)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				var body toolErrorResult
+				json.NewDecoder(resp.Body).Decode(&body)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusUnauthorized || body.Code != common.ErrorCodeAuthentication {
+					t.Errorf("%s (%q): status = %d, body = %+v", url, authorization, resp.StatusCode, body)
+				}
+			}
+		}
+	})
+
+	t.Run("authenticated sessions", func(t *testing.T) {
+		header := http.Header{"Authorization": {"Bearer secret-key"}}
+		if status, tools := sseListTools(t, sse.URL, header); status != http.StatusOK || len(tools) == 0 {
+			t.Errorf("sse: status = %d, tools = %v", status, tools)
+		}
+		// セッションIDを知っていても、認証情報のないメッセージは拒否する
+		resp, err := http.Post(sse.URL+"/message?sessionId=unknown", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("message status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+		}
+		if status, tools := httpListTools(t, streamable.URL, header); status != http.StatusOK || len(tools) == 0 {
+			t.Errorf("http: status = %d, tools = %v", status, tools)
+		}
+	})
+
+	t.Run("identity is mapped to github token", func(t *testing.T) {
+		var identity *common.Identity
+		var token string
+		handler := s.withInboundAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := s.requestContext(r.Context(), r)
+			identity = common.IdentityFromContext(ctx)
+			token, _ = common.GetAuthTokenFromContext(ctx)
+		}))
+		req := httptest.NewRequest(http.MethodPost, httpEndpoint, nil)
+		req.Header.Set("Authorization", "Bearer secret-key")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if identity == nil || identity.Subject != "ci-bot" || identity.Method != common.IdentityMethodAPIKey {
+			t.Errorf("identity = %+v", identity)
+		}
+		if token != "ci-bot-token" {
+			t.Errorf("token = %q, want ci-bot-token", token)
+		}
+	})
+}
+
+func TestAnonymousServerToken(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "server-token")
+	req := httptest.NewRequest(http.MethodPost, httpEndpoint, nil)
+
+	// 呼び出し元を認証しない場合、既定ではサーバーのトークンを使用させない
+	s := NewGitHubMCPServer(ServerOptions{})
+	if _, err := common.GetAuthTokenFromContext(s.requestContext(context.Background(), req)); common.ErrorCode(err) != common.ErrorCodeAuthentication {
+		t.Errorf("expected authentication error, got %v", err)
+	}
+
+	s = NewGitHubMCPServer(ServerOptions{AllowAnonymousServerToken: true})
+	if token, err := common.GetAuthTokenFromContext(s.requestContext(context.Background(), req)); err != nil || token != "server-token" {
+		t.Errorf("token = %q, err = %v", token, err)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"localhost:8080": true,
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		"0.0.0.0:8080":   false,
+		":8080":          false,
+		"10.0.0.1:8080":  false,
+	}
+	for listen, want := range tests {
+		if got := isLoopback(listen); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", listen, got, want)
+		}
+	}
+}
+
+// progressSession は送信された通知を記録するテスト用のクライアントセッションです
+type progressSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *progressSession) Initialize()       {}
+func (s *progressSession) Initialized() bool { return true }
+func (s *progressSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+func (s *progressSession) SessionID() string { return "progress-session" }
+
+func TestRetryProgress(t *testing.T) {
+	env := newTestEnv(t)
+	transport := &operations.RetryTransport{
+		Base:       env.gh.HTTPClient().Transport,
+		MaxRetries: 3,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   10 * time.Millisecond,
+		MaxWait:    time.Second,
+	}
+	ctx := operations.WithClientCache(env.ctx, operations.NewClientCache(operations.NewClientFactory(transport), 0, 0))
+	session := &progressSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	ctx = env.server.server.WithContext(ctx, session)
+
+	env.gh.CreateBranch("octocat", "hello", "feature", "main")
+	number := env.gh.CreatePull("octocat", "hello", "feature", "main", "Add feature")
+	path := fmt.Sprintf("/repos/octocat/hello/pulls/%d", number)
+	args := map[string]interface{}{"owner": "octocat", "repo": "hello", "pull_number": number}
+	env.gh.AddFault(fakegithub.Fault{Method: http.MethodGet, Path: path, Status: http.StatusServiceUnavailable, Message: "unavailable", Times: 2})
+
+	raw := env.request(ctx, "tools/call", map[string]interface{}{
+		"name":      "get_pull_request",
+		"arguments": args,
+		"_meta":     map[string]interface{}{"progressToken": "retry-1"},
+	})
+	result, err := mcp.ParseCallToolResult(&raw)
+	if err != nil {
+		t.Fatalf("failed to parse tool result: %v", err)
+	}
+	// 一時的な障害は再試行され、呼び出し元にはエラーを返さない
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+
+	if got := env.gh.Requests(); len(got) != 3 {
+		t.Errorf("requests = %v, want 3 attempts", got)
+	}
+	close(session.notifications)
+	var progress []interface{}
+	for n := range session.notifications {
+		fields := n.Params.AdditionalFields
+		if n.Method != "notifications/progress" || fields["progressToken"] != "retry-1" || !strings.Contains(fmt.Sprint(fields["message"]), "再試行") {
+			t.Errorf("unexpected notification: %+v", n)
+		}
+		progress = append(progress, fields["progress"])
+	}
+	if !reflect.DeepEqual(progress, []interface{}{1, 2}) {
+		t.Errorf("progress = %v, want [1 2]", progress)
+	}
+
+	// 進捗の通知を要求していない呼び出しでも再試行する
+	env.gh.ResetRequests()
+	env.gh.AddFault(fakegithub.Fault{Method: http.MethodGet, Path: path, Status: http.StatusServiceUnavailable, Message: "unavailable"})
+	if result := env.callToolWithContext(ctx, "get_pull_request", args); result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if got := env.gh.Requests(); len(got) != 2 {
+		t.Errorf("requests = %v, want 2 attempts", got)
+	}
+}
+
+func TestHTTPCache(t *testing.T) {
+	env := newTestEnv(t)
+	transport := operations.NewCachingTransport(env.gh.HTTPClient().Transport, operations.NewMemoryCache(0))
+	ctx := operations.WithClientCache(env.ctx, operations.NewClientCache(operations.NewClientFactory(transport), 0, 0))
+	args := map[string]interface{}{"owner": "octocat", "repo": "hello", "path": "README.md"}
+
+	readContent := func() string {
+		t.Helper()
+		var file struct {
+			Content string `json:"content"`
+		}
+		decodeResult(t, env.callToolWithContext(ctx, "get_file_contents", args), &file)
+		return file.Content
+	}
+
+	first := readContent()
+	if env.gh.NotModified() != 0 {
+		t.Fatalf("first read should not be revalidated")
+	}
+	// 同じ内容の再取得は304で再検証され、キャッシュした内容を返す
+	if again := readContent(); again != first || env.gh.NotModified() == 0 {
+		t.Errorf("content = %q, notModified = %d", again, env.gh.NotModified())
+	}
+
+	// 更新された内容は再検証で取得し直す
+	env.gh.SetFiles(fakegithub.Login, "hello", "main", map[string]string{"README.md": "updated"})
+	if updated := readContent(); updated != "updated" {
+		t.Errorf("content = %q, want updated", updated)
+	}
+
+	// 別のトークンでは保存したレスポンスを使用しない
+	env.gh.ResetRequests()
+	other := common.WithAuthToken(ctx, "other-token")
+	if result := env.callToolWithContext(other, "get_file_contents", args); result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if env.gh.NotModified() != 0 {
+		t.Errorf("another token should not be revalidated with the cached response")
+	}
+}
+
+func TestNewClientCache(t *testing.T) {
+	for _, mode := range []string{httpCacheMemory, httpCacheDisk} {
+		cache, err := newClientCache(mode, t.TempDir(), 1)
+		if err != nil || cache == nil {
+			t.Errorf("%s: cache = %v, err = %v", mode, cache, err)
+		}
+	}
+	if cache, err := newClientCache(httpCacheOff, "", 1); err != nil || cache != nil {
+		t.Errorf("off: cache = %v, err = %v", cache, err)
+	}
+	if _, err := newClientCache("redis", "", 1); err == nil {
+		t.Errorf("expected error for unknown cache")
+	}
+	if _, err := newClientCache(httpCacheMemory, "", -1); err == nil {
+		t.Errorf("expected error for negative size")
+	}
+}
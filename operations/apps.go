@@ -0,0 +1,254 @@
+package operations
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+const (
+	// appJWTLifetime はGitHub AppのJWTの有効期間です (GitHubの上限は10分)
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockSkew はサーバー間の時刻のずれを考慮してJWTの発行時刻を過去にずらす時間です
+	appJWTClockSkew = time.Minute
+	// installationTokenRefreshMargin はインストールトークンを有効期限のどれだけ前に更新するかを表します
+	// ツールのタイムアウトより長くして、ツールの実行中にトークンが失効しないようにします
+	installationTokenRefreshMargin = 5 * time.Minute
+)
+
+// GitHubApp はGitHub Appとして認証し、インストールごとのトークンを発行します
+//
+// オーナーごとのインストールIDとインストールトークンはキャッシュされ、
+// トークンは有効期限が近づくと自動的に再発行されます。
+// キャッシュはGitHub APIの接続先ごとに分けて保持します。
+type GitHubApp struct {
+	appID int64
+	key   *rsa.PrivateKey
+	now   func() time.Time
+
+	// mu はキャッシュのマップのみを保護します (ネットワーク呼び出しの間は保持しません)
+	mu            sync.Mutex
+	installations map[string]int64
+	tokens        map[string]installationToken
+	// ownerLocks はオーナーごとのロックです
+	// 同じオーナーのインストールの検索とトークンの発行を並行して行わないために使用します
+	ownerLocks map[string]*sync.Mutex
+}
+
+// installationToken はキャッシュされたインストールトークンを表します
+type installationToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// LoadGitHubApp はApp IDとPEM形式の秘密鍵ファイルからGitHubAppを作成します
+func LoadGitHubApp(appID int64, keyFile string) (*GitHubApp, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub Appの秘密鍵を読み込めません: %w", err)
+	}
+	return NewGitHubApp(appID, data)
+}
+
+// NewGitHubApp はApp IDとPEM形式の秘密鍵からGitHubAppを作成します
+// 秘密鍵はGitHubが発行するPKCS#1形式とPKCS#8形式のRSA鍵に対応しています
+func NewGitHubApp(appID int64, privateKeyPEM []byte) (*GitHubApp, error) {
+	if appID <= 0 {
+		return nil, fmt.Errorf("GitHub AppのIDが不正です: %d", appID)
+	}
+	key, err := parseRSAPrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub Appの秘密鍵を解析できません: %w", err)
+	}
+	return &GitHubApp{
+		appID:         appID,
+		key:           key,
+		now:           time.Now,
+		installations: make(map[string]int64),
+		tokens:        make(map[string]installationToken),
+		ownerLocks:    make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// parseRSAPrivateKey はPEM形式のRSA秘密鍵を解析します
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("PEM形式ではありません")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("RSA鍵ではありません")
+	}
+	return key, nil
+}
+
+// JWT はGitHub Appとして認証するためのRS256で署名したJWTを作成します
+func (a *GitHubApp) JWT() (string, error) {
+	now := a.now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(a.appID, 10),
+	})
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("GitHub AppのJWTに署名できません: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// InstallationToken は指定されたオーナーのインストールトークンを返します
+// repo を指定した場合はリポジトリからインストールを検索し、省略した場合は組織またはユーザーから検索します
+// キャッシュされたトークンの有効期限が近い場合は新しいトークンを発行します
+func (a *GitHubApp) InstallationToken(ctx context.Context, owner, repo string) (string, error) {
+	if owner == "" {
+		return "", errors.New("GitHub Appのインストールを特定するにはオーナーが必要です")
+	}
+	host, _ := common.GitHubHostFromContext(ctx)
+
+	ownerKey := host.APIURL + "\x00" + strings.ToLower(owner)
+
+	// 同じオーナーのトークンを並行して発行しないように、発行が完了するまでオーナーごとのロックを保持する
+	// 他のオーナーの呼び出しは、このオーナーのAPI呼び出しが遅くても待たされない
+	lock := a.ownerLock(ownerKey)
+	lock.Lock()
+	defer lock.Unlock()
+
+	a.mu.Lock()
+	installationID, ok := a.installations[ownerKey]
+	a.mu.Unlock()
+	if !ok {
+		id, err := a.findInstallation(ctx, owner, repo)
+		if err != nil {
+			return "", err
+		}
+		installationID = id
+		a.mu.Lock()
+		a.installations[ownerKey] = id
+		a.mu.Unlock()
+	}
+
+	tokenKey := host.APIURL + "\x00" + strconv.FormatInt(installationID, 10)
+	a.mu.Lock()
+	cached, ok := a.tokens[tokenKey]
+	a.mu.Unlock()
+	if ok && a.now().Add(installationTokenRefreshMargin).Before(cached.expiresAt) {
+		return cached.token, nil
+	}
+
+	client, err := a.appClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	token, _, err := client.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		mapped := mapGitHubError(err)
+		if common.ErrorCode(mapped) == common.ErrorCodeNotFound {
+			// アンインストールされた場合は次回インストールを検索し直す
+			a.mu.Lock()
+			delete(a.installations, ownerKey)
+			a.mu.Unlock()
+		}
+		return "", fmt.Errorf("インストールトークンの発行に失敗: %w", mapped)
+	}
+	a.mu.Lock()
+	a.tokens[tokenKey] = installationToken{token: token.GetToken(), expiresAt: token.GetExpiresAt().Time}
+	a.mu.Unlock()
+	return token.GetToken(), nil
+}
+
+// ownerLock はオーナーごとのロックを返します (初めて使用するオーナーの場合は作成します)
+func (a *GitHubApp) ownerLock(ownerKey string) *sync.Mutex {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lock, ok := a.ownerLocks[ownerKey]
+	if !ok {
+		lock = &sync.Mutex{}
+		a.ownerLocks[ownerKey] = lock
+	}
+	return lock
+}
+
+// findInstallation はオーナーまたはリポジトリに対応するインストールIDを検索します
+func (a *GitHubApp) findInstallation(ctx context.Context, owner, repo string) (int64, error) {
+	client, err := a.appClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if repo != "" {
+		installation, _, err := client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+		if err != nil {
+			return 0, installationLookupError(owner+"/"+repo, err)
+		}
+		return installation.GetID(), nil
+	}
+
+	installation, _, err := client.Apps.FindOrganizationInstallation(ctx, owner)
+	if err == nil {
+		return installation.GetID(), nil
+	}
+	if common.ErrorCode(mapGitHubError(err)) != common.ErrorCodeNotFound {
+		return 0, installationLookupError(owner, err)
+	}
+	// 組織でない場合はユーザーのインストールを検索する
+	installation, _, err = client.Apps.FindUserInstallation(ctx, owner)
+	if err != nil {
+		return 0, installationLookupError(owner, err)
+	}
+	return installation.GetID(), nil
+}
+
+// installationLookupError はインストールの検索エラーを変換します
+// 見つからない場合はGitHub Appがインストールされていないことが分かるメッセージにします
+func installationLookupError(target string, err error) error {
+	mapped := mapGitHubError(err)
+	if common.ErrorCode(mapped) == common.ErrorCodeNotFound {
+		return &common.GitHubResourceNotFoundError{
+			GitHubError: common.GitHubError{
+				Message: fmt.Sprintf("%s にGitHub Appがインストールされていません", target),
+				Status:  http.StatusNotFound,
+			},
+		}
+	}
+	return fmt.Errorf("%s のGitHub Appのインストールを取得できません: %w", target, mapped)
+}
+
+// appClient はGitHub AppのJWTで認証するクライアントを作成します
+// JWTは呼び出しごとに署名し直すため、クライアントはキャッシュしません
+func (a *GitHubApp) appClient(ctx context.Context) (*github.Client, error) {
+	jwt, err := a.JWT()
+	if err != nil {
+		return nil, err
+	}
+	return newUncachedGitHubClient(ctx, jwt)
+}
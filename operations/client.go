@@ -0,0 +1,199 @@
+package operations
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// DefaultClientCacheSize はクライアントキャッシュに保持するクライアントの既定の最大数です
+	DefaultClientCacheSize = 256
+	// DefaultClientCacheTTL はキャッシュしたクライアントを再利用する既定の期間です
+	DefaultClientCacheTTL = 30 * time.Minute
+)
+
+// ClientFactory はトークンと接続先からGitHubクライアントを作成します
+type ClientFactory func(ctx context.Context, token string, host common.GitHubHost) (*github.Client, error)
+
+// NewTransport はGitHub APIへの接続を再利用するように調整したHTTPトランスポートを作成します
+// ほとんどのリクエストは同じホストに送信されるため、ホストごとのアイドル接続数を増やしています
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   50,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// NewClientFactory は指定したトランスポートで接続するClientFactoryを作成します
+// 作成したクライアントはすべて同じトランスポートを共有するため、接続が再利用されます
+func NewClientFactory(transport http.RoundTripper) ClientFactory {
+	return func(ctx context.Context, token string, host common.GitHubHost) (*github.Client, error) {
+		httpClient := &http.Client{
+			Transport: &oauth2.Transport{
+				Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}),
+				Base:   transport,
+			},
+		}
+		client := github.NewClient(httpClient)
+		if host.IsDefault() {
+			return client, nil
+		}
+		client, err := client.WithEnterpriseURLs(host.APIURL, host.UploadURL)
+		if err != nil {
+			return nil, fmt.Errorf("GitHub APIの接続先 %s が不正です: %v", host, err)
+		}
+		return client, nil
+	}
+}
+
+// ClientCache はトークンと接続先ごとにGitHubクライアントを再利用します
+//
+// クライアントは最大数を超えると最も長く使用されていないものから破棄され、
+// 作成からTTLが経過したものは作成し直されます。
+// キャッシュはトークンのハッシュと接続先で識別され、トークンそのものはキーに含めません。
+type ClientCache struct {
+	factory ClientFactory
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// clientCacheEntry はキャッシュされたクライアントを表します
+type clientCacheEntry struct {
+	key       string
+	client    *github.Client
+	expiresAt time.Time
+}
+
+// NewClientCache は新しいClientCacheを作成します
+// size が0以下の場合は DefaultClientCacheSize、ttl が0以下の場合は DefaultClientCacheTTL を使用します
+func NewClientCache(factory ClientFactory, size int, ttl time.Duration) *ClientCache {
+	if size <= 0 {
+		size = DefaultClientCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultClientCacheTTL
+	}
+	return &ClientCache{
+		factory: factory,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Client はトークンとコンテキストの接続先に対応するクライアントを返します
+// キャッシュにない場合や期限が切れている場合は新しく作成します
+func (c *ClientCache) Client(ctx context.Context, token string) (*github.Client, error) {
+	host, _ := common.GitHubHostFromContext(ctx)
+	sum := sha256.Sum256([]byte(host.APIURL + "\x00" + host.UploadURL + "\x00" + token))
+	key := hex.EncodeToString(sum[:])
+	now := c.now()
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*clientCacheEntry)
+		if now.Before(entry.expiresAt) {
+			c.order.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry.client, nil
+		}
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	client, err := c.factory(ctx, token, host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 並行して作成された場合は先にキャッシュされたクライアントを使用する
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*clientCacheEntry).client, nil
+	}
+	c.entries[key] = c.order.PushFront(&clientCacheEntry{key: key, client: client, expiresAt: now.Add(c.ttl)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*clientCacheEntry).key)
+	}
+	return client, nil
+}
+
+// Len はキャッシュされているクライアントの数を返します
+func (c *ClientCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// defaultClientCache は共有のトランスポートで接続するクライアントのキャッシュです
+// レート制限と一時的な障害のリクエストは自動的に再試行されます
+var defaultClientCache = NewClientCache(NewClientFactory(NewRetryTransport(NewTransport())), DefaultClientCacheSize, DefaultClientCacheTTL)
+
+// clientCacheKey はクライアントキャッシュを保存するためのコンテキストキー
+type clientCacheKey struct{}
+
+// WithClientCache はコンテキストに使用するクライアントキャッシュを設定します
+// テストや独自のトランスポートを使用する場合に、既定のキャッシュの代わりに使用されます
+func WithClientCache(ctx context.Context, cache *ClientCache) context.Context {
+	return context.WithValue(ctx, clientCacheKey{}, cache)
+}
+
+// getGitHubClient は認証済みのGitHubクライアントを返します
+// コンテキストにGitHub Enterprise Serverなどの接続先が設定されている場合はそのホストに接続します
+// クライアントはトークンと接続先ごとにキャッシュされ、接続が再利用されます
+func getGitHubClient(ctx context.Context, token string) (*github.Client, error) {
+	if cache, ok := ctx.Value(clientCacheKey{}).(*ClientCache); ok {
+		return cache.Client(ctx, token)
+	}
+	// oauth2.HTTPClient でHTTPクライアントが指定されている場合は、そのクライアントで接続する
+	if base, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		host, _ := common.GitHubHostFromContext(ctx)
+		return NewClientFactory(base.Transport)(ctx, token, host)
+	}
+	return defaultClientCache.Client(ctx, token)
+}
+
+// newUncachedGitHubClient はキャッシュせずにGitHubクライアントを作成します
+// GitHub AppのJWTのように呼び出しごとに変わるトークンでキャッシュを埋めないために使用します
+func newUncachedGitHubClient(ctx context.Context, token string) (*github.Client, error) {
+	host, _ := common.GitHubHostFromContext(ctx)
+	if cache, ok := ctx.Value(clientCacheKey{}).(*ClientCache); ok {
+		return cache.factory(ctx, token, host)
+	}
+	if base, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		return NewClientFactory(base.Transport)(ctx, token, host)
+	}
+	return defaultClientCache.factory(ctx, token, host)
+}
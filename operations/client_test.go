@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+func TestClientCache(t *testing.T) {
+	created := 0
+	factory := func(ctx context.Context, token string, host common.GitHubHost) (*github.Client, error) {
+		created++
+		return github.NewClient(nil), nil
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewClientCache(factory, 2, time.Minute)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	a, _ := cache.Client(ctx, "token-a")
+	if again, _ := cache.Client(ctx, "token-a"); again != a || created != 1 {
+		t.Fatalf("same token should reuse the client: created = %d", created)
+	}
+
+	// 接続先が異なる場合は別のクライアントを作成する
+	ghe := common.WithGitHubHost(ctx, common.GitHubHost{APIURL: "https://ghe.example.com/api/v3/", UploadURL: "https://ghe.example.com/api/uploads/"})
+	if other, _ := cache.Client(ghe, "token-a"); other == a || created != 2 {
+		t.Fatalf("another host should not share the client: created = %d", created)
+	}
+
+	// 最大数を超えると最も長く使用されていないクライアントを破棄する
+	cache.Client(ctx, "token-a")
+	cache.Client(ctx, "token-b")
+	if cache.Len() != 2 {
+		t.Fatalf("len = %d, want 2", cache.Len())
+	}
+	created = 0
+	if again, _ := cache.Client(ctx, "token-a"); again != a || created != 0 {
+		t.Errorf("recently used client should be kept: created = %d", created)
+	}
+	cache.Client(ghe, "token-a")
+	if created != 1 {
+		t.Errorf("evicted client should be recreated: created = %d", created)
+	}
+
+	// TTLが経過したクライアントは作成し直す
+	created = 0
+	now = now.Add(2 * time.Minute)
+	if again, _ := cache.Client(ctx, "token-a"); again == a || created != 1 {
+		t.Errorf("expired client should be recreated: created = %d", created)
+	}
+}
+
+func TestClientFactoryEnterpriseHost(t *testing.T) {
+	factory := NewClientFactory(NewTransport())
+	host := common.GitHubHost{APIURL: "https://ghe.example.com/api/v3/", UploadURL: "https://ghe.example.com/api/uploads/"}
+	client, err := factory(context.Background(), "token", host)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := client.BaseURL.String(); got != host.APIURL {
+		t.Errorf("base URL = %q, want %q", got, host.APIURL)
+	}
+
+	client, err = factory(context.Background(), "token", common.GitHubHost{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := client.BaseURL.String(); got != "https://api.github.com/" {
+		t.Errorf("base URL = %q", got)
+	}
+}
@@ -0,0 +1,248 @@
+package operations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+const (
+	// DefaultDiffBytes はPull Requestのdiffを1回で返す既定の最大バイト数です
+	DefaultDiffBytes = 50000
+	// MaxDiffBytes はPull Requestのdiffを1回で返す最大バイト数の上限です
+	MaxDiffBytes = 1000000
+)
+
+// GetPullRequestDiffOptions はPull Requestのdiffの取得オプションを表します
+//
+// Files にはパスまたはglobパターン (例: "src/*.go") を指定し、一致するファイルの差分のみを返します。
+// diffが MaxBytes を超える場合は行の区切りで切り詰め、続きを取得するための NextCursor を返します。
+type GetPullRequestDiffOptions struct {
+	Owner      string   `json:"owner"`
+	Repo       string   `json:"repo"`
+	PullNumber int      `json:"pull_number"`
+	Files      []string `json:"files,omitempty"`
+	MaxBytes   int      `json:"max_bytes,omitempty"`
+	Cursor     string   `json:"cursor,omitempty"`
+}
+
+// PullRequestDiff はPull Requestのdiffの取得結果を表します
+type PullRequestDiff struct {
+	Diff       string   `json:"diff"`
+	Files      []string `json:"files"`       // 絞り込み後のdiffに含まれるファイル
+	TotalBytes int      `json:"total_bytes"` // 絞り込み後のdiff全体のバイト数
+	Truncated  bool     `json:"truncated"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// diffCursor は next_cursor に含める続きの位置を表します
+// 前回と異なるdiff (ヘッドの更新や絞り込みの変更) に対して使われないよう、diffのハッシュを保持します
+type diffCursor struct {
+	Offset int    `json:"o"`
+	Hash   string `json:"h"`
+}
+
+// encode はカーソルをクライアントに返す不透明な文字列に変換します
+func (c diffCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeDiffCursor は next_cursor の文字列を解析します
+func decodeDiffCursor(value string) (diffCursor, error) {
+	var c diffCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Offset < 1 || c.Hash == "" {
+		return diffCursor{}, fmt.Errorf("cursor %q is not a next_cursor returned by a previous result", value)
+	}
+	return c, nil
+}
+
+// diffHash はカーソルとdiffの対応を確認するためのハッシュを返します
+func diffHash(diff string) string {
+	sum := sha256.Sum256([]byte(diff))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Validate はdiffの取得オプションを検証します
+func (o GetPullRequestDiffOptions) Validate() error {
+	if o.MaxBytes < 0 || o.MaxBytes > MaxDiffBytes {
+		return fmt.Errorf("max_bytes must be between 1 and %d", MaxDiffBytes)
+	}
+	for _, pattern := range o.Files {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("files contains an invalid pattern %q", pattern)
+		}
+	}
+	if o.Cursor != "" {
+		if _, err := decodeDiffCursor(o.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffSection はdiffの1ファイル分の範囲を表します
+type diffSection struct {
+	text  string
+	paths []string // 変更前と変更後のパス
+}
+
+// splitDiff はunified diffをファイルごとの範囲に分割します
+func splitDiff(diff string) []diffSection {
+	var sections []diffSection
+	for len(diff) > 0 {
+		end := strings.Index(diff[1:], "\ndiff --git ")
+		if end < 0 {
+			end = len(diff)
+		} else {
+			end += 2
+		}
+		text := diff[:end]
+		sections = append(sections, diffSection{text: text, paths: diffSectionPaths(text)})
+		diff = diff[end:]
+	}
+	return sections
+}
+
+// diffSectionPaths はdiffの1ファイル分のヘッダーから変更前と変更後のパスを求めます
+func diffSectionPaths(text string) []string {
+	var paths []string
+	header, _, _ := strings.Cut(text, "\n")
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			// ハンクの本文にはヘッダーがないため、ここで打ち切る
+			return withHeaderPath(paths, header)
+		case strings.HasPrefix(line, "--- a/"), strings.HasPrefix(line, "+++ b/"):
+			paths = append(paths, line[len("--- a/"):])
+		case strings.HasPrefix(line, "rename from "):
+			paths = append(paths, strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			paths = append(paths, strings.TrimPrefix(line, "rename to "))
+		}
+	}
+	return withHeaderPath(paths, header)
+}
+
+// withHeaderPath は "diff --git a/<path> b/<path>" の行からパスを補います
+// 内容の差分がないファイル (モードの変更など) はヘッダー以外にパスを含まないためです
+func withHeaderPath(paths []string, header string) []string {
+	if len(paths) > 0 {
+		return paths
+	}
+	rest := strings.TrimPrefix(header, "diff --git a/")
+	if n := (len(rest) - len(" b/")) / 2; n > 0 && rest[n:] == " b/"+rest[:n] {
+		return []string{rest[:n]}
+	}
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return []string{rest[:i], rest[i+len(" b/"):]}
+	}
+	return nil
+}
+
+// matchesAny はパスのいずれかがパターンのいずれかに一致するかどうかを判断します
+func matchesAny(paths, patterns []string) bool {
+	for _, p := range paths {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// truncateDiff はdiffの offset から最大 maxBytes バイトを返します
+// 行の途中で切れないよう、可能な限り直前の改行の位置で切り詰めます
+func truncateDiff(diff string, offset, maxBytes int) (chunk string, next int) {
+	rest := diff[offset:]
+	if len(rest) <= maxBytes {
+		return rest, 0
+	}
+	end := strings.LastIndexByte(rest[:maxBytes], '\n') + 1
+	if end == 0 {
+		// 1行が上限を超える場合は、UTF-8の文字の途中で切れないようにする
+		end = maxBytes
+		for end > 0 && !utf8.RuneStart(rest[end]) {
+			end--
+		}
+		if end == 0 {
+			// 上限が1文字より小さい場合は、進まなくならないよう文字の終わりまで含める
+			end = maxBytes
+			for end < len(rest) && !utf8.RuneStart(rest[end]) {
+				end++
+			}
+		}
+	}
+	return rest[:end], offset + end
+}
+
+// GetPullRequestDiff はPull Requestのunified diffを取得します
+func GetPullRequestDiff(ctx context.Context, options GetPullRequestDiffOptions, token string) (*PullRequestDiff, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してdiffを取得
+	raw, _, err := client.PullRequests.GetRaw(ctx, options.Owner, options.Repo, options.PullNumber, github.RawOptions{Type: github.Diff})
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	// ファイルで絞り込む
+	result := &PullRequestDiff{Files: []string{}}
+	var diff strings.Builder
+	for _, section := range splitDiff(raw) {
+		if len(options.Files) > 0 && !matchesAny(section.paths, options.Files) {
+			continue
+		}
+		diff.WriteString(section.text)
+		if len(section.paths) > 0 {
+			result.Files = append(result.Files, section.paths[len(section.paths)-1])
+		}
+	}
+	full := diff.String()
+	result.TotalBytes = len(full)
+
+	// カーソルの位置から切り詰める
+	offset := 0
+	if options.Cursor != "" {
+		cursor, _ := decodeDiffCursor(options.Cursor)
+		if cursor.Hash != diffHash(full) || cursor.Offset > len(full) {
+			return nil, &common.GitHubConflictError{GitHubError: common.GitHubError{
+				Message: "the diff has changed since the cursor was returned; fetch it again without cursor",
+				Status:  http.StatusConflict,
+			}}
+		}
+		offset = cursor.Offset
+	}
+	maxBytes := options.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = DefaultDiffBytes
+	}
+	chunk, next := truncateDiff(full, offset, maxBytes)
+	result.Diff = chunk
+	if next > 0 {
+		result.Truncated = true
+		result.NextCursor = diffCursor{Offset: next, Hash: diffHash(full)}.encode()
+	}
+	return result, nil
+}
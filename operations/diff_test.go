@@ -0,0 +1,81 @@
+package operations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDiff = `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1,1 +1,1 @@
+-old
++new
+diff --git a/old name.txt b/new name.txt
+similarity index 100%
+rename from old name.txt
+rename to new name.txt
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+diff --git a/gone.txt b/gone.txt
+deleted file mode 100644
+index 3333333..0000000
+--- a/gone.txt
++++ /dev/null
+@@ -1,1 +0,0 @@
+-diff --git a/not-a-header b/not-a-header
+`
+
+func TestSplitDiff(t *testing.T) {
+	sections := splitDiff(testDiff)
+	var paths [][]string
+	var joined strings.Builder
+	for _, s := range sections {
+		paths = append(paths, s.paths)
+		joined.WriteString(s.text)
+	}
+	want := [][]string{
+		{"a.go", "a.go"},
+		{"old name.txt", "new name.txt"},
+		{"run.sh"},
+		{"gone.txt"},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+	if joined.String() != testDiff {
+		t.Error("sections do not cover the whole diff")
+	}
+	if !matchesAny(sections[1].paths, []string{"old *"}) || matchesAny(sections[0].paths, []string{"*.txt"}) {
+		t.Error("unexpected pattern match result")
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		diff      string
+		offset    int
+		maxBytes  int
+		wantChunk string
+		wantNext  int
+	}{
+		{name: "fits", diff: "a\nb\n", maxBytes: 10, wantChunk: "a\nb\n"},
+		{name: "line boundary", diff: "aa\nbb\ncc\n", maxBytes: 7, wantChunk: "aa\nbb\n", wantNext: 6},
+		{name: "from offset", diff: "aa\nbb\ncc\n", offset: 6, maxBytes: 7, wantChunk: "cc\n"},
+		{name: "long line", diff: "abcdef\n", maxBytes: 4, wantChunk: "abcd", wantNext: 4},
+		{name: "multibyte", diff: "あい\n", maxBytes: 4, wantChunk: "あ", wantNext: 3},
+		{name: "smaller than a rune", diff: "あい\n", maxBytes: 1, wantChunk: "あ", wantNext: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk, next := truncateDiff(tt.diff, tt.offset, tt.maxBytes)
+			if chunk != tt.wantChunk || next != tt.wantNext {
+				t.Errorf("truncateDiff = (%q, %d), want (%q, %d)", chunk, next, tt.wantChunk, tt.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+// mapGitHubError はgo-githubのエラーをcommon パッケージの型付きエラーに変換します
+// GitHub API以外のエラー (ネットワークエラーやコンテキストのキャンセルなど) はそのまま返します
+func mapGitHubError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// 既に変換済みのエラーはそのまま返す
+	if common.IsGitHubError(err) {
+		return err
+	}
+
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return &common.GitHubRateLimitError{
+			GitHubError: common.GitHubError{
+				Message: describeResponse(rateLimitErr.Response, rateLimitErr.Message),
+				Status:  statusCode(rateLimitErr.Response),
+			},
+			ResetAt: rateLimitErr.Rate.Reset.Time,
+		}
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		resetAt := rateLimitResetAt(abuseErr.Response)
+		if abuseErr.RetryAfter != nil {
+			resetAt = time.Now().Add(*abuseErr.RetryAfter)
+		}
+		return &common.GitHubRateLimitError{
+			GitHubError: common.GitHubError{
+				Message: describeResponse(abuseErr.Response, abuseErr.Message),
+				Status:  statusCode(abuseErr.Response),
+			},
+			ResetAt: resetAt,
+		}
+	}
+
+	var twoFactorErr *github.TwoFactorAuthError
+	if errors.As(err, &twoFactorErr) {
+		return &common.GitHubAuthenticationError{
+			GitHubError: common.GitHubError{
+				Message: describeResponse(twoFactorErr.Response, twoFactorErr.Message),
+				Status:  statusCode(twoFactorErr.Response),
+			},
+		}
+	}
+
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) {
+		return err
+	}
+
+	base := common.GitHubError{
+		Message: describeResponse(errResp.Response, errResp.Message),
+		Status:  statusCode(errResp.Response),
+	}
+
+	switch base.Status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		var details interface{}
+		if len(errResp.Errors) > 0 {
+			details = errResp.Errors
+		}
+		return &common.GitHubValidationError{GitHubError: base, Response: details}
+	case http.StatusUnauthorized:
+		return &common.GitHubAuthenticationError{GitHubError: base}
+	case http.StatusForbidden:
+		return &common.GitHubPermissionError{GitHubError: base}
+	case http.StatusNotFound:
+		return &common.GitHubResourceNotFoundError{GitHubError: base}
+	case http.StatusConflict:
+		return &common.GitHubConflictError{GitHubError: base}
+	case http.StatusTooManyRequests:
+		return &common.GitHubRateLimitError{GitHubError: base, ResetAt: rateLimitResetAt(errResp.Response)}
+	default:
+		return &base
+	}
+}
+
+// describeResponse はエラーメッセージにリクエストのメソッドとパスを付加します
+// api.github.com 以外のホスト (GitHub Enterprise Serverなど) ではホスト名も付加します
+func describeResponse(resp *http.Response, message string) string {
+	if message == "" && resp != nil {
+		message = http.StatusText(resp.StatusCode)
+	}
+	if resp == nil || resp.Request == nil || resp.Request.URL == nil {
+		return message
+	}
+	target := resp.Request.URL.Path
+	if host := resp.Request.URL.Host; host != "api.github.com" {
+		target = host + target
+	}
+	return fmt.Sprintf("%s %s: %s", resp.Request.Method, target, message)
+}
+
+// statusCode はレスポンスのHTTPステータスコードを返します
+func statusCode(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// rateLimitResetAt はRetry-AfterまたはX-RateLimit-Resetヘッダーからレート制限の解除時刻を求めます
+func rateLimitResetAt(resp *http.Response) time.Time {
+	if resp == nil {
+		return time.Time{}
+	}
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+		if epoch, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(epoch, 0)
+		}
+	}
+	return time.Time{}
+}
@@ -1,32 +1,92 @@
 package operations
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
 )
 
 // FileContent はGitHubファイルの内容を表します
+// パスがディレクトリの場合、Typeは "dir" となり、Entriesに直下のエントリ一覧が設定されます
 type FileContent struct {
-	Type        string `json:"type"`
-	Encoding    string `json:"encoding,omitempty"`
-	Size        int    `json:"size"`
-	Name        string `json:"name"`
-	Path        string `json:"path"`
-	Content     string `json:"content,omitempty"`
-	SHA         string `json:"sha"`
-	URL         string `json:"url"`
-	HTMLURL     string `json:"html_url"`
-	DownloadURL string `json:"download_url,omitempty"`
+	Type string `json:"type"`
+	// Encoding はContentの形式です (テキストは utf-8、バイナリは base64)
+	Encoding string `json:"encoding,omitempty"`
+	// MIMEType はファイル名と内容から推定したMIMEタイプです
+	MIMEType    string           `json:"mime_type,omitempty"`
+	Size        int              `json:"size"`
+	Name        string           `json:"name"`
+	Path        string           `json:"path"`
+	Content     string           `json:"content,omitempty"`
+	SHA         string           `json:"sha,omitempty"`
+	URL         string           `json:"url,omitempty"`
+	HTMLURL     string           `json:"html_url,omitempty"`
+	DownloadURL string           `json:"download_url,omitempty"`
+	Entries     []DirectoryEntry `json:"entries,omitempty"`
 }
 
+// IsBinary はContentがbase64でエンコードされたバイナリかどうかを判断します
+func (c *FileContent) IsBinary() bool {
+	return c.Encoding == ContentEncodingBase64
+}
+
+// DirectoryEntry はディレクトリ内のエントリを表します
+type DirectoryEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int    `json:"size"`
+	SHA  string `json:"sha"`
+}
+
+// FileContent.Encoding の値
+const (
+	ContentEncodingText   = "utf-8"
+	ContentEncodingBase64 = "base64"
+)
+
+// ファイル操作の種類
+const (
+	// FileOperationUpsert はファイルが存在すれば更新し、存在しなければ追加します
+	FileOperationUpsert = ""
+	FileOperationAdd    = "add"
+	FileOperationUpdate = "update"
+	FileOperationDelete = "delete"
+	FileOperationRename = "rename"
+)
+
+// Gitのファイルモード
+const (
+	FileModeFile       = "100644"
+	FileModeExecutable = "100755"
+	FileModeSymlink    = "120000"
+)
+
 // FileOperation はファイル操作のタイプを表します
 type FileOperation struct {
-	Path    string `json:"path"`
-	Content string `json:"content"`
-	SHA     string `json:"sha,omitempty"`
+	Path string `json:"path"`
+	// Content はファイルの内容です (nilの場合は指定なし)
+	// renameで指定しない場合は移動元の内容をそのまま使用し、空文字列を指定すると空のファイルになります
+	Content *string `json:"content,omitempty"`
+	// SHA を指定すると、ベースツリー上のblob SHAと一致しない場合に競合エラーとします
+	SHA string `json:"sha,omitempty"`
+	// Operation は add, update, delete, rename のいずれかです (省略時は追加または更新)
+	Operation string `json:"operation,omitempty"`
+	// Mode はファイルモード (100644, 100755, 120000) またはその別名 (file, executable, symlink) です
+	Mode string `json:"mode,omitempty"`
+	// PreviousPath はrenameの移動元パスです
+	PreviousPath string `json:"previous_path,omitempty"`
 }
 
 // GetFileContentOptions はファイル取得オプションを表します
@@ -37,6 +97,31 @@ type GetFileContentOptions struct {
 	Branch string `json:"branch,omitempty"`
 }
 
+// GetRepositoryTreeOptions はファイルツリー取得オプションを表します
+type GetRepositoryTreeOptions struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	// Ref はブランチ名、タグ名またはコミットSHAです (省略時はデフォルトブランチ)
+	Ref string `json:"ref,omitempty"`
+}
+
+// TreeEntry はファイルツリーのエントリを表します
+type TreeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Mode string `json:"mode"`
+	SHA  string `json:"sha"`
+	Size int    `json:"size,omitempty"`
+}
+
+// RepositoryTree はリポジトリのファイルツリーを表します
+type RepositoryTree struct {
+	SHA string `json:"sha"`
+	// Truncated はGitHub APIの上限によりエントリが省略されたかどうかを表します
+	Truncated bool        `json:"truncated"`
+	Entries   []TreeEntry `json:"entries"`
+}
+
 // CreateOrUpdateFileOptions はファイル作成・更新オプションを表します
 type CreateOrUpdateFileOptions struct {
 	Owner   string `json:"owner"`
@@ -69,10 +154,13 @@ type CommitResult struct {
 	Message string `json:"message"`
 }
 
-// GetFileContents はファイルの内容を取得します
-func GetFileContents(options GetFileContentOptions, token string) (*FileContent, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
+// GetFileContents はファイルの内容またはディレクトリのエントリ一覧を取得します
+// バイナリファイルはbase64で返し、Contents APIが内容を返さない1MBを超えるファイルはGit Blobs APIで取得します
+func GetFileContents(ctx context.Context, options GetFileContentOptions, token string) (*FileContent, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
 
 	// ファイル取得オプションの設定
 	opts := &github.RepositoryContentGetOptions{}
@@ -81,7 +169,7 @@ func GetFileContents(options GetFileContentOptions, token string) (*FileContent,
 	}
 
 	// GitHub APIを呼び出してファイル内容を取得
-	fileContent, _, _, err := client.Repositories.GetContents(
+	fileContent, directoryContent, _, err := client.Repositories.GetContents(
 		ctx,
 		options.Owner,
 		options.Repo,
@@ -89,34 +177,150 @@ func GetFileContents(options GetFileContentOptions, token string) (*FileContent,
 		opts,
 	)
 	if err != nil {
-		return nil, err
+		return nil, mapGitHubError(err)
+	}
+
+	// パスがディレクトリの場合はエントリ一覧を返す
+	if fileContent == nil {
+		entries := make([]DirectoryEntry, 0, len(directoryContent))
+		for _, entry := range directoryContent {
+			entries = append(entries, DirectoryEntry{
+				Name: entry.GetName(),
+				Path: entry.GetPath(),
+				Type: entry.GetType(),
+				Size: entry.GetSize(),
+				SHA:  entry.GetSHA(),
+			})
+		}
+		dirPath := strings.Trim(options.Path, "/")
+		return &FileContent{
+			Type:    "dir",
+			Name:    path.Base(dirPath),
+			Path:    dirPath,
+			Entries: entries,
+		}, nil
 	}
 
-	// content, err := fileContent.GetContent()
-	content, decodeErr := fileContent.GetContent()
-	if decodeErr != nil {
-		return nil, fmt.Errorf("ファイル内容のデコードに失敗: %v", decodeErr)
+	data, err := fileContentData(ctx, client, options, fileContent)
+	if err != nil {
+		return nil, err
 	}
 
 	// 結果をマッピング
-	return &FileContent{
+	result := &FileContent{
 		Type:        fileContent.GetType(),
-		Encoding:    fileContent.GetEncoding(),
 		Size:        fileContent.GetSize(),
 		Name:        fileContent.GetName(),
 		Path:        fileContent.GetPath(),
-		Content:     content,
 		SHA:         fileContent.GetSHA(),
 		URL:         fileContent.GetURL(),
 		HTMLURL:     fileContent.GetHTMLURL(),
 		DownloadURL: fileContent.GetDownloadURL(),
+	}
+
+	mimeType, binary := detectContentType(result.Name, data)
+	result.MIMEType = mimeType
+	if binary {
+		result.Encoding = ContentEncodingBase64
+		result.Content = base64.StdEncoding.EncodeToString(data)
+	} else {
+		result.Encoding = ContentEncodingText
+		result.Content = string(data)
+	}
+	return result, nil
+}
+
+// fileContentData はファイルの内容をデコードして返します
+// 1MBを超えるファイルではContents APIのencodingが "none" となるため、Git Blobs APIで取得します
+func fileContentData(ctx context.Context, client *github.Client, options GetFileContentOptions, fileContent *github.RepositoryContent) ([]byte, error) {
+	if fileContent.GetEncoding() != "none" {
+		content, err := fileContent.GetContent()
+		if err != nil {
+			return nil, fmt.Errorf("ファイル内容のデコードに失敗: %v", err)
+		}
+		return []byte(content), nil
+	}
+
+	blob, _, err := client.Git.GetBlob(ctx, options.Owner, options.Repo, fileContent.GetSHA())
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+	if blob.GetEncoding() != "base64" {
+		return []byte(blob.GetContent()), nil
+	}
+	// GitHubはbase64を60文字ごとに改行して返す
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(blob.GetContent(), "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("blobのデコードに失敗: %v", err)
+	}
+	return data, nil
+}
+
+// detectContentType はファイル名と内容からMIMEタイプを推定し、バイナリかどうかを判断します
+// UTF-8として不正な内容やNULバイトを含む内容はバイナリとして扱います
+func detectContentType(name string, data []byte) (string, bool) {
+	binary := !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0
+	byExtension := mime.TypeByExtension(path.Ext(name))
+
+	if !binary {
+		if byExtension != "" {
+			return byExtension, false
+		}
+		return "text/plain; charset=utf-8", false
+	}
+
+	// バイナリは内容から判定した結果を優先する (拡張子と内容が一致しない場合に備える)
+	if sniffed := http.DetectContentType(data); sniffed != "application/octet-stream" && !strings.HasPrefix(sniffed, "text/") {
+		return sniffed, true
+	}
+	if byExtension != "" && !strings.HasPrefix(byExtension, "text/") {
+		return byExtension, true
+	}
+	return "application/octet-stream", true
+}
+
+// GetRepositoryTree はリポジトリのファイルツリーを再帰的に取得します
+func GetRepositoryTree(ctx context.Context, options GetRepositoryTreeOptions, token string) (*RepositoryTree, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ref := options.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// ツリーAPIはツリーSHAの代わりにブランチ名やコミットSHAも受け付ける
+	tree, _, err := client.Git.GetTree(ctx, options.Owner, options.Repo, ref, true)
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	entries := make([]TreeEntry, 0, len(tree.Entries))
+	for _, entry := range tree.Entries {
+		entries = append(entries, TreeEntry{
+			Path: entry.GetPath(),
+			Type: entry.GetType(),
+			Mode: entry.GetMode(),
+			SHA:  entry.GetSHA(),
+			Size: entry.GetSize(),
+		})
+	}
+
+	return &RepositoryTree{
+		SHA:       tree.GetSHA(),
+		Truncated: tree.GetTruncated(),
+		Entries:   entries,
 	}, nil
 }
 
 // CreateOrUpdateFile はファイルを作成または更新します
-func CreateOrUpdateFile(options CreateOrUpdateFileOptions, token string) (*CommitResult, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
+func CreateOrUpdateFile(ctx context.Context, options CreateOrUpdateFileOptions, token string) (*CommitResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
 
 	// ファイル作成・更新リクエストの設定
 	opts := &github.RepositoryContentFileOptions{
@@ -141,7 +345,7 @@ func CreateOrUpdateFile(options CreateOrUpdateFileOptions, token string) (*Commi
 		opts,
 	)
 	if err != nil {
-		return nil, err
+		return nil, mapGitHubError(err)
 	}
 
 	// 結果をマッピング
@@ -164,68 +368,103 @@ func CreateOrUpdateFile(options CreateOrUpdateFileOptions, token string) (*Commi
 }
 
 // PushFiles は複数のファイルを一度にプッシュします
-func PushFiles(options PushFilesOptions, token string) (*CommitResult, error) {
+// ブランチの先頭コミットを親とする1つのコミットを作成し、ブランチが途中で更新されていた場合は競合エラーを返します
+func PushFiles(ctx context.Context, options PushFilesOptions, token string) (*CommitResult, error) {
 	// 注意: GitHub APIは一度に複数ファイルを更新する直接的なエンドポイントを提供していません
-	// そのため、このような実装が必要になります
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
+	// そのため、Git Data APIでツリーとコミットを作成してからブランチを更新します
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	branchRef := "refs/heads/" + options.Branch
 
 	// 現在のブランチの最新コミットSHAを取得
-	ref, _, err := client.Git.GetRef(ctx, options.Owner, options.Repo, "refs/heads/"+options.Branch)
+	ref, _, err := client.Git.GetRef(ctx, options.Owner, options.Repo, branchRef)
 	if err != nil {
-		return nil, fmt.Errorf("ブランチの取得に失敗: %v", err)
+		return nil, fmt.Errorf("ブランチの取得に失敗: %w", mapGitHubError(err))
 	}
-	baseTreeSHA := ref.Object.GetSHA()
+	headSHA := ref.Object.GetSHA()
 
 	// ベースとなるツリーを取得
-	baseCommit, _, err := client.Git.GetCommit(ctx, options.Owner, options.Repo, baseTreeSHA)
+	baseCommit, _, err := client.Git.GetCommit(ctx, options.Owner, options.Repo, headSHA)
 	if err != nil {
-		return nil, fmt.Errorf("コミットの取得に失敗: %v", err)
+		return nil, fmt.Errorf("コミットの取得に失敗: %w", mapGitHubError(err))
+	}
+	baseTreeSHA := baseCommit.Tree.GetSHA()
+
+	// 既存ファイルの確認とモードの引き継ぎのためにベースツリーを読み込む
+	base, err := loadBaseTree(ctx, client, options.Owner, options.Repo, headSHA, baseTreeSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同じパスへの複数の操作は結果が順序に依存するため拒否する
+	if err := checkDuplicatePaths(options.Files); err != nil {
+		return nil, err
 	}
-	baseTreeSHA = baseCommit.Tree.GetSHA()
 
 	// 新しいツリーのエントリを作成
 	entries := make([]*github.TreeEntry, 0, len(options.Files))
 	for _, file := range options.Files {
-		// ファイル内容をBase64からデコード (必要な場合)
-		content := file.Content
-		mode := "100644" // 通常ファイルのモード
-		entryType := "blob"
-
-		entry := &github.TreeEntry{
-			Path:    github.String(file.Path),
-			Mode:    github.String(mode),
-			Type:    github.String(entryType),
-			Content: github.String(content),
+		fileEntries, err := buildTreeEntries(ctx, base, file)
+		if err != nil {
+			return nil, err
 		}
-		entries = append(entries, entry)
+		entries = append(entries, fileEntries...)
+	}
+
+	// 書き込みを始める前にキャンセルされていないか確認
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// 新しいツリーを作成
 	newTree, _, err := client.Git.CreateTree(ctx, options.Owner, options.Repo, baseTreeSHA, entries)
 	if err != nil {
-		return nil, fmt.Errorf("ツリーの作成に失敗: %v", err)
+		return nil, fmt.Errorf("ツリーの作成に失敗: %w", mapGitHubError(err))
 	}
 
-	// 新しいコミットを作成
+	// ブランチの先頭コミットを親として新しいコミットを作成
 	newCommit, _, err := client.Git.CreateCommit(ctx, options.Owner, options.Repo, &github.Commit{
-		Message: github.String(options.Message),
-		Tree:    newTree,
-		Parents: []*github.Commit{{SHA: github.String(baseTreeSHA)}},
+		Message: github.Ptr(options.Message),
+		Tree:    &github.Tree{SHA: newTree.SHA},
+		Parents: []*github.Commit{{SHA: github.Ptr(headSHA)}},
 	}, nil)
 	if err != nil {
-		return nil, fmt.Errorf("コミットの作成に失敗: %v", err)
+		return nil, fmt.Errorf("コミットの作成に失敗: %w", mapGitHubError(err))
 	}
 
-	// リファレンスを更新
+	// ブランチを更新する前にキャンセルされていないか確認
+	// ここで中断すれば作成済みのツリーとコミットは参照されずに残るだけで、ブランチは変更されません
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// ブランチが読み込み後に移動していないか確認
+	// 祖先への巻き戻しはfast-forwardの検査では検出できないため、ここで明示的に比較します
+	current, _, err := client.Git.GetRef(ctx, options.Owner, options.Repo, branchRef)
+	if err != nil {
+		return nil, fmt.Errorf("ブランチの取得に失敗: %w", mapGitHubError(err))
+	}
+	if current.Object.GetSHA() != headSHA {
+		return nil, newBranchMovedError(options.Branch, headSHA, current.Object.GetSHA())
+	}
+
+	// リファレンスを更新 (強制更新はしないため、並行して更新された場合はGitHubが拒否します)
 	_, _, err = client.Git.UpdateRef(ctx, options.Owner, options.Repo, &github.Reference{
-		Ref: github.String("refs/heads/" + options.Branch),
+		Ref: github.Ptr(branchRef),
 		Object: &github.GitObject{
 			SHA: newCommit.SHA,
 		},
 	}, false)
 	if err != nil {
-		return nil, fmt.Errorf("リファレンスの更新に失敗: %v", err)
+		mapped := mapGitHubError(err)
+		var validationErr *common.GitHubValidationError
+		if errors.As(mapped, &validationErr) && strings.Contains(validationErr.Message, "fast forward") {
+			// "Update is not a fast forward" はブランチが並行して更新されたことを意味します
+			return nil, newBranchMovedError(options.Branch, headSHA, "")
+		}
+		return nil, fmt.Errorf("リファレンスの更新に失敗: %w", mapped)
 	}
 
 	// 結果をマッピング
@@ -246,3 +485,288 @@ func PushFiles(options PushFilesOptions, token string) (*CommitResult, error) {
 
 	return result, nil
 }
+
+// baseTree はプッシュ先ブランチの先頭コミットのツリーを表します
+type baseTree struct {
+	client  *github.Client
+	owner   string
+	repo    string
+	headSHA string
+	// entries はパスごとのblobエントリです
+	entries map[string]*github.TreeEntry
+	// truncated はツリーが大きすぎて一覧が途中で切り詰められたかどうかです
+	truncated bool
+}
+
+// loadBaseTree はベースツリーを再帰的に取得します
+func loadBaseTree(ctx context.Context, client *github.Client, owner, repo, headSHA, treeSHA string) (*baseTree, error) {
+	tree, _, err := client.Git.GetTree(ctx, owner, repo, treeSHA, true)
+	if err != nil {
+		return nil, fmt.Errorf("ツリーの取得に失敗: %w", mapGitHubError(err))
+	}
+
+	base := &baseTree{
+		client:    client,
+		owner:     owner,
+		repo:      repo,
+		headSHA:   headSHA,
+		entries:   make(map[string]*github.TreeEntry, len(tree.Entries)),
+		truncated: tree.GetTruncated(),
+	}
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			base.entries[entry.GetPath()] = entry
+		}
+	}
+	return base, nil
+}
+
+// lookup はベースツリーから指定されたパスのエントリを取得します
+// 存在しない場合はnilを返します
+func (b *baseTree) lookup(ctx context.Context, path string) (*github.TreeEntry, error) {
+	if entry, ok := b.entries[path]; ok || !b.truncated {
+		return entry, nil
+	}
+
+	// ツリーが切り詰められている場合はContents APIで個別に確認する
+	content, _, _, err := b.client.Repositories.GetContents(ctx, b.owner, b.repo, path, &github.RepositoryContentGetOptions{Ref: b.headSHA})
+	if err != nil {
+		mapped := mapGitHubError(err)
+		var notFound *common.GitHubResourceNotFoundError
+		if errors.As(mapped, &notFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ファイルの取得に失敗: %w", mapped)
+	}
+	if content == nil || content.GetType() != "file" && content.GetType() != "symlink" {
+		return nil, nil
+	}
+
+	mode := FileModeFile
+	if content.GetType() == "symlink" {
+		mode = FileModeSymlink
+	}
+	entry := &github.TreeEntry{
+		Path: github.Ptr(path),
+		Mode: github.Ptr(mode),
+		Type: github.Ptr("blob"),
+		SHA:  content.SHA,
+	}
+	b.entries[path] = entry
+	return entry, nil
+}
+
+// buildTreeEntries は1つのファイル操作を新しいツリーのエントリに変換します
+func buildTreeEntries(ctx context.Context, base *baseTree, file FileOperation) ([]*github.TreeEntry, error) {
+	if file.Path == "" {
+		return nil, newFileOperationError(file, "path is required")
+	}
+
+	mode, err := normalizeFileMode(file.Mode)
+	if err != nil {
+		return nil, newFileOperationError(file, err.Error())
+	}
+
+	switch file.Operation {
+	case FileOperationUpsert, FileOperationAdd, FileOperationUpdate:
+		existing, err := base.lookup(ctx, file.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case file.Operation == FileOperationAdd && existing != nil:
+			return nil, newFileConflictError(file, "file already exists")
+		case file.Operation == FileOperationUpdate && existing == nil:
+			return nil, newFileNotFoundError(file)
+		case file.SHA != "" && existing == nil:
+			return nil, newFileConflictError(file, fmt.Sprintf("expected sha %s but the file does not exist", file.SHA))
+		}
+		if err := verifySHA(file, existing); err != nil {
+			return nil, err
+		}
+
+		if file.Content == nil {
+			return nil, newFileOperationError(file, "content is required")
+		}
+
+		// モードが指定されていない場合は既存ファイルのモードを引き継ぐ
+		if mode == "" && existing != nil {
+			mode = existing.GetMode()
+		}
+		if mode == "" {
+			mode = FileModeFile
+		}
+
+		return []*github.TreeEntry{{
+			Path:    github.Ptr(file.Path),
+			Mode:    github.Ptr(mode),
+			Type:    github.Ptr("blob"),
+			Content: file.Content,
+		}}, nil
+
+	case FileOperationDelete:
+		existing, err := base.lookup(ctx, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, newFileNotFoundError(file)
+		}
+		if err := verifySHA(file, existing); err != nil {
+			return nil, err
+		}
+
+		// SHAとContentを指定しないエントリはファイルの削除を意味します
+		return []*github.TreeEntry{{
+			Path: github.Ptr(file.Path),
+			Mode: github.Ptr(existing.GetMode()),
+			Type: github.Ptr("blob"),
+		}}, nil
+
+	case FileOperationRename:
+		if file.PreviousPath == "" {
+			return nil, newFileOperationError(file, "previous_path is required for rename")
+		}
+		if file.PreviousPath == file.Path {
+			return nil, newFileOperationError(file, "previous_path must differ from path")
+		}
+		existing, err := base.lookup(ctx, file.PreviousPath)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, newFileNotFoundError(FileOperation{Path: file.PreviousPath, Operation: file.Operation})
+		}
+		if err := verifySHA(file, existing); err != nil {
+			return nil, err
+		}
+
+		// 移動先に既存のファイルがある場合は上書きせずに競合として扱う
+		destination, err := base.lookup(ctx, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if destination != nil {
+			return nil, newFileConflictError(file, "destination file already exists")
+		}
+		if mode == "" {
+			mode = existing.GetMode()
+		}
+
+		// 内容が指定されていなければ既存のblobをそのまま新しいパスに移動する
+		added := &github.TreeEntry{
+			Path: github.Ptr(file.Path),
+			Mode: github.Ptr(mode),
+			Type: github.Ptr("blob"),
+		}
+		if file.Content != nil {
+			added.Content = file.Content
+		} else {
+			added.SHA = existing.SHA
+		}
+
+		return []*github.TreeEntry{
+			{
+				Path: github.Ptr(file.PreviousPath),
+				Mode: github.Ptr(existing.GetMode()),
+				Type: github.Ptr("blob"),
+			},
+			added,
+		}, nil
+
+	default:
+		return nil, newFileOperationError(file, fmt.Sprintf("unknown operation %q (add, update, delete, rename)", file.Operation))
+	}
+}
+
+// checkDuplicatePaths は1回のプッシュで同じパスが複数の操作の対象になっていないか確認します
+// renameは移動元と移動先の両方のパスを対象とします
+func checkDuplicatePaths(files []FileOperation) error {
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		paths := []string{file.Path}
+		if file.Operation == FileOperationRename && file.PreviousPath != "" {
+			paths = append(paths, file.PreviousPath)
+		}
+		for _, p := range paths {
+			if p == "" {
+				continue
+			}
+			if seen[p] {
+				return newFileOperationError(FileOperation{Path: p}, "multiple operations on the same path in one push")
+			}
+			seen[p] = true
+		}
+	}
+	return nil
+}
+
+// normalizeFileMode はモードの別名をGitのファイルモードに変換します
+func normalizeFileMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return "", nil
+	case FileModeFile, "file":
+		return FileModeFile, nil
+	case FileModeExecutable, "executable":
+		return FileModeExecutable, nil
+	case FileModeSymlink, "symlink":
+		return FileModeSymlink, nil
+	default:
+		return "", fmt.Errorf("unsupported mode %q (100644, 100755, 120000)", mode)
+	}
+}
+
+// verifySHA は指定されたSHAがベースツリーのblob SHAと一致するか検証します
+func verifySHA(file FileOperation, existing *github.TreeEntry) error {
+	if file.SHA == "" || existing == nil || existing.GetSHA() == file.SHA {
+		return nil
+	}
+	return newFileConflictError(file, fmt.Sprintf("sha mismatch: expected %s but the branch has %s", file.SHA, existing.GetSHA()))
+}
+
+// newFileOperationError はファイル操作の指定が不正な場合のエラーを作成します
+func newFileOperationError(file FileOperation, message string) error {
+	return &common.GitHubValidationError{
+		GitHubError: common.GitHubError{
+			Message: fmt.Sprintf("%s: %s", file.Path, message),
+			Status:  http.StatusUnprocessableEntity,
+		},
+	}
+}
+
+// newFileNotFoundError は更新・削除対象のファイルが存在しない場合のエラーを作成します
+func newFileNotFoundError(file FileOperation) error {
+	return &common.GitHubResourceNotFoundError{
+		GitHubError: common.GitHubError{
+			Message: fmt.Sprintf("%s: file does not exist on the branch (operation %s)", file.Path, file.Operation),
+			Status:  http.StatusNotFound,
+		},
+	}
+}
+
+// newFileConflictError はファイルの状態が期待と異なる場合のエラーを作成します
+func newFileConflictError(file FileOperation, message string) error {
+	return &common.GitHubConflictError{
+		GitHubError: common.GitHubError{
+			Message: fmt.Sprintf("%s: %s", file.Path, message),
+			Status:  http.StatusConflict,
+		},
+	}
+}
+
+// newBranchMovedError はプッシュ中にブランチが更新された場合のエラーを作成します
+func newBranchMovedError(branch, expectedSHA, actualSHA string) error {
+	message := fmt.Sprintf("branch %s was updated while pushing (expected head %s", branch, expectedSHA)
+	if actualSHA != "" {
+		message += fmt.Sprintf(", now %s", actualSHA)
+	}
+	message += "); fetch the latest state and retry"
+	return &common.GitHubConflictError{
+		GitHubError: common.GitHubError{
+			Message: message,
+			Status:  http.StatusConflict,
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+// graphQLError はGraphQL APIが返すエラーを表します
+type graphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// graphQLURL はREST APIのベースURLに対応するGraphQL APIのURLを返します
+// GitHub Enterprise Serverでは /api/v3/ ではなく /api/graphql を使用します
+func graphQLURL(base *url.URL) string {
+	u := *base
+	if strings.HasSuffix(u.Path, "/api/v3/") {
+		u.Path = strings.TrimSuffix(u.Path, "v3/") + "graphql"
+	} else {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/graphql"
+	}
+	return u.String()
+}
+
+// graphQL はGitHub GraphQL APIを呼び出し、レスポンスの data を result にデコードします
+// REST APIで操作できない機能 (ドラフトの切り替えや自動マージなど) に使用します
+func graphQL(ctx context.Context, client *github.Client, query string, variables map[string]interface{}, result interface{}) error {
+	req, err := client.NewRequest(http.MethodPost, graphQLURL(client.BaseURL), map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	if _, err := client.Do(ctx, req, &body); err != nil {
+		return mapGitHubError(err)
+	}
+
+	// GraphQL APIはエラーでも200を返すため、errors の内容から型付きエラーに変換する
+	if len(body.Errors) > 0 {
+		return mapGraphQLError(body.Errors[0])
+	}
+	if result == nil || len(body.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body.Data, result)
+}
+
+// mapGraphQLError はGraphQL APIのエラーをcommon パッケージの型付きエラーに変換します
+func mapGraphQLError(e graphQLError) error {
+	base := common.GitHubError{Message: fmt.Sprintf("POST graphql: %s", e.Message)}
+	switch e.Type {
+	case "NOT_FOUND":
+		base.Status = http.StatusNotFound
+		return &common.GitHubResourceNotFoundError{GitHubError: base}
+	case "FORBIDDEN":
+		base.Status = http.StatusForbidden
+		return &common.GitHubPermissionError{GitHubError: base}
+	default:
+		base.Status = http.StatusUnprocessableEntity
+		return &common.GitHubValidationError{GitHubError: base}
+	}
+}
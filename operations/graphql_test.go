@@ -0,0 +1,40 @@
+package operations
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+func TestGraphQLURL(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/":                "https://api.github.com/graphql",
+		"https://github.example.com/api/v3/":     "https://github.example.com/api/graphql",
+		"http://127.0.0.1:8080/prefix/":          "http://127.0.0.1:8080/prefix/graphql",
+		"https://github.example.com/ghe/api/v3/": "https://github.example.com/ghe/api/graphql",
+	}
+	for base, want := range tests {
+		u, err := url.Parse(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := graphQLURL(u); got != want {
+			t.Errorf("graphQLURL(%q) = %q, want %q", base, got, want)
+		}
+	}
+}
+
+func TestMapGraphQLError(t *testing.T) {
+	tests := map[string]string{
+		"NOT_FOUND":     common.ErrorCodeNotFound,
+		"FORBIDDEN":     common.ErrorCodePermission,
+		"UNPROCESSABLE": common.ErrorCodeValidation,
+		"":              common.ErrorCodeValidation,
+	}
+	for errorType, want := range tests {
+		if got := common.ErrorCode(mapGraphQLError(graphQLError{Type: errorType, Message: "boom"})); got != want {
+			t.Errorf("%q: error code = %q, want %q", errorType, got, want)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package operations
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHTTPCacheSize はHTTPキャッシュに保存するレスポンスの既定の合計サイズです
+const DefaultHTTPCacheSize = 64 << 20
+
+// CacheBackend はHTTPキャッシュのレスポンスを保存する場所を表します
+// 実装は複数のゴルーチンから同時に呼び出されても安全である必要があります
+type CacheBackend interface {
+	// Get はキーに対応するレスポンスを返します
+	Get(key string) ([]byte, bool)
+	// Set はキーに対応するレスポンスを保存します
+	Set(key string, value []byte)
+	// Delete はキーに対応するレスポンスを削除します
+	Delete(key string)
+}
+
+// CachingTransport はGETリクエストのレスポンスをETagとLast-Modifiedで再検証するHTTPトランスポートです
+//
+// ETagまたはLast-Modifiedを含む200のレスポンスを、URL・認証情報・Acceptヘッダーごとに保存します。
+// 同じリクエストを再び送信するときは If-None-Match と If-Modified-Since を付けて送信し、
+// 304が返された場合は保存したレスポンスを返します。GitHub APIでは304のレスポンスは
+// レート制限の消費に数えられません。
+type CachingTransport struct {
+	// Base は実際にリクエストを送信するトランスポートです (nilの場合は http.DefaultTransport)
+	Base http.RoundTripper
+	// Cache はレスポンスの保存先です
+	Cache CacheBackend
+	// MaxEntrySize は保存するレスポンスの最大サイズです (0以下の場合は DefaultHTTPCacheSize の1/8)
+	MaxEntrySize int64
+}
+
+// NewCachingTransport は指定した保存先を使用するCachingTransportを作成します
+func NewCachingTransport(base http.RoundTripper, cache CacheBackend) *CachingTransport {
+	return &CachingTransport{Base: base, Cache: cache}
+}
+
+// RoundTrip はリクエストを送信し、キャッシュしたレスポンスを再検証します
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return base.RoundTrip(req)
+	}
+
+	key := httpCacheKey(req)
+	cached := t.cachedResponse(key, req)
+	if cached != nil && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		// RoundTripperはリクエストを変更してはならないため、複製してから条件を付ける
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := cached.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		// 304のヘッダー (ETagやレート制限の残り回数など) で保存したヘッダーを更新する
+		for name, values := range resp.Header {
+			if name != "Content-Length" {
+				cached.Header[name] = values
+			}
+		}
+		cached.Header.Set("X-From-Cache", "1")
+		cached.Request = req
+		return cached, nil
+	}
+	if cached != nil {
+		cached.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK || !isCacheable(resp) {
+		return resp, nil
+	}
+	return t.store(key, resp), nil
+}
+
+// cachedResponse は保存したレスポンスを返します (ない場合や読み取れない場合はnil)
+func (t *CachingTransport) cachedResponse(key string, req *http.Request) *http.Response {
+	data, ok := t.Cache.Get(key)
+	if !ok {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		t.Cache.Delete(key)
+		return nil
+	}
+	return resp
+}
+
+// store はレスポンスを保存し、本文を読み直せるように置き換えたレスポンスを返します
+// 本文がMaxEntrySizeを超える場合は保存しません
+func (t *CachingTransport) store(key string, resp *http.Response) *http.Response {
+	limit := t.MaxEntrySize
+	if limit <= 0 {
+		limit = DefaultHTTPCacheSize / 8
+	}
+	if resp.ContentLength > limit {
+		return resp
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil || int64(len(body)) > limit {
+		// 読み取った部分と残りを連結して、呼び出し元がそのまま読めるようにする
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp
+	}
+	resp.Body.Close()
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if data, err := httputil.DumpResponse(resp, true); err == nil {
+		t.Cache.Set(key, data)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp
+}
+
+// isCacheable はレスポンスを再検証のために保存できるかどうかを判断します
+func isCacheable(resp *http.Response) bool {
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return false
+	}
+	return resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// httpCacheKey はリクエストのキャッシュキーを返します
+// 認証情報ごとに見えるリソースが異なるため、Authorizationヘッダーのハッシュをキーに含めます
+func httpCacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		req.URL.String(),
+		req.Header.Get("Authorization"),
+		req.Header.Get("Accept"),
+		req.Header.Get("X-GitHub-Api-Version"),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryCache はレスポンスをメモリに保存するCacheBackendです
+// 合計サイズが上限を超えると最も長く使用されていないものから破棄します
+type MemoryCache struct {
+	maxSize int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// memoryCacheEntry はメモリに保存されたレスポンスを表します
+type memoryCacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache は合計サイズの上限を指定してMemoryCacheを作成します
+// maxSize が0以下の場合は DefaultHTTPCacheSize を使用します
+func NewMemoryCache(maxSize int64) *MemoryCache {
+	if maxSize <= 0 {
+		maxSize = DefaultHTTPCacheSize
+	}
+	return &MemoryCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get はキーに対応するレスポンスを返します
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*memoryCacheEntry).value, true
+}
+
+// Set はキーに対応するレスポンスを保存します
+func (c *MemoryCache) Set(key string, value []byte) {
+	if int64(len(value)) > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	c.entries[key] = c.order.PushFront(&memoryCacheEntry{key: key, value: value})
+	c.size += int64(len(value))
+	for c.size > c.maxSize {
+		c.remove(c.order.Back().Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete はキーに対応するレスポンスを削除します
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+// Size は保存されているレスポンスの合計サイズを返します
+func (c *MemoryCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// remove はキーに対応するエントリを削除します (ロック取得済みで呼び出すこと)
+func (c *MemoryCache) remove(key string) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(elem)
+	delete(c.entries, key)
+	c.size -= int64(len(elem.Value.(*memoryCacheEntry).value))
+}
+
+// DiskCache はレスポンスをディレクトリ内のファイルに保存するCacheBackendです
+// サーバーを再起動してもキャッシュを引き継げます。合計サイズが上限を超えると
+// 最終使用時刻 (ファイルの更新時刻) が最も古いものから削除します。
+type DiskCache struct {
+	dir     string
+	maxSize int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+// NewDiskCache は保存先のディレクトリと合計サイズの上限を指定してDiskCacheを作成します
+// maxSize が0以下の場合は DefaultHTTPCacheSize を使用します
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultHTTPCacheSize
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("キャッシュディレクトリ %s を作成できません: %v", dir, err)
+	}
+	c := &DiskCache{dir: dir, maxSize: maxSize}
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		c.size += f.size
+	}
+	c.mu.Lock()
+	c.evict("")
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Get はキーに対応するレスポンスを返します
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	// 最終使用時刻を更新して、よく使用されるレスポンスが削除されないようにする
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Set はキーに対応するレスポンスを保存します
+func (c *DiskCache) Set(key string, value []byte) {
+	if int64(len(value)) > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 読み取り途中のファイルを置き換えないように、一時ファイルに書き込んでから名前を変更する
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(value)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	path := c.path(key)
+	var previous int64
+	if info, err := os.Stat(path); err == nil {
+		previous = info.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	c.size += int64(len(value)) - previous
+	c.evict(path)
+}
+
+// Delete はキーに対応するレスポンスを削除します
+func (c *DiskCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(key)
+	if info, err := os.Stat(path); err == nil && os.Remove(path) == nil {
+		c.size -= info.Size()
+	}
+}
+
+// Size は保存されているレスポンスの合計サイズを返します
+func (c *DiskCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// path はキーに対応するファイルのパスを返します
+// キーは16進数のハッシュのため、そのままファイル名として使用できます
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// diskCacheFile はキャッシュディレクトリ内のファイルを表します
+type diskCacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// files はキャッシュディレクトリ内のファイルを最終使用時刻の古い順に返します
+func (c *DiskCache) files() ([]diskCacheFile, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, fmt.Errorf("キャッシュディレクトリ %s を読み取れません: %v", c.dir, err)
+	}
+	var files []diskCacheFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, diskCacheFile{path: filepath.Join(c.dir, entry.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	return files, nil
+}
+
+// evict は合計サイズが上限以下になるまで古いファイルを削除します (ロック取得済みで呼び出すこと)
+// 更新時刻の精度によらず、書き込んだばかりの keep は削除しません
+func (c *DiskCache) evict(keep string) {
+	if c.size <= c.maxSize {
+		return
+	}
+	files, err := c.files()
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if c.size <= c.maxSize {
+			return
+		}
+		if f.path == keep {
+			continue
+		}
+		if os.Remove(f.path) == nil {
+			c.size -= f.size
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// etagServer はリクエストごとの内容にETagを付けて返すテスト用サーバーです
+type etagServer struct {
+	mu          sync.Mutex
+	version     int
+	requests    int
+	notModified int
+}
+
+func (s *etagServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	etag := fmt.Sprintf(`"%s-%d"`, r.Header.Get("Authorization"), s.version)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Ratelimit-Remaining", fmt.Sprint(5000-s.requests))
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	fmt.Fprintf(w, "%s version %d", r.URL.Path, s.version)
+}
+
+func TestCachingTransport(t *testing.T) {
+	handler := &etagServer{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	cache := NewMemoryCache(0)
+	client := &http.Client{Transport: NewCachingTransport(nil, cache)}
+	get := func(path, token string) (string, *http.Response) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		req.Header.Set("Authorization", token)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), resp
+	}
+
+	body, resp := get("/a", "token-a")
+	if body != "/a version 0" || resp.Header.Get("X-From-Cache") != "" {
+		t.Fatalf("first response: %q %v", body, resp.Header)
+	}
+
+	// 2回目は条件付きリクエストを送信し、304の場合は保存したレスポンスを返す
+	body, resp = get("/a", "token-a")
+	if body != "/a version 0" || resp.StatusCode != http.StatusOK || resp.Header.Get("X-From-Cache") != "1" {
+		t.Errorf("cached response: %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+	if handler.notModified != 1 {
+		t.Errorf("notModified = %d, want 1", handler.notModified)
+	}
+	// 304のヘッダーで保存したヘッダーを更新する
+	if got := resp.Header.Get("X-Ratelimit-Remaining"); got != "4998" {
+		t.Errorf("rate limit remaining = %q, want the value from the 304 response", got)
+	}
+
+	// 別のトークンのレスポンスは共有しない
+	if body, resp = get("/a", "token-b"); resp.Header.Get("X-From-Cache") != "" || handler.notModified != 1 {
+		t.Errorf("another token should not use the cached response: %q", body)
+	}
+
+	// 内容が変わった場合は新しいレスポンスを返して保存し直す
+	handler.version++
+	if body, _ = get("/a", "token-a"); body != "/a version 1" {
+		t.Errorf("updated response = %q", body)
+	}
+	if body, resp = get("/a", "token-a"); body != "/a version 1" || resp.Header.Get("X-From-Cache") != "1" {
+		t.Errorf("updated cached response = %q", body)
+	}
+
+	// GET以外のリクエストはキャッシュしない
+	requests := handler.requests
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/a", strings.NewReader("{}"))
+	req.Header.Set("Authorization", "token-a")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-From-Cache") != "" || handler.requests != requests+1 {
+		t.Errorf("POST should not be served from cache")
+	}
+}
+
+func TestCachingTransportMaxEntrySize(t *testing.T) {
+	large := strings.Repeat("x", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"large"`)
+		w.Write([]byte(large))
+	}))
+	defer srv.Close()
+
+	cache := NewMemoryCache(0)
+	client := &http.Client{Transport: &CachingTransport{Cache: cache, MaxEntrySize: 10}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	// 保存しないレスポンスも本文はすべて読める
+	if body, _ := io.ReadAll(resp.Body); string(body) != large {
+		t.Errorf("body = %q", body)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("size = %d, large responses should not be stored", cache.Size())
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(10)
+	cache.Set("a", []byte("aaaa"))
+	cache.Set("b", []byte("bbbb"))
+	cache.Get("a")
+	cache.Set("c", []byte("cccc"))
+
+	// 上限を超えると最も長く使用されていないものから破棄する
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("least recently used entry should be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Errorf("recently used entry should be kept")
+	}
+	if cache.Size() != 8 {
+		t.Errorf("size = %d, want 8", cache.Size())
+	}
+
+	cache.Set("a", []byte("a"))
+	cache.Delete("c")
+	if cache.Size() != 1 {
+		t.Errorf("size = %d, want 1", cache.Size())
+	}
+	cache.Set("huge", []byte(strings.Repeat("x", 11)))
+	if _, ok := cache.Get("huge"); ok {
+		t.Errorf("entries larger than the cache should not be stored")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache.Set("a", []byte("aaaa"))
+	cache.Set("b", []byte("bbbb"))
+	if got, ok := cache.Get("a"); !ok || string(got) != "aaaa" {
+		t.Errorf("Get(a) = %q, %v", got, ok)
+	}
+
+	// 再作成しても保存した内容とサイズを引き継ぐ
+	cache, err = NewDiskCache(dir, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache.Size() != 8 {
+		t.Errorf("size = %d, want 8", cache.Size())
+	}
+	if got, ok := cache.Get("b"); !ok || string(got) != "bbbb" {
+		t.Errorf("Get(b) = %q, %v", got, ok)
+	}
+
+	cache.Set("b", []byte("bb"))
+	cache.Delete("a")
+	if cache.Size() != 2 {
+		t.Errorf("size = %d, want 2", cache.Size())
+	}
+	cache.Set("c", []byte("cccccccc"))
+	cache.Set("d", []byte("dd"))
+	if cache.Size() > 10 {
+		t.Errorf("size = %d, should not exceed the limit", cache.Size())
+	}
+	if _, ok := cache.Get("d"); !ok {
+		t.Errorf("newest entry should be kept")
+	}
+
+	// 上限を小さくして再作成すると古いものから削除する
+	cache, err = NewDiskCache(dir, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cache.Size() > 3 {
+		t.Errorf("size = %d, should be evicted to the new limit", cache.Size())
+	}
+}
@@ -0,0 +1,322 @@
+package operations
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+)
+
+// Issue はGitHub Issueを表します
+type Issue struct {
+	ID            int       `json:"id"`
+	Number        int       `json:"number"`
+	State         string    `json:"state"`
+	StateReason   string    `json:"state_reason,omitempty"`
+	Title         string    `json:"title"`
+	Body          string    `json:"body"`
+	User          User      `json:"user"`
+	Labels        []string  `json:"labels"`
+	Assignees     []User    `json:"assignees"`
+	Milestone     string    `json:"milestone,omitempty"`
+	Comments      int       `json:"comments"`
+	IsPullRequest bool      `json:"is_pull_request,omitempty"`
+	HTMLURL       string    `json:"html_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	ClosedAt      time.Time `json:"closed_at,omitempty"`
+}
+
+// IssueComment はIssueのコメントを表します
+type IssueComment struct {
+	ID        int       `json:"id"`
+	User      User      `json:"user"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateIssueOptions はIssue作成オプションを表します
+type CreateIssueOptions struct {
+	Owner     string   `json:"owner"`
+	Repo      string   `json:"repo"`
+	Title     string   `json:"title"`
+	Body      string   `json:"body,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+	Assignees []string `json:"assignees,omitempty"`
+	Milestone int      `json:"milestone,omitempty"`
+}
+
+// GetIssueOptions はIssue取得オプションを表します
+type GetIssueOptions struct {
+	Owner       string `json:"owner"`
+	Repo        string `json:"repo"`
+	IssueNumber int    `json:"issue_number"`
+}
+
+// ListIssuesOptions はIssue一覧の取得オプションを表します
+// GitHub APIの仕様により、結果にはPull Requestも含まれます (is_pull_request で区別できます)
+type ListIssuesOptions struct {
+	Owner     string    `json:"owner"`
+	Repo      string    `json:"repo"`
+	State     string    `json:"state,omitempty"`    // open, closed, all (既定は open)
+	Labels    []string  `json:"labels,omitempty"`   // すべてのラベルが付いたものに絞り込みます
+	Assignee  string    `json:"assignee,omitempty"` // ユーザー名、none (未割り当て) または * (割り当て済み)
+	Since     time.Time `json:"since,omitempty"`    // この日時以降に更新されたものに絞り込みます
+	Sort      string    `json:"sort,omitempty"`
+	Direction string    `json:"direction,omitempty"`
+	PageOptions
+}
+
+// ListIssuesResult はIssue一覧の取得結果を表します
+type ListIssuesResult struct {
+	Items      []Issue `json:"items"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// UpdateIssueOptions はIssue更新オプションを表します
+// nil のフィールドは変更しません。Labels と Assignees に空のスライスを指定するとすべて外します
+type UpdateIssueOptions struct {
+	Owner       string    `json:"owner"`
+	Repo        string    `json:"repo"`
+	IssueNumber int       `json:"issue_number"`
+	Title       *string   `json:"title,omitempty"`
+	Body        *string   `json:"body,omitempty"`
+	State       *string   `json:"state,omitempty"`        // open または closed
+	StateReason *string   `json:"state_reason,omitempty"` // completed, not_planned, reopened
+	Labels      *[]string `json:"labels,omitempty"`
+	Assignees   *[]string `json:"assignees,omitempty"`
+	Milestone   *int      `json:"milestone,omitempty"` // 0 を指定するとマイルストーンを外します
+}
+
+// AddIssueCommentOptions はIssueコメント追加オプションを表します
+type AddIssueCommentOptions struct {
+	Owner       string `json:"owner"`
+	Repo        string `json:"repo"`
+	IssueNumber int    `json:"issue_number"`
+	Body        string `json:"body"`
+}
+
+// ListIssueCommentsOptions はIssueコメント一覧の取得オプションを表します
+type ListIssueCommentsOptions struct {
+	Owner       string    `json:"owner"`
+	Repo        string    `json:"repo"`
+	IssueNumber int       `json:"issue_number"`
+	Since       time.Time `json:"since,omitempty"`
+	PageOptions
+}
+
+// ListIssueCommentsResult はIssueコメント一覧の取得結果を表します
+type ListIssueCommentsResult struct {
+	Items      []IssueComment `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// mapGitHubIssueToIssue はGitHub IssueをIssueモデルに変換します
+func mapGitHubIssueToIssue(issue *github.Issue) Issue {
+	result := Issue{
+		ID:            int(issue.GetID()),
+		Number:        issue.GetNumber(),
+		State:         issue.GetState(),
+		StateReason:   issue.GetStateReason(),
+		Title:         issue.GetTitle(),
+		Body:          issue.GetBody(),
+		User:          mapGitHubUserToUser(issue.User),
+		Labels:        make([]string, 0, len(issue.Labels)),
+		Assignees:     make([]User, 0, len(issue.Assignees)),
+		Milestone:     issue.GetMilestone().GetTitle(),
+		Comments:      issue.GetComments(),
+		IsPullRequest: issue.IsPullRequest(),
+		HTMLURL:       issue.GetHTMLURL(),
+		CreatedAt:     mapTimestamp(issue.CreatedAt),
+		UpdatedAt:     mapTimestamp(issue.UpdatedAt),
+		ClosedAt:      mapTimestamp(issue.ClosedAt),
+	}
+	for _, label := range issue.Labels {
+		result.Labels = append(result.Labels, label.GetName())
+	}
+	for _, assignee := range issue.Assignees {
+		result.Assignees = append(result.Assignees, mapGitHubUserToUser(assignee))
+	}
+	return result
+}
+
+// mapGitHubIssueComment はGitHub IssueコメントをIssueCommentモデルに変換します
+func mapGitHubIssueComment(comment *github.IssueComment) IssueComment {
+	return IssueComment{
+		ID:        int(comment.GetID()),
+		User:      mapGitHubUserToUser(comment.User),
+		Body:      comment.GetBody(),
+		HTMLURL:   comment.GetHTMLURL(),
+		CreatedAt: mapTimestamp(comment.CreatedAt),
+		UpdatedAt: mapTimestamp(comment.UpdatedAt),
+	}
+}
+
+// CreateIssue は新しいIssueを作成します
+func CreateIssue(ctx context.Context, options CreateIssueOptions, token string) (*Issue, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Issue作成リクエストの設定
+	req := &github.IssueRequest{
+		Title: github.String(options.Title),
+		Body:  github.String(options.Body),
+	}
+	if len(options.Labels) > 0 {
+		req.Labels = &options.Labels
+	}
+	if len(options.Assignees) > 0 {
+		req.Assignees = &options.Assignees
+	}
+	if options.Milestone > 0 {
+		req.Milestone = github.Int(options.Milestone)
+	}
+
+	// GitHub APIを呼び出してIssueを作成
+	issue, _, err := client.Issues.Create(ctx, options.Owner, options.Repo, req)
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	result := mapGitHubIssueToIssue(issue)
+	return &result, nil
+}
+
+// GetIssue はIssueの詳細を取得します
+func GetIssue(ctx context.Context, options GetIssueOptions, token string) (*Issue, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してIssueを取得
+	issue, _, err := client.Issues.Get(ctx, options.Owner, options.Repo, options.IssueNumber)
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	result := mapGitHubIssueToIssue(issue)
+	return &result, nil
+}
+
+// ListIssues はリポジトリのIssueを一覧で取得します
+func ListIssues(ctx context.Context, options ListIssuesOptions, token string) (*ListIssuesResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してIssueの一覧を取得
+	issues, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.Issue, *github.Response, error) {
+		return client.Issues.ListByRepo(ctx, options.Owner, options.Repo, &github.IssueListByRepoOptions{
+			State:       options.State,
+			Labels:      options.Labels,
+			Assignee:    options.Assignee,
+			Since:       options.Since,
+			Sort:        options.Sort,
+			Direction:   options.Direction,
+			ListOptions: listOpts,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result := &ListIssuesResult{Items: make([]Issue, 0, len(issues)), NextCursor: next}
+	for _, issue := range issues {
+		result.Items = append(result.Items, mapGitHubIssueToIssue(issue))
+	}
+	return result, nil
+}
+
+// UpdateIssue はIssueのタイトル、本文、状態、ラベル、担当者、マイルストーンを更新します
+func UpdateIssue(ctx context.Context, options UpdateIssueOptions, token string) (*Issue, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Issue更新リクエストの設定
+	req := &github.IssueRequest{
+		Title:       options.Title,
+		Body:        options.Body,
+		State:       options.State,
+		StateReason: options.StateReason,
+		Labels:      options.Labels,
+		Assignees:   options.Assignees,
+	}
+	// go-githubは milestone: null を送信できないため、外す場合は専用のAPIを使用する
+	removeMilestone := options.Milestone != nil && *options.Milestone == 0
+	if options.Milestone != nil && !removeMilestone {
+		req.Milestone = options.Milestone
+	}
+
+	// GitHub APIを呼び出してIssueを更新
+	var issue *github.Issue
+	if !removeMilestone || *req != (github.IssueRequest{}) {
+		issue, _, err = client.Issues.Edit(ctx, options.Owner, options.Repo, options.IssueNumber, req)
+		if err != nil {
+			return nil, mapGitHubError(err)
+		}
+	}
+	if removeMilestone {
+		issue, _, err = client.Issues.RemoveMilestone(ctx, options.Owner, options.Repo, options.IssueNumber)
+		if err != nil {
+			return nil, mapGitHubError(err)
+		}
+	}
+
+	result := mapGitHubIssueToIssue(issue)
+	return &result, nil
+}
+
+// AddIssueComment はIssueにコメントを追加します
+func AddIssueComment(ctx context.Context, options AddIssueCommentOptions, token string) (*IssueComment, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してコメントを作成
+	comment, _, err := client.Issues.CreateComment(ctx, options.Owner, options.Repo, options.IssueNumber, &github.IssueComment{
+		Body: github.String(options.Body),
+	})
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	result := mapGitHubIssueComment(comment)
+	return &result, nil
+}
+
+// ListIssueComments はIssueのコメントを古い順に一覧で取得します
+func ListIssueComments(ctx context.Context, options ListIssueCommentsOptions, token string) (*ListIssueCommentsResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してコメントの一覧を取得
+	comments, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.IssueComment, *github.Response, error) {
+		opts := &github.IssueListCommentsOptions{ListOptions: listOpts}
+		if !options.Since.IsZero() {
+			opts.Since = &options.Since
+		}
+		return client.Issues.ListComments(ctx, options.Owner, options.Repo, options.IssueNumber, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result := &ListIssueCommentsResult{Items: make([]IssueComment, 0, len(comments)), NextCursor: next}
+	for _, comment := range comments {
+		result.Items = append(result.Items, mapGitHubIssueComment(comment))
+	}
+	return result, nil
+}
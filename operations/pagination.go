@@ -0,0 +1,142 @@
+package operations
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-github/v70/github"
+)
+
+const (
+	// DefaultPerPage は一覧系の操作で1ページに取得する既定の件数です
+	DefaultPerPage = 30
+	// MaxPerPage はGitHub APIが1ページで返す最大の件数です
+	MaxPerPage = 100
+	// MaxPaginatedItems は自動でページをたどる場合に取得する最大の件数です
+	// 検索APIが返す結果も最大1000件に制限されています
+	MaxPaginatedItems = 1000
+)
+
+// PageOptions は一覧系の操作の取得範囲を表します
+//
+// Cursor には前回の結果の next_cursor を指定し、続きから取得します。Cursor を指定した場合は
+// Page と PerPage より優先されます。MaxItems を指定すると、最大でその件数になるまで
+// 自動的に次のページを取得します。
+type PageOptions struct {
+	Cursor   string `json:"cursor,omitempty"`
+	Page     int    `json:"page,omitempty"`
+	PerPage  int    `json:"per_page,omitempty"`
+	MaxItems int    `json:"max_items,omitempty"`
+}
+
+// pageCursor は next_cursor に含める続きの位置を表します
+// 取得件数の上限でページの途中まで返した場合は、そのページで読み飛ばす件数を Offset に保持します
+type pageCursor struct {
+	Page    int `json:"p"`
+	PerPage int `json:"n"`
+	Offset  int `json:"o,omitempty"`
+}
+
+// encode はカーソルをクライアントに返す不透明な文字列に変換します
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageCursor は next_cursor の文字列を解析します
+func decodePageCursor(value string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Page < 1 || c.PerPage < 1 || c.PerPage > MaxPerPage || c.Offset < 0 || c.Offset >= c.PerPage {
+		return pageCursor{}, fmt.Errorf("cursor %q is not a next_cursor returned by a previous result", value)
+	}
+	return c, nil
+}
+
+// Validate は取得範囲の指定を検証します
+func (o PageOptions) Validate() error {
+	if o.Page < 0 || o.PerPage < 0 || o.MaxItems < 0 {
+		return fmt.Errorf("page, per_page and max_items must not be negative")
+	}
+	if o.PerPage > MaxPerPage {
+		return fmt.Errorf("per_page must be %d or less", MaxPerPage)
+	}
+	_, err := o.start()
+	return err
+}
+
+// start は取得を始める位置を返します
+func (o PageOptions) start() (pageCursor, error) {
+	if o.Cursor != "" {
+		return decodePageCursor(o.Cursor)
+	}
+	c := pageCursor{Page: o.Page, PerPage: o.PerPage}
+	if c.Page < 1 {
+		c.Page = 1
+	}
+	if c.PerPage < 1 {
+		c.PerPage = DefaultPerPage
+	}
+	if c.PerPage > MaxPerPage {
+		c.PerPage = MaxPerPage
+	}
+	if o.MaxItems > 0 && o.PerPage < 1 {
+		// 自動でページをたどる場合は、リクエストの回数が少なくなるよう大きなページで取得する
+		c.PerPage = MaxPerPage
+	}
+	return c, nil
+}
+
+// listPage はページを指定して一覧を取得する関数です
+type listPage[T any] func(ctx context.Context, opts github.ListOptions) ([]T, *github.Response, error)
+
+// paginate はページ指定に従って一覧を取得し、続きを取得するためのカーソルとともに返します
+//
+// MaxItems が指定されていない場合は1ページのみを取得します。指定されている場合は、
+// 件数に達するか最後のページに到達するまで次のページを取得します (最大 MaxPaginatedItems 件)。
+// 続きがない場合、返すカーソルは空文字列です。
+func paginate[T any](ctx context.Context, opts PageOptions, list listPage[T]) ([]T, string, error) {
+	cursor, err := opts.start()
+	if err != nil {
+		return nil, "", err
+	}
+	limit := opts.MaxItems
+	if limit > MaxPaginatedItems {
+		limit = MaxPaginatedItems
+	}
+
+	var items []T
+	for {
+		page, resp, err := list(ctx, github.ListOptions{Page: cursor.Page, PerPage: cursor.PerPage})
+		if err != nil {
+			return nil, "", mapGitHubError(err)
+		}
+		offset := min(cursor.Offset, len(page))
+		page = page[offset:]
+		next := 0
+		if resp != nil {
+			next = resp.NextPage
+		}
+
+		if limit > 0 && len(items)+len(page) > limit {
+			// 件数の上限でページの途中まで返した場合は、同じページの残りから続ける
+			used := limit - len(items)
+			items = append(items, page[:used]...)
+			return items, pageCursor{Page: cursor.Page, PerPage: cursor.PerPage, Offset: offset + used}.encode(), nil
+		}
+		items = append(items, page...)
+
+		if next == 0 {
+			return items, "", nil
+		}
+		cursor = pageCursor{Page: next, PerPage: cursor.PerPage}
+		if limit <= 0 || len(items) >= limit {
+			return items, cursor.encode(), nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package operations
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v70/github"
+)
+
+// numbers は1からtotalまでの数をページ単位で返す一覧の関数を作成します
+func numbers(total int, requests *[]github.ListOptions) listPage[int] {
+	return func(ctx context.Context, opts github.ListOptions) ([]int, *github.Response, error) {
+		*requests = append(*requests, opts)
+		var page []int
+		for i := (opts.Page-1)*opts.PerPage + 1; i <= min(opts.Page*opts.PerPage, total); i++ {
+			page = append(page, i)
+		}
+		resp := &github.Response{}
+		if opts.Page*opts.PerPage < total {
+			resp.NextPage = opts.Page + 1
+		}
+		return page, resp, nil
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	ctx := context.Background()
+	var requests []github.ListOptions
+
+	// 1ページのみを取得し、続きのカーソルを返す
+	items, next, err := paginate(ctx, PageOptions{PerPage: 3}, numbers(7, &requests))
+	if err != nil || !reflect.DeepEqual(items, []int{1, 2, 3}) || next == "" {
+		t.Fatalf("items = %v, next = %q, err = %v", items, next, err)
+	}
+	items, next, _ = paginate(ctx, PageOptions{Cursor: next}, numbers(7, &requests))
+	if !reflect.DeepEqual(items, []int{4, 5, 6}) || next == "" {
+		t.Fatalf("items = %v, next = %q", items, next)
+	}
+	items, next, _ = paginate(ctx, PageOptions{Cursor: next}, numbers(7, &requests))
+	if !reflect.DeepEqual(items, []int{7}) || next != "" {
+		t.Fatalf("last page: items = %v, next = %q", items, next)
+	}
+
+	// max_items を指定すると件数に達するまで次のページを取得する
+	requests = nil
+	items, next, _ = paginate(ctx, PageOptions{PerPage: 3, MaxItems: 5}, numbers(7, &requests))
+	if !reflect.DeepEqual(items, []int{1, 2, 3, 4, 5}) || len(requests) != 2 {
+		t.Fatalf("items = %v, requests = %v", items, requests)
+	}
+	// ページの途中で止めた場合は残りから続ける
+	items, next, _ = paginate(ctx, PageOptions{Cursor: next, MaxItems: 5}, numbers(7, &requests))
+	if !reflect.DeepEqual(items, []int{6, 7}) || next != "" {
+		t.Fatalf("continued items = %v, next = %q", items, next)
+	}
+
+	// per_page を指定しない場合は最大のページで取得する
+	requests = nil
+	items, _, _ = paginate(ctx, PageOptions{MaxItems: 150}, numbers(250, &requests))
+	if len(items) != 150 || len(requests) != 2 || requests[0].PerPage != MaxPerPage {
+		t.Errorf("len = %d, requests = %v", len(items), requests)
+	}
+
+	// 取得件数には上限がある
+	requests = nil
+	items, next, _ = paginate(ctx, PageOptions{MaxItems: 5000}, numbers(2000, &requests))
+	if len(items) != MaxPaginatedItems || next == "" {
+		t.Errorf("len = %d, next = %q", len(items), next)
+	}
+}
+
+func TestPageOptionsValidate(t *testing.T) {
+	valid := []PageOptions{
+		{},
+		{Page: 2, PerPage: 100},
+		{Cursor: pageCursor{Page: 2, PerPage: 30, Offset: 5}.encode()},
+	}
+	for _, opts := range valid {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error: %v", opts, err)
+		}
+	}
+
+	invalid := []PageOptions{
+		{Page: -1},
+		{PerPage: 101},
+		{MaxItems: -1},
+		{Cursor: "not-a-cursor"},
+		{Cursor: pageCursor{Page: 0, PerPage: 30}.encode()},
+		{Cursor: pageCursor{Page: 1, PerPage: 30, Offset: 30}.encode()},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%+v: expected error", opts)
+		}
+	}
+}
@@ -2,41 +2,54 @@ package operations
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
 )
 
 // PullRequest はGitHub Pull Requestを表します
 type PullRequest struct {
-	ID                  int       `json:"id"`
-	Number              int       `json:"number"`
-	State               string    `json:"state"`
-	Title               string    `json:"title"`
-	Body                string    `json:"body"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
-	ClosedAt            time.Time `json:"closed_at,omitempty"`
-	MergedAt            time.Time `json:"merged_at,omitempty"`
-	MergeCommitSHA      string    `json:"merge_commit_sha,omitempty"`
-	User                User      `json:"user"`
-	HTMLURL             string    `json:"html_url"`
-	DiffURL             string    `json:"diff_url"`
-	PatchURL            string    `json:"patch_url"`
-	Base                Ref       `json:"base"`
-	Head                Ref       `json:"head"`
-	Merged              bool      `json:"merged"`
-	Mergeable           bool      `json:"mergeable"`
-	MergeableState      string    `json:"mergeable_state"`
-	Comments            int       `json:"comments"`
-	ReviewComments      int       `json:"review_comments"`
-	Commits             int       `json:"commits"`
-	Additions           int       `json:"additions"`
-	Deletions           int       `json:"deletions"`
-	ChangedFiles        int       `json:"changed_files"`
-	Draft               bool      `json:"draft"`
-	RequestedReviewers  []User    `json:"requested_reviewers"`
-	MaintainerCanModify bool      `json:"maintainer_can_modify"`
+	ID                  int        `json:"id"`
+	Number              int        `json:"number"`
+	State               string     `json:"state"`
+	Title               string     `json:"title"`
+	Body                string     `json:"body"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	ClosedAt            time.Time  `json:"closed_at,omitempty"`
+	MergedAt            time.Time  `json:"merged_at,omitempty"`
+	MergeCommitSHA      string     `json:"merge_commit_sha,omitempty"`
+	User                User       `json:"user"`
+	HTMLURL             string     `json:"html_url"`
+	DiffURL             string     `json:"diff_url"`
+	PatchURL            string     `json:"patch_url"`
+	Base                Ref        `json:"base"`
+	Head                Ref        `json:"head"`
+	Merged              bool       `json:"merged"`
+	Mergeable           bool       `json:"mergeable"`
+	MergeableState      string     `json:"mergeable_state"`
+	Comments            int        `json:"comments"`
+	ReviewComments      int        `json:"review_comments"`
+	Commits             int        `json:"commits"`
+	Additions           int        `json:"additions"`
+	Deletions           int        `json:"deletions"`
+	ChangedFiles        int        `json:"changed_files"`
+	Draft               bool       `json:"draft"`
+	RequestedReviewers  []User     `json:"requested_reviewers"`
+	MaintainerCanModify bool       `json:"maintainer_can_modify"`
+	AutoMerge           *AutoMerge `json:"auto_merge,omitempty"`
+}
+
+// AutoMerge はPull Requestの自動マージの設定を表します
+type AutoMerge struct {
+	EnabledBy   User   `json:"enabled_by"`
+	MergeMethod string `json:"merge_method"`
 }
 
 // User はGitHubユーザーを表します
@@ -77,6 +90,8 @@ type PullRequestReviewOptions struct {
 	Body       string `json:"body,omitempty"`
 	Event      string `json:"event"` // APPROVE, REQUEST_CHANGES, COMMENT
 	CommitID   string `json:"commit_id,omitempty"`
+	// Comments は行へのコメントです。レビューとともに1回のリクエストで作成されます
+	Comments []DraftReviewComment `json:"comments,omitempty"`
 }
 
 // PullRequestReview はレビュー結果を表します
@@ -97,6 +112,39 @@ type GetPullRequestOptions struct {
 	PullNumber int    `json:"pull_number"`
 }
 
+// ListPullRequestsOptions はPull Request一覧の取得オプションを表します
+type ListPullRequestsOptions struct {
+	Owner     string `json:"owner"`
+	Repo      string `json:"repo"`
+	State     string `json:"state,omitempty"` // open, closed, all (既定は open)
+	Head      string `json:"head,omitempty"`  // "user:branch" 形式。ブランチ名のみの場合はリポジトリオーナーのブランチとみなします
+	Base      string `json:"base,omitempty"`
+	Sort      string `json:"sort,omitempty"`      // created, updated, popularity, long-running
+	Direction string `json:"direction,omitempty"` // asc または desc
+	PageOptions
+}
+
+// PullRequestSummary は一覧で返すPull Requestの概要を表します
+type PullRequestSummary struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	State     string    `json:"state"`
+	Author    string    `json:"author"`
+	Draft     bool      `json:"draft"`
+	Labels    []string  `json:"labels,omitempty"`
+	Head      string    `json:"head"`
+	Base      string    `json:"base"`
+	HTMLURL   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListPullRequestsResult はPull Request一覧の取得結果を表します
+type ListPullRequestsResult struct {
+	Items      []PullRequestSummary `json:"items"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
 // mapGitHubUserToUser はGitHubユーザーをUserモデルに変換します
 func mapGitHubUserToUser(ghUser *github.User) User {
 	if ghUser == nil {
@@ -129,36 +177,8 @@ func mapGitHubRepositoryToRepo(ghRepo *github.Repository) Repository {
 	}
 }
 
-// mapTimestamp はgithub.Timestampをtime.Timeに変換します
-func mapTimestamp(timestamp *github.Timestamp) time.Time {
-	if timestamp == nil {
-		return time.Time{}
-	}
-	return timestamp.Time
-}
-
-// CreatePullRequest は新しいPull Requestを作成します
-func CreatePullRequest(options CreatePullRequestOptions, token string) (*PullRequest, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
-
-	// Pull Request作成リクエストの設定
-	newPR := &github.NewPullRequest{
-		Title:               github.String(options.Title),
-		Head:                github.String(options.Head),
-		Base:                github.String(options.Base),
-		Body:                github.String(options.Body),
-		MaintainerCanModify: github.Bool(options.MaintainerCanModify),
-		Draft:               github.Bool(options.Draft),
-	}
-
-	// GitHub APIを呼び出してPull Requestを作成
-	pr, _, err := client.PullRequests.Create(ctx, options.Owner, options.Repo, newPR)
-	if err != nil {
-		return nil, err
-	}
-
-	// 結果をマッピング
+// mapGitHubPullRequest はGitHub Pull RequestをPullRequestモデルに変換します
+func mapGitHubPullRequest(pr *github.PullRequest) *PullRequest {
 	result := &PullRequest{
 		ID:                  int(pr.GetID()),
 		Number:              pr.GetNumber(),
@@ -216,85 +236,124 @@ func CreatePullRequest(options CreatePullRequestOptions, token string) (*PullReq
 		}
 	}
 
-	return result, nil
+	// 自動マージが有効な場合は設定
+	if pr.AutoMerge != nil {
+		result.AutoMerge = &AutoMerge{
+			EnabledBy:   mapGitHubUserToUser(pr.AutoMerge.EnabledBy),
+			MergeMethod: pr.AutoMerge.GetMergeMethod(),
+		}
+	}
+
+	return result
+}
+
+// mapTimestamp はgithub.Timestampをtime.Timeに変換します
+func mapTimestamp(timestamp *github.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Time{}
+	}
+	return timestamp.Time
+}
+
+// CreatePullRequest は新しいPull Requestを作成します
+func CreatePullRequest(ctx context.Context, options CreatePullRequestOptions, token string) (*PullRequest, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pull Request作成リクエストの設定
+	newPR := &github.NewPullRequest{
+		Title:               github.String(options.Title),
+		Head:                github.String(options.Head),
+		Base:                github.String(options.Base),
+		Body:                github.String(options.Body),
+		MaintainerCanModify: github.Bool(options.MaintainerCanModify),
+		Draft:               github.Bool(options.Draft),
+	}
+
+	// GitHub APIを呼び出してPull Requestを作成
+	pr, _, err := client.PullRequests.Create(ctx, options.Owner, options.Repo, newPR)
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	return mapGitHubPullRequest(pr), nil
 }
 
 // GetPullRequest はPull Requestの詳細を取得します
-func GetPullRequest(options GetPullRequestOptions, token string) (*PullRequest, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
+func GetPullRequest(ctx context.Context, options GetPullRequestOptions, token string) (*PullRequest, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
 
 	// GitHub APIを呼び出してPull Requestを取得
 	pr, _, err := client.PullRequests.Get(ctx, options.Owner, options.Repo, options.PullNumber)
 	if err != nil {
-		return nil, err
+		return nil, mapGitHubError(err)
 	}
 
-	// 結果をマッピング
-	result := &PullRequest{
-		ID:                  int(pr.GetID()),
-		Number:              pr.GetNumber(),
-		State:               pr.GetState(),
-		Title:               pr.GetTitle(),
-		Body:                pr.GetBody(),
-		CreatedAt:           mapTimestamp(pr.CreatedAt),
-		UpdatedAt:           mapTimestamp(pr.UpdatedAt),
-		ClosedAt:            mapTimestamp(pr.ClosedAt),
-		MergedAt:            mapTimestamp(pr.MergedAt),
-		MergeCommitSHA:      pr.GetMergeCommitSHA(),
-		User:                mapGitHubUserToUser(pr.User),
-		HTMLURL:             pr.GetHTMLURL(),
-		DiffURL:             pr.GetDiffURL(),
-		PatchURL:            pr.GetPatchURL(),
-		Merged:              pr.GetMerged(),
-		Mergeable:           pr.GetMergeable(),
-		MergeableState:      pr.GetMergeableState(),
-		Comments:            pr.GetComments(),
-		ReviewComments:      pr.GetReviewComments(),
-		Commits:             pr.GetCommits(),
-		Additions:           pr.GetAdditions(),
-		Deletions:           pr.GetDeletions(),
-		ChangedFiles:        pr.GetChangedFiles(),
-		Draft:               pr.GetDraft(),
-		MaintainerCanModify: pr.GetMaintainerCanModify(),
+	return mapGitHubPullRequest(pr), nil
+}
+
+// ListPullRequests はリポジトリのPull Requestを一覧で取得します
+func ListPullRequests(ctx context.Context, options ListPullRequestsOptions, token string) (*ListPullRequestsResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
 	}
 
-	// ベースとヘッドの設定
-	if pr.Base != nil {
-		result.Base = Ref{
-			Label: pr.Base.GetLabel(),
-			Ref:   pr.Base.GetRef(),
-			SHA:   pr.Base.GetSHA(),
-			User:  mapGitHubUserToUser(pr.Base.User),
-			Repo:  mapGitHubRepositoryToRepo(pr.Base.Repo),
-		}
+	// ブランチ名のみのheadはリポジトリオーナーのブランチとして扱う
+	head := options.Head
+	if head != "" && !strings.Contains(head, ":") {
+		head = options.Owner + ":" + head
 	}
 
-	if pr.Head != nil {
-		result.Head = Ref{
-			Label: pr.Head.GetLabel(),
-			Ref:   pr.Head.GetRef(),
-			SHA:   pr.Head.GetSHA(),
-			User:  mapGitHubUserToUser(pr.Head.User),
-			Repo:  mapGitHubRepositoryToRepo(pr.Head.Repo),
-		}
+	// GitHub APIを呼び出してPull Requestの一覧を取得
+	pulls, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.PullRequest, *github.Response, error) {
+		return client.PullRequests.List(ctx, options.Owner, options.Repo, &github.PullRequestListOptions{
+			State:       options.State,
+			Head:        head,
+			Base:        options.Base,
+			Sort:        options.Sort,
+			Direction:   options.Direction,
+			ListOptions: listOpts,
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// レビューア情報があれば設定
-	if pr.RequestedReviewers != nil {
-		result.RequestedReviewers = make([]User, 0, len(pr.RequestedReviewers))
-		for _, reviewer := range pr.RequestedReviewers {
-			result.RequestedReviewers = append(result.RequestedReviewers, mapGitHubUserToUser(reviewer))
+	// 結果をマッピング
+	result := &ListPullRequestsResult{Items: make([]PullRequestSummary, 0, len(pulls)), NextCursor: next}
+	for _, pr := range pulls {
+		summary := PullRequestSummary{
+			Number:    pr.GetNumber(),
+			Title:     pr.GetTitle(),
+			State:     pr.GetState(),
+			Author:    pr.GetUser().GetLogin(),
+			Draft:     pr.GetDraft(),
+			Head:      pr.GetHead().GetLabel(),
+			Base:      pr.GetBase().GetRef(),
+			HTMLURL:   pr.GetHTMLURL(),
+			CreatedAt: mapTimestamp(pr.CreatedAt),
+			UpdatedAt: mapTimestamp(pr.UpdatedAt),
 		}
+		for _, label := range pr.Labels {
+			summary.Labels = append(summary.Labels, label.GetName())
+		}
+		result.Items = append(result.Items, summary)
 	}
-
 	return result, nil
 }
 
 // CreatePullRequestReview はPull Requestにレビューを作成します
-func CreatePullRequestReview(options PullRequestReviewOptions, token string) (*PullRequestReview, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
+func CreatePullRequestReview(ctx context.Context, options PullRequestReviewOptions, token string) (*PullRequestReview, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
 
 	// レビュー作成リクエストの設定
 	review := &github.PullRequestReviewRequest{
@@ -302,6 +361,9 @@ func CreatePullRequestReview(options PullRequestReviewOptions, token string) (*P
 		Event:    github.String(options.Event),
 		CommitID: github.String(options.CommitID),
 	}
+	for _, comment := range options.Comments {
+		review.Comments = append(review.Comments, comment.toGitHub())
+	}
 
 	// GitHub APIを呼び出してレビューを作成
 	prReview, _, err := client.PullRequests.CreateReview(
@@ -312,7 +374,7 @@ func CreatePullRequestReview(options PullRequestReviewOptions, token string) (*P
 		review,
 	)
 	if err != nil {
-		return nil, err
+		return nil, mapGitHubError(err)
 	}
 
 	// 結果をマッピング
@@ -326,3 +388,305 @@ func CreatePullRequestReview(options PullRequestReviewOptions, token string) (*P
 		SubmittedAt: mapTimestamp(prReview.SubmittedAt),
 	}, nil
 }
+
+// MergePullRequestOptions はPull Requestのマージオプションを表します
+type MergePullRequestOptions struct {
+	Owner         string `json:"owner"`
+	Repo          string `json:"repo"`
+	PullNumber    int    `json:"pull_number"`
+	MergeMethod   string `json:"merge_method,omitempty"` // merge, squash, rebase (既定は merge)
+	CommitTitle   string `json:"commit_title,omitempty"`
+	CommitMessage string `json:"commit_message,omitempty"`
+	SHA           string `json:"sha,omitempty"` // 指定した場合、ヘッドがこのSHAでなければマージしません
+}
+
+// MergePullRequestResult はPull Requestのマージ結果を表します
+type MergePullRequestResult struct {
+	Merged  bool   `json:"merged"`
+	SHA     string `json:"sha"`
+	Message string `json:"message"`
+}
+
+// UpdatePullRequestOptions はPull Request更新オプションを表します
+// nil のフィールドは変更しません
+type UpdatePullRequestOptions struct {
+	Owner               string  `json:"owner"`
+	Repo                string  `json:"repo"`
+	PullNumber          int     `json:"pull_number"`
+	Title               *string `json:"title,omitempty"`
+	Body                *string `json:"body,omitempty"`
+	Base                *string `json:"base,omitempty"`
+	State               *string `json:"state,omitempty"` // open または closed
+	Draft               *bool   `json:"draft,omitempty"`
+	MaintainerCanModify *bool   `json:"maintainer_can_modify,omitempty"`
+}
+
+// UpdatePullRequestBranchOptions はPull Requestのブランチ更新オプションを表します
+type UpdatePullRequestBranchOptions struct {
+	Owner           string `json:"owner"`
+	Repo            string `json:"repo"`
+	PullNumber      int    `json:"pull_number"`
+	ExpectedHeadSHA string `json:"expected_head_sha,omitempty"`
+}
+
+// UpdatePullRequestBranchResult はPull Requestのブランチ更新結果を表します
+type UpdatePullRequestBranchResult struct {
+	Message string `json:"message"`
+	URL     string `json:"url"`
+}
+
+// PullRequestAutoMergeOptions はPull Requestの自動マージの設定オプションを表します
+type PullRequestAutoMergeOptions struct {
+	Owner       string `json:"owner"`
+	Repo        string `json:"repo"`
+	PullNumber  int    `json:"pull_number"`
+	MergeMethod string `json:"merge_method,omitempty"` // merge, squash, rebase (有効にする場合のみ)
+}
+
+// PullRequestAutoMergeResult は自動マージの設定結果を表します
+type PullRequestAutoMergeResult struct {
+	Number      int    `json:"number"`
+	Enabled     bool   `json:"enabled"`
+	MergeMethod string `json:"merge_method,omitempty"`
+}
+
+// MergePullRequest はPull Requestをマージします
+// マージできない状態の場合は、理由を含む競合エラーまたは検証エラーを返します
+func MergePullRequest(ctx context.Context, options MergePullRequestOptions, token string) (*MergePullRequestResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してPull Requestをマージ
+	merged, resp, err := client.PullRequests.Merge(ctx, options.Owner, options.Repo, options.PullNumber, options.CommitMessage, &github.PullRequestOptions{
+		CommitTitle: options.CommitTitle,
+		SHA:         options.SHA,
+		MergeMethod: options.MergeMethod,
+	})
+	if err != nil {
+		// マージできない場合は405が返るため、Pull Requestの状態から理由を求める
+		if resp != nil && resp.StatusCode == http.StatusMethodNotAllowed {
+			var errResp *github.ErrorResponse
+			original := ""
+			if errors.As(err, &errResp) {
+				original = errResp.Message
+			}
+			pr, _, getErr := client.PullRequests.Get(ctx, options.Owner, options.Repo, options.PullNumber)
+			if getErr == nil {
+				return nil, newNotMergeableError(pr, original)
+			}
+		}
+		return nil, mapGitHubError(err)
+	}
+
+	return &MergePullRequestResult{
+		Merged:  merged.GetMerged(),
+		SHA:     merged.GetSHA(),
+		Message: merged.GetMessage(),
+	}, nil
+}
+
+// newNotMergeableError はマージできないPull Requestについて、状態に応じたエラーを作成します
+// ブランチの更新で解消できる場合は競合エラー、それ以外は検証エラーとします
+// 状態から理由が分からない場合は、マージ方法が許可されていないなどのGitHubのメッセージ (original) を含めます
+func newNotMergeableError(pr *github.PullRequest, original string) error {
+	base := common.GitHubError{Status: http.StatusMethodNotAllowed}
+	prefix := fmt.Sprintf("pull request #%d is not mergeable", pr.GetNumber())
+	switch {
+	case pr.GetMerged():
+		base.Message = fmt.Sprintf("pull request #%d is already merged", pr.GetNumber())
+	case pr.GetState() == "closed":
+		base.Message = fmt.Sprintf("pull request #%d is closed; reopen it before merging", pr.GetNumber())
+	case pr.GetDraft():
+		base.Message = prefix + ": it is a draft; mark it ready for review with update_pull_request first"
+	default:
+		switch state := pr.GetMergeableState(); state {
+		case "dirty":
+			base.Message = prefix + ": the head branch has conflicts with the base branch; resolve them and try again"
+			return &common.GitHubConflictError{GitHubError: base}
+		case "behind":
+			base.Message = prefix + ": the head branch is behind the base branch; update it with update_pull_request_branch and try again"
+			return &common.GitHubConflictError{GitHubError: base}
+		case "blocked":
+			base.Message = prefix + ": required reviews or status checks have not passed (consider enabling auto-merge)"
+		default:
+			if original != "" {
+				prefix += ": " + original
+			}
+			base.Message = fmt.Sprintf("%s (mergeable_state: %s)", prefix, state)
+		}
+	}
+	return &common.GitHubValidationError{GitHubError: base}
+}
+
+// UpdatePullRequest はPull Requestのタイトル、本文、ベースブランチ、状態、ドラフトを更新します
+// ドラフトの切り替えはREST APIで行えないため、GraphQL APIを使用します
+func UpdatePullRequest(ctx context.Context, options UpdatePullRequestOptions, token string) (*PullRequest, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// REST APIで変更できる項目を更新
+	update := &github.PullRequest{
+		Title:               options.Title,
+		Body:                options.Body,
+		State:               options.State,
+		MaintainerCanModify: options.MaintainerCanModify,
+	}
+	if options.Base != nil {
+		update.Base = &github.PullRequestBranch{Ref: options.Base}
+	}
+	var pr *github.PullRequest
+	if options.Title != nil || options.Body != nil || options.State != nil || options.Base != nil || options.MaintainerCanModify != nil {
+		pr, _, err = client.PullRequests.Edit(ctx, options.Owner, options.Repo, options.PullNumber, update)
+	} else {
+		pr, _, err = client.PullRequests.Get(ctx, options.Owner, options.Repo, options.PullNumber)
+	}
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	// ドラフトの切り替え
+	if options.Draft != nil && *options.Draft != pr.GetDraft() {
+		mutation := `mutation($id: ID!) { markPullRequestReadyForReview(input: {pullRequestId: $id}) { pullRequest { isDraft } } }`
+		if *options.Draft {
+			mutation = `mutation($id: ID!) { convertPullRequestToDraft(input: {pullRequestId: $id}) { pullRequest { isDraft } } }`
+		}
+		if err := graphQL(ctx, client, mutation, map[string]interface{}{"id": pr.GetNodeID()}, nil); err != nil {
+			return nil, err
+		}
+		pr.Draft = options.Draft
+	}
+
+	return mapGitHubPullRequest(pr), nil
+}
+
+// UpdatePullRequestBranch はPull Requestのヘッドブランチにベースブランチの変更を取り込みます
+// 更新はGitHub側で非同期に行われます
+func UpdatePullRequestBranch(ctx context.Context, options UpdatePullRequestBranchOptions, token string) (*UpdatePullRequestBranchResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts *github.PullRequestBranchUpdateOptions
+	if options.ExpectedHeadSHA != "" {
+		opts = &github.PullRequestBranchUpdateOptions{ExpectedHeadSHA: github.String(options.ExpectedHeadSHA)}
+	}
+
+	// GitHub APIを呼び出してブランチを更新
+	// 更新の受け付けは202で返り、go-githubはAcceptedErrorとして扱うため成功とみなす
+	updated, _, err := client.PullRequests.UpdateBranch(ctx, options.Owner, options.Repo, options.PullNumber, opts)
+	var accepted *github.AcceptedError
+	if errors.As(err, &accepted) {
+		updated = &github.PullRequestBranchUpdateResponse{}
+		if err = json.Unmarshal(accepted.Raw, updated); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	return &UpdatePullRequestBranchResult{
+		Message: updated.GetMessage(),
+		URL:     updated.GetURL(),
+	}, nil
+}
+
+// EnablePullRequestAutoMerge は必要な条件を満たした時点で自動的にマージされるように設定します
+func EnablePullRequestAutoMerge(ctx context.Context, options PullRequestAutoMergeOptions, token string) (*PullRequestAutoMergeResult, error) {
+	return setPullRequestAutoMerge(ctx, options, token, true)
+}
+
+// DisablePullRequestAutoMerge は自動マージの設定を解除します
+func DisablePullRequestAutoMerge(ctx context.Context, options PullRequestAutoMergeOptions, token string) (*PullRequestAutoMergeResult, error) {
+	return setPullRequestAutoMerge(ctx, options, token, false)
+}
+
+// setPullRequestAutoMerge はGraphQL APIで自動マージを有効または無効にします
+func setPullRequestAutoMerge(ctx context.Context, options PullRequestAutoMergeOptions, token string, enable bool) (*PullRequestAutoMergeResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GraphQL APIで指定するためのノードIDを取得
+	pr, _, err := client.PullRequests.Get(ctx, options.Owner, options.Repo, options.PullNumber)
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	result := &PullRequestAutoMergeResult{Number: pr.GetNumber(), Enabled: enable}
+	variables := map[string]interface{}{"id": pr.GetNodeID()}
+	mutation := `mutation($id: ID!) { disablePullRequestAutoMerge(input: {pullRequestId: $id}) { pullRequest { number } } }`
+	if enable {
+		mutation = `mutation($id: ID!, $method: PullRequestMergeMethod) { enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { pullRequest { number } } }`
+		if options.MergeMethod != "" {
+			// GraphQL APIの列挙値は大文字 (MERGE, SQUASH, REBASE)
+			variables["method"] = strings.ToUpper(options.MergeMethod)
+			result.MergeMethod = options.MergeMethod
+		}
+	}
+	if err := graphQL(ctx, client, mutation, variables, nil); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PullRequestFile はPull Requestで変更されたファイルを表します
+type PullRequestFile struct {
+	Filename         string `json:"filename"`
+	Status           string `json:"status"` // added, removed, modified, renamed など
+	Additions        int    `json:"additions"`
+	Deletions        int    `json:"deletions"`
+	Changes          int    `json:"changes"`
+	Patch            string `json:"patch,omitempty"` // バイナリや大きすぎる差分では省略されます
+	PreviousFilename string `json:"previous_filename,omitempty"`
+}
+
+// GetPullRequestFilesOptions はPull Requestの変更ファイル一覧の取得オプションを表します
+type GetPullRequestFilesOptions struct {
+	Owner      string `json:"owner"`
+	Repo       string `json:"repo"`
+	PullNumber int    `json:"pull_number"`
+	PageOptions
+}
+
+// GetPullRequestFilesResult はPull Requestの変更ファイル一覧の取得結果を表します
+type GetPullRequestFilesResult struct {
+	Items      []PullRequestFile `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// GetPullRequestFiles はPull Requestで変更されたファイルを差分とともに一覧で取得します
+func GetPullRequestFiles(ctx context.Context, options GetPullRequestFilesOptions, token string) (*GetPullRequestFilesResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出して変更ファイルの一覧を取得
+	files, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+		return client.PullRequests.ListFiles(ctx, options.Owner, options.Repo, options.PullNumber, &listOpts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result := &GetPullRequestFilesResult{Items: make([]PullRequestFile, 0, len(files)), NextCursor: next}
+	for _, file := range files {
+		result.Items = append(result.Items, PullRequestFile{
+			Filename:         file.GetFilename(),
+			Status:           file.GetStatus(),
+			Additions:        file.GetAdditions(),
+			Deletions:        file.GetDeletions(),
+			Changes:          file.GetChanges(),
+			Patch:            file.GetPatch(),
+			PreviousFilename: file.GetPreviousFilename(),
+		})
+	}
+	return result, nil
+}
@@ -2,9 +2,9 @@ package operations
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/go-github/v70/github"
-	"golang.org/x/oauth2"
 )
 
 // Repository はGitHubリポジトリを表します
@@ -22,15 +22,16 @@ type Repository struct {
 
 // SearchRepositoriesOptions は検索オプションを表します
 type SearchRepositoriesOptions struct {
-	Query   string `json:"query"`
-	Page    int    `json:"page,omitempty"`
-	PerPage int    `json:"per_page,omitempty"`
+	Query string `json:"query"`
+	PageOptions
 }
 
 // SearchRepositoriesResult は検索結果を表します
+// NextCursor は続きの結果がある場合に、次の呼び出しの cursor に指定する値です
 type SearchRepositoriesResult struct {
 	TotalCount int          `json:"total_count"`
 	Items      []Repository `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
 }
 
 // CreateRepositoryOptions はリポジトリ作成オプションを表します
@@ -39,6 +40,8 @@ type CreateRepositoryOptions struct {
 	Description string `json:"description,omitempty"`
 	Private     bool   `json:"private,omitempty"`
 	AutoInit    bool   `json:"auto_init,omitempty"`
+	// Organization を指定すると組織に作成します (省略時は認証済みユーザー)
+	Organization string `json:"organization,omitempty"`
 }
 
 // ForkRepositoryOptions はフォークオプションを表します
@@ -48,37 +51,30 @@ type ForkRepositoryOptions struct {
 	Organization string `json:"organization,omitempty"`
 }
 
-// getGitHubClient は認証済みのGitHubクライアントを作成します
-func getGitHubClient(ctx context.Context, token string) *github.Client {
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-	return github.NewClient(tc)
-}
-
 // SearchRepositories はGitHubリポジトリを検索します
-func SearchRepositories(options SearchRepositoriesOptions, token string) (*SearchRepositoriesResult, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
-
-	// 検索オプションの設定
-	opts := &github.SearchOptions{
-		ListOptions: github.ListOptions{
-			Page:    options.Page,
-			PerPage: options.PerPage,
-		},
+func SearchRepositories(ctx context.Context, options SearchRepositoriesOptions, token string) (*SearchRepositoriesResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
 	}
 
 	// GitHub APIを呼び出してリポジトリを検索
-	repos, _, err := client.Search.Repositories(ctx, options.Query, opts)
+	var total int
+	repos, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.Repository, *github.Response, error) {
+		result, resp, err := client.Search.Repositories(ctx, options.Query, &github.SearchOptions{ListOptions: listOpts})
+		if err != nil {
+			return nil, resp, err
+		}
+		total = result.GetTotal()
+		return result.Repositories, resp, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// 結果をマッピング
 	var items []Repository
-	for _, repo := range repos.Repositories {
+	for _, repo := range repos {
 		items = append(items, Repository{
 			ID:          int(repo.GetID()),
 			Name:        repo.GetName(),
@@ -93,15 +89,18 @@ func SearchRepositories(options SearchRepositoriesOptions, token string) (*Searc
 	}
 
 	return &SearchRepositoriesResult{
-		TotalCount: int(repos.GetTotal()),
+		TotalCount: total,
 		Items:      items,
+		NextCursor: next,
 	}, nil
 }
 
 // CreateRepository は新しいリポジトリを作成します
-func CreateRepository(options CreateRepositoryOptions, token string) (*Repository, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
+func CreateRepository(ctx context.Context, options CreateRepositoryOptions, token string) (*Repository, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
 
 	// リポジトリ作成リクエストの設定
 	repo := &github.Repository{
@@ -112,9 +111,9 @@ func CreateRepository(options CreateRepositoryOptions, token string) (*Repositor
 	}
 
 	// GitHub APIを呼び出してリポジトリを作成
-	newRepo, _, err := client.Repositories.Create(ctx, "", repo)
+	newRepo, _, err := client.Repositories.Create(ctx, options.Organization, repo)
 	if err != nil {
-		return nil, err
+		return nil, mapGitHubError(err)
 	}
 
 	// 結果をマッピング
@@ -132,9 +131,11 @@ func CreateRepository(options CreateRepositoryOptions, token string) (*Repositor
 }
 
 // ForkRepository はリポジトリをフォークします
-func ForkRepository(options ForkRepositoryOptions, token string) (*Repository, error) {
-	ctx := context.Background()
-	client := getGitHubClient(ctx, token)
+func ForkRepository(ctx context.Context, options ForkRepositoryOptions, token string) (*Repository, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
 
 	// フォークオプションの設定
 	forkOpts := &github.RepositoryCreateForkOptions{}
@@ -143,9 +144,11 @@ func ForkRepository(options ForkRepositoryOptions, token string) (*Repository, e
 	}
 
 	// GitHub APIを呼び出してリポジトリをフォーク
+	// フォークはバックグラウンドで作成されるため、GitHubは202 Acceptedとフォーク先の情報を返します
 	newRepo, _, err := client.Repositories.CreateFork(ctx, options.Owner, options.Repo, forkOpts)
-	if err != nil {
-		return nil, err
+	var acceptedErr *github.AcceptedError
+	if err != nil && !(errors.As(err, &acceptedErr) && newRepo != nil) {
+		return nil, mapGitHubError(err)
 	}
 
 	// 結果をマッピング
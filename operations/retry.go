@@ -0,0 +1,189 @@
+package operations
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+const (
+	// DefaultMaxRetries は再試行の既定の最大回数です
+	DefaultMaxRetries = 3
+	// DefaultRetryBaseDelay は一時的な障害で再試行するまでの既定の基準時間です
+	DefaultRetryBaseDelay = time.Second
+	// DefaultRetryMaxDelay は一時的な障害で再試行するまでの既定の最大時間です
+	DefaultRetryMaxDelay = 30 * time.Second
+	// DefaultRetryMaxWait はレート制限の解除を待つ既定の最大時間です
+	DefaultRetryMaxWait = time.Minute
+	// secondaryRateLimitWait は待ち時間が示されないセカンダリレート制限で待つ時間です
+	// GitHubは少なくとも1分待ってから再試行することを推奨しています
+	secondaryRateLimitWait = time.Minute
+)
+
+// RetryTransport はレート制限と一時的な障害に対してリクエストを再試行するHTTPトランスポートです
+//
+// レート制限 (429、またはレート制限を示すヘッダーや本文を含む403) では、Retry-After または
+// X-RateLimit-Reset が示す時刻まで待ってから再試行します。待ち時間がMaxWaitを超える場合や
+// コンテキストの期限までに解除されない場合は、待たずにレスポンスをそのまま返します。
+// 502/503/504 では、冪等なメソッドのリクエストに限りジッター付きの指数バックオフで再試行します。
+// 待つ間はコンテキストの common.RetryNotifyFunc に通知します。
+type RetryTransport struct {
+	// Base は実際にリクエストを送信するトランスポートです (nilの場合は http.DefaultTransport)
+	Base http.RoundTripper
+	// MaxRetries は再試行の最大回数です
+	MaxRetries int
+	// BaseDelay と MaxDelay は一時的な障害で再試行するまでの待ち時間の基準と上限です
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxWait はレート制限の解除を待つ最大時間です
+	MaxWait time.Duration
+}
+
+// NewRetryTransport は既定の設定でRetryTransportを作成します
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Base:       base,
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultRetryBaseDelay,
+		MaxDelay:   DefaultRetryMaxDelay,
+		MaxWait:    DefaultRetryMaxWait,
+	}
+}
+
+// RoundTrip はリクエストを送信し、必要に応じて再試行します
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := base.RoundTrip(req)
+		if err != nil || attempt >= t.MaxRetries {
+			return resp, err
+		}
+
+		wait, reason, ok := t.retryAfter(req, resp, attempt)
+		if !ok {
+			return resp, nil
+		}
+		// リクエストの本文を再送できない場合は再試行しない
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+		if deadline, hasDeadline := ctx.Deadline(); hasDeadline && time.Now().Add(wait).After(deadline) {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if notify := common.RetryNotifyFromContext(ctx); notify != nil {
+			notify(attempt+1, wait, reason)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		next := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			next.Body = body
+		}
+		req = next
+	}
+}
+
+// retryAfter はレスポンスを再試行するかどうかと、再試行までの待ち時間を判断します
+func (t *RetryTransport) retryAfter(req *http.Request, resp *http.Response, attempt int) (time.Duration, string, bool) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusForbidden:
+		wait, ok := rateLimitWait(resp)
+		if !ok || wait > t.MaxWait {
+			return 0, "", false
+		}
+		return wait, "GitHub APIのレート制限", true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !isIdempotent(req.Method) {
+			return 0, "", false
+		}
+		return t.backoff(attempt), fmt.Sprintf("GitHub APIの一時的な障害 (%d)", resp.StatusCode), true
+	}
+	return 0, "", false
+}
+
+// backoff は再試行の回数に応じたジッター付きの待ち時間を返します
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.BaseDelay << attempt
+	if delay <= 0 || delay > t.MaxDelay {
+		delay = t.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// 同時に失敗したリクエストが一斉に再試行しないように、待ち時間を0からdelayの間で分散させる
+	return rand.N(delay + 1)
+}
+
+// rateLimitWait はレート制限のレスポンスから解除までの待ち時間を求めます
+// レート制限によるレスポンスでない場合は false を返します
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(value); err == nil {
+			return max(time.Until(at), 0), true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// 解除時刻は秒単位のため、解除直後に再試行できるよう1秒加える
+			return max(time.Until(time.Unix(reset, 0)), 0) + time.Second, true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return secondaryRateLimitWait, true
+	}
+	// 待ち時間を示すヘッダーのないセカンダリレート制限は本文で判断する
+	if resp.StatusCode == http.StatusForbidden && isSecondaryRateLimit(resp) {
+		return secondaryRateLimitWait, true
+	}
+	return 0, false
+}
+
+// isSecondaryRateLimit はレスポンスの本文がセカンダリレート制限を示しているかどうかを判断します
+// 本文は読み取った後も呼び出し元が読めるように置き換えます
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// isIdempotent はメソッドが冪等で、一時的な障害の後に再送しても安全かどうかを判断します
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package operations
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+// retryServer は指定した順にレスポンスを返すテスト用サーバーです
+type retryServer struct {
+	mu        sync.Mutex
+	responses []func(w http.ResponseWriter)
+	bodies    []string
+}
+
+func (s *retryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.bodies = append(s.bodies, string(body))
+	var respond func(w http.ResponseWriter)
+	if len(s.responses) > 0 {
+		respond = s.responses[0]
+		s.responses = s.responses[1:]
+	}
+	s.mu.Unlock()
+	if respond == nil {
+		w.Write([]byte(`{"ok":true}`))
+		return
+	}
+	respond(w)
+}
+
+func status(code int, header http.Header, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	unavailable := status(http.StatusServiceUnavailable, nil, `{"message":"unavailable"}`)
+
+	tests := []struct {
+		name         string
+		method       string
+		responses    []func(w http.ResponseWriter)
+		timeout      time.Duration
+		wantStatus   int
+		wantRequests int
+		wantNotified int
+	}{
+		{
+			name:         "transient failure",
+			method:       http.MethodGet,
+			responses:    []func(w http.ResponseWriter){unavailable, status(http.StatusBadGateway, nil, "")},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+			wantNotified: 2,
+		},
+		{
+			name:         "gives up after max retries",
+			method:       http.MethodGet,
+			responses:    []func(w http.ResponseWriter){unavailable, unavailable, unavailable, unavailable},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: 4,
+			wantNotified: 3,
+		},
+		{
+			name:         "post is not retried on transient failure",
+			method:       http.MethodPost,
+			responses:    []func(w http.ResponseWriter){unavailable},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: 1,
+		},
+		{
+			name:         "put is retried with body",
+			method:       http.MethodPut,
+			responses:    []func(w http.ResponseWriter){unavailable},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantNotified: 1,
+		},
+		{
+			name:   "primary rate limit reset",
+			method: http.MethodPost,
+			responses: []func(w http.ResponseWriter){status(http.StatusForbidden, http.Header{
+				"X-Ratelimit-Remaining": {"0"},
+				"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)},
+			}, `{"message":"API rate limit exceeded"}`)},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantNotified: 1,
+		},
+		{
+			name:         "retry after",
+			method:       http.MethodGet,
+			responses:    []func(w http.ResponseWriter){status(http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, "")},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantNotified: 1,
+		},
+		{
+			name:   "reset beyond max wait",
+			method: http.MethodGet,
+			responses: []func(w http.ResponseWriter){status(http.StatusForbidden, http.Header{
+				"X-Ratelimit-Remaining": {"0"},
+				"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)},
+			}, `{"message":"API rate limit exceeded"}`)},
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+		{
+			name:         "retry after beyond deadline",
+			method:       http.MethodGet,
+			responses:    []func(w http.ResponseWriter){status(http.StatusTooManyRequests, http.Header{"Retry-After": {"2"}}, "")},
+			timeout:      time.Second,
+			wantStatus:   http.StatusTooManyRequests,
+			wantRequests: 1,
+		},
+		{
+			name:         "forbidden without rate limit",
+			method:       http.MethodGet,
+			responses:    []func(w http.ResponseWriter){status(http.StatusForbidden, nil, `{"message":"Resource not accessible by integration"}`)},
+			wantStatus:   http.StatusForbidden,
+			wantRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &retryServer{responses: tt.responses}
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			transport := &RetryTransport{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, MaxWait: 5 * time.Second}
+			notified := 0
+			ctx := common.WithRetryNotify(context.Background(), func(attempt int, wait time.Duration, reason string) {
+				notified++
+				if attempt != notified || reason == "" {
+					t.Errorf("notify(%d, %s, %q)", attempt, wait, reason)
+				}
+			})
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			req, _ := http.NewRequestWithContext(ctx, tt.method, srv.URL, strings.NewReader("payload"))
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(handler.bodies) != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", len(handler.bodies), tt.wantRequests)
+			}
+			for _, body := range handler.bodies {
+				if body != "payload" {
+					t.Errorf("body = %q, want the original payload on every attempt", body)
+				}
+			}
+			if notified != tt.wantNotified {
+				t.Errorf("notified = %d, want %d", notified, tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestRetryTransportSecondaryRateLimitBody(t *testing.T) {
+	handler := &retryServer{responses: []func(w http.ResponseWriter){
+		status(http.StatusForbidden, nil, `{"message":"You have exceeded a secondary rate limit"}`),
+	}}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	// 待ち時間がMaxWaitを超えるため再試行せず、呼び出し元は本文をそのまま読める
+	transport := &RetryTransport{MaxRetries: 3, MaxWait: time.Second}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(body), "secondary rate limit") {
+		t.Errorf("status = %d, body = %q", resp.StatusCode, body)
+	}
+	if len(handler.bodies) != 1 {
+		t.Errorf("requests = %d, want 1", len(handler.bodies))
+	}
+}
+
+func TestRetryTransportCanceledWhileWaiting(t *testing.T) {
+	handler := &retryServer{responses: []func(w http.ResponseWriter){
+		status(http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}}, ""),
+	}}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = common.WithRetryNotify(ctx, func(int, time.Duration, string) { cancel() })
+	transport := &RetryTransport{MaxRetries: 3, MaxWait: time.Minute}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := transport.RoundTrip(req); err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,331 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+// DraftReviewComment はレビューとともに作成する行へのコメントを表します
+//
+// Line はコメントする行 (複数行の場合は最後の行) で、Side が RIGHT なら変更後、LEFT なら変更前の
+// 行番号です。複数行にコメントする場合は StartLine (と必要なら StartSide) に最初の行を指定します。
+// Suggestion を指定すると、その内容で対象の行を置き換える ```suggestion ブロックを本文に追加します。
+type DraftReviewComment struct {
+	Path       string  `json:"path"`
+	Line       int     `json:"line"`
+	Side       string  `json:"side,omitempty"` // LEFT または RIGHT (既定は RIGHT)
+	StartLine  int     `json:"start_line,omitempty"`
+	StartSide  string  `json:"start_side,omitempty"`
+	Body       string  `json:"body,omitempty"`
+	Suggestion *string `json:"suggestion,omitempty"` // 空文字列の場合は対象の行の削除を提案します
+}
+
+// Validate はコメントの指定を検証します
+func (c DraftReviewComment) Validate() error {
+	side, startSide := c.Side, c.StartSide
+	if side == "" {
+		side = "RIGHT"
+	}
+	if startSide == "" {
+		startSide = side
+	}
+	switch {
+	case c.Path == "":
+		return fmt.Errorf("path is required")
+	case c.Line < 1:
+		return fmt.Errorf("line must be a positive line number")
+	case c.Side != "" && c.Side != "LEFT" && c.Side != "RIGHT":
+		return fmt.Errorf("side must be LEFT or RIGHT")
+	case c.StartSide != "" && c.StartSide != "LEFT" && c.StartSide != "RIGHT":
+		return fmt.Errorf("start_side must be LEFT or RIGHT")
+	case c.StartSide != "" && c.StartLine == 0:
+		return fmt.Errorf("start_side requires start_line")
+	case c.StartLine < 0 || (c.StartLine > 0 && c.StartLine >= c.Line && startSide == side):
+		return fmt.Errorf("start_line must be less than line")
+	case c.Body == "" && c.Suggestion == nil:
+		return fmt.Errorf("body or suggestion is required")
+	case c.Suggestion != nil && (side == "LEFT" || startSide == "LEFT"):
+		return fmt.Errorf("suggestion can only be used on the RIGHT side")
+	}
+	return nil
+}
+
+// body はSuggestionを含めたコメントの本文を返します
+func (c DraftReviewComment) body() string {
+	if c.Suggestion == nil {
+		return c.Body
+	}
+	// 提案の内容にバッククォートの並びが含まれていても閉じられないよう、より長いフェンスを使う
+	fence := "```"
+	for strings.Contains(*c.Suggestion, fence) {
+		fence += "`"
+	}
+	suggestion := fmt.Sprintf("%ssuggestion\n%s\n%s", fence, strings.TrimSuffix(*c.Suggestion, "\n"), fence)
+	if *c.Suggestion == "" {
+		suggestion = fmt.Sprintf("%ssuggestion\n%s", fence, fence)
+	}
+	if c.Body == "" {
+		return suggestion
+	}
+	return c.Body + "\n\n" + suggestion
+}
+
+// toGitHub はコメントをGitHub APIのリクエスト形式に変換します
+func (c DraftReviewComment) toGitHub() *github.DraftReviewComment {
+	comment := &github.DraftReviewComment{
+		Path: github.String(c.Path),
+		Body: github.String(c.body()),
+		Line: github.Int(c.Line),
+		Side: github.String("RIGHT"),
+	}
+	if c.Side != "" {
+		comment.Side = github.String(c.Side)
+	}
+	if c.StartLine > 0 {
+		comment.StartLine = github.Int(c.StartLine)
+		comment.StartSide = comment.Side
+		if c.StartSide != "" {
+			comment.StartSide = github.String(c.StartSide)
+		}
+	}
+	return comment
+}
+
+// ReviewComment はPull Requestのレビューコメント (行へのコメント) を表します
+type ReviewComment struct {
+	ID                  int64     `json:"id"`
+	PullRequestReviewID int64     `json:"pull_request_review_id"`
+	InReplyToID         int64     `json:"in_reply_to_id,omitempty"` // 返信の場合、スレッドの最初のコメントのID
+	User                User      `json:"user"`
+	Body                string    `json:"body"`
+	Path                string    `json:"path"`
+	Line                int       `json:"line,omitempty"` // ヘッドの更新で行が無くなった場合は省略されます
+	Side                string    `json:"side,omitempty"`
+	StartLine           int       `json:"start_line,omitempty"`
+	StartSide           string    `json:"start_side,omitempty"`
+	OriginalLine        int       `json:"original_line,omitempty"`
+	CommitID            string    `json:"commit_id"`
+	DiffHunk            string    `json:"diff_hunk"`
+	HTMLURL             string    `json:"html_url"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// ListPullRequestReviewCommentsOptions はレビューコメント一覧の取得オプションを表します
+type ListPullRequestReviewCommentsOptions struct {
+	Owner      string    `json:"owner"`
+	Repo       string    `json:"repo"`
+	PullNumber int       `json:"pull_number"`
+	Sort       string    `json:"sort,omitempty"`      // created または updated
+	Direction  string    `json:"direction,omitempty"` // asc または desc
+	Since      time.Time `json:"since,omitempty"`
+	PageOptions
+}
+
+// ListPullRequestReviewCommentsResult はレビューコメント一覧の取得結果を表します
+type ListPullRequestReviewCommentsResult struct {
+	Items      []ReviewComment `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ReplyToReviewCommentOptions はレビューコメントのスレッドへの返信オプションを表します
+type ReplyToReviewCommentOptions struct {
+	Owner      string `json:"owner"`
+	Repo       string `json:"repo"`
+	PullNumber int    `json:"pull_number"`
+	CommentID  int64  `json:"comment_id"`
+	Body       string `json:"body"`
+}
+
+// ReviewThreadOptions はレビュースレッドの解決オプションを表します
+// スレッドはそのスレッド内のいずれかのレビューコメントのIDで指定します
+type ReviewThreadOptions struct {
+	Owner      string `json:"owner"`
+	Repo       string `json:"repo"`
+	PullNumber int    `json:"pull_number"`
+	CommentID  int64  `json:"comment_id"`
+}
+
+// ReviewThreadResult はレビュースレッドの解決結果を表します
+type ReviewThreadResult struct {
+	ThreadID   string `json:"thread_id"`
+	IsResolved bool   `json:"is_resolved"`
+}
+
+// mapGitHubReviewComment はGitHubのレビューコメントをReviewCommentモデルに変換します
+func mapGitHubReviewComment(comment *github.PullRequestComment) ReviewComment {
+	return ReviewComment{
+		ID:                  comment.GetID(),
+		PullRequestReviewID: comment.GetPullRequestReviewID(),
+		InReplyToID:         comment.GetInReplyTo(),
+		User:                mapGitHubUserToUser(comment.User),
+		Body:                comment.GetBody(),
+		Path:                comment.GetPath(),
+		Line:                comment.GetLine(),
+		Side:                comment.GetSide(),
+		StartLine:           comment.GetStartLine(),
+		StartSide:           comment.GetStartSide(),
+		OriginalLine:        comment.GetOriginalLine(),
+		CommitID:            comment.GetCommitID(),
+		DiffHunk:            comment.GetDiffHunk(),
+		HTMLURL:             comment.GetHTMLURL(),
+		CreatedAt:           mapTimestamp(comment.CreatedAt),
+		UpdatedAt:           mapTimestamp(comment.UpdatedAt),
+	}
+}
+
+// ListPullRequestReviewComments はPull Requestのレビューコメントを一覧で取得します
+func ListPullRequestReviewComments(ctx context.Context, options ListPullRequestReviewCommentsOptions, token string) (*ListPullRequestReviewCommentsResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してレビューコメントの一覧を取得
+	comments, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.PullRequestComment, *github.Response, error) {
+		return client.PullRequests.ListComments(ctx, options.Owner, options.Repo, options.PullNumber, &github.PullRequestListCommentsOptions{
+			Sort:        options.Sort,
+			Direction:   options.Direction,
+			Since:       options.Since,
+			ListOptions: listOpts,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result := &ListPullRequestReviewCommentsResult{Items: make([]ReviewComment, 0, len(comments)), NextCursor: next}
+	for _, comment := range comments {
+		result.Items = append(result.Items, mapGitHubReviewComment(comment))
+	}
+	return result, nil
+}
+
+// ReplyToReviewComment はレビューコメントのスレッドに返信します
+func ReplyToReviewComment(ctx context.Context, options ReplyToReviewCommentOptions, token string) (*ReviewComment, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出して返信を作成
+	comment, _, err := client.PullRequests.CreateCommentInReplyTo(ctx, options.Owner, options.Repo, options.PullNumber, options.Body, options.CommentID)
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	result := mapGitHubReviewComment(comment)
+	return &result, nil
+}
+
+// ResolveReviewThread はレビュースレッドを解決済みにします
+func ResolveReviewThread(ctx context.Context, options ReviewThreadOptions, token string) (*ReviewThreadResult, error) {
+	return setReviewThreadResolved(ctx, options, token, true)
+}
+
+// UnresolveReviewThread はレビュースレッドを未解決に戻します
+func UnresolveReviewThread(ctx context.Context, options ReviewThreadOptions, token string) (*ReviewThreadResult, error) {
+	return setReviewThreadResolved(ctx, options, token, false)
+}
+
+// reviewThreadsQuery はPull Requestのレビュースレッドとそのコメントのデータベース上のIDを取得するクエリです
+const reviewThreadsQuery = `query($owner: String!, $repo: String!, $number: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $cursor) {
+        nodes { id isResolved comments(first: 100) { nodes { databaseId } } }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+// findReviewThread はレビューコメントのIDから、そのコメントを含むスレッドのノードIDを求めます
+// スレッドの操作はGraphQL APIでのみ行えますが、REST APIはスレッドのIDを返さないためです
+func findReviewThread(ctx context.Context, client *github.Client, options ReviewThreadOptions) (*ReviewThreadResult, error) {
+	var cursor *string
+	for {
+		var data struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							ID         string `json:"id"`
+							IsResolved bool   `json:"isResolved"`
+							Comments   struct {
+								Nodes []struct {
+									DatabaseID int64 `json:"databaseId"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		err := graphQL(ctx, client, reviewThreadsQuery, map[string]interface{}{
+			"owner":  options.Owner,
+			"repo":   options.Repo,
+			"number": options.PullNumber,
+			"cursor": cursor,
+		}, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		threads := data.Repository.PullRequest.ReviewThreads
+		for _, thread := range threads.Nodes {
+			for _, comment := range thread.Comments.Nodes {
+				if comment.DatabaseID == options.CommentID {
+					return &ReviewThreadResult{ThreadID: thread.ID, IsResolved: thread.IsResolved}, nil
+				}
+			}
+		}
+		if !threads.PageInfo.HasNextPage {
+			break
+		}
+		cursor = &threads.PageInfo.EndCursor
+	}
+
+	return nil, &common.GitHubResourceNotFoundError{GitHubError: common.GitHubError{
+		Message: fmt.Sprintf("review comment %d was not found in pull request #%d", options.CommentID, options.PullNumber),
+		Status:  http.StatusNotFound,
+	}}
+}
+
+// setReviewThreadResolved はGraphQL APIでレビュースレッドを解決済みまたは未解決にします
+func setReviewThreadResolved(ctx context.Context, options ReviewThreadOptions, token string, resolve bool) (*ReviewThreadResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	thread, err := findReviewThread(ctx, client, options)
+	if err != nil {
+		return nil, err
+	}
+	if thread.IsResolved == resolve {
+		// すでに目的の状態であれば何もしない
+		return thread, nil
+	}
+
+	mutation := `mutation($id: ID!) { unresolveReviewThread(input: {threadId: $id}) { thread { id isResolved } } }`
+	if resolve {
+		mutation = `mutation($id: ID!) { resolveReviewThread(input: {threadId: $id}) { thread { id isResolved } } }`
+	}
+	if err := graphQL(ctx, client, mutation, map[string]interface{}{"id": thread.ThreadID}, nil); err != nil {
+		return nil, err
+	}
+	thread.IsResolved = resolve
+	return thread, nil
+}
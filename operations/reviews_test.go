@@ -0,0 +1,24 @@
+package operations
+
+import "testing"
+
+func TestDraftReviewCommentBody(t *testing.T) {
+	suggestion := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		comment DraftReviewComment
+		want    string
+	}{
+		{name: "body only", comment: DraftReviewComment{Body: "nit"}, want: "nit"},
+		{name: "suggestion", comment: DraftReviewComment{Body: "Try:", Suggestion: suggestion("a := 1\n")}, want: "Try:\n\n```suggestion\na := 1\n```"},
+		{name: "delete lines", comment: DraftReviewComment{Suggestion: suggestion("")}, want: "```suggestion\n```"},
+		{name: "suggestion with fence", comment: DraftReviewComment{Suggestion: suggestion("```go\nx\n```")}, want: "````suggestion\n```go\nx\n```\n````"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.comment.body(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
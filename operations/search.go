@@ -0,0 +1,289 @@
+package operations
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v70/github"
+)
+
+// SearchOptions はコード・Issue・コミット・ユーザーの検索オプションを表します
+// Sort と Order を省略した場合はGitHubの既定 (一致度の高い順) で並べます
+type SearchOptions struct {
+	Query string `json:"query"`
+	Sort  string `json:"sort,omitempty"`
+	Order string `json:"order,omitempty"` // asc または desc
+	PageOptions
+}
+
+// TextMatch は検索語に一致した箇所を含む断片を表します
+type TextMatch struct {
+	Property string   `json:"property"`
+	Fragment string   `json:"fragment"`
+	Matches  []string `json:"matches,omitempty"`
+}
+
+// CodeSearchItem はコード検索で見つかったファイルを表します
+type CodeSearchItem struct {
+	Repository  string      `json:"repository"`
+	Path        string      `json:"path"`
+	Name        string      `json:"name"`
+	SHA         string      `json:"sha"`
+	HTMLURL     string      `json:"html_url"`
+	TextMatches []TextMatch `json:"text_matches,omitempty"`
+}
+
+// SearchCodeResult はコード検索の結果を表します
+type SearchCodeResult struct {
+	TotalCount        int              `json:"total_count"`
+	IncompleteResults bool             `json:"incomplete_results"`
+	Items             []CodeSearchItem `json:"items"`
+	NextCursor        string           `json:"next_cursor,omitempty"`
+}
+
+// IssueSummary はIssueまたはPull Requestの概要を表します
+type IssueSummary struct {
+	Repository    string    `json:"repository"`
+	Number        int       `json:"number"`
+	Title         string    `json:"title"`
+	State         string    `json:"state"`
+	IsPullRequest bool      `json:"is_pull_request"`
+	Author        string    `json:"author"`
+	Labels        []string  `json:"labels,omitempty"`
+	Comments      int       `json:"comments"`
+	HTMLURL       string    `json:"html_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SearchIssuesResult はIssueとPull Requestの検索結果を表します
+type SearchIssuesResult struct {
+	TotalCount        int            `json:"total_count"`
+	IncompleteResults bool           `json:"incomplete_results"`
+	Items             []IssueSummary `json:"items"`
+	NextCursor        string         `json:"next_cursor,omitempty"`
+}
+
+// CommitSummary はコミットの概要を表します
+type CommitSummary struct {
+	Repository string    `json:"repository"`
+	SHA        string    `json:"sha"`
+	Message    string    `json:"message"`
+	Author     string    `json:"author"`
+	Date       time.Time `json:"date"`
+	HTMLURL    string    `json:"html_url"`
+}
+
+// SearchCommitsResult はコミットの検索結果を表します
+type SearchCommitsResult struct {
+	TotalCount        int             `json:"total_count"`
+	IncompleteResults bool            `json:"incomplete_results"`
+	Items             []CommitSummary `json:"items"`
+	NextCursor        string          `json:"next_cursor,omitempty"`
+}
+
+// SearchUsersResult はユーザーの検索結果を表します
+type SearchUsersResult struct {
+	TotalCount        int    `json:"total_count"`
+	IncompleteResults bool   `json:"incomplete_results"`
+	Items             []User `json:"items"`
+	NextCursor        string `json:"next_cursor,omitempty"`
+}
+
+// searchOptions はページ指定と組み合わせてgo-githubの検索オプションを作成します
+func (o SearchOptions) searchOptions(listOpts github.ListOptions) *github.SearchOptions {
+	return &github.SearchOptions{Sort: o.Sort, Order: o.Order, ListOptions: listOpts}
+}
+
+// SearchCode はリポジトリ内のファイルの内容を検索します
+// 結果には検索語に一致した箇所の断片 (text_matches) が含まれます
+func SearchCode(ctx context.Context, options SearchOptions, token string) (*SearchCodeResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してコードを検索
+	result := &SearchCodeResult{}
+	codes, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.CodeResult, *github.Response, error) {
+		opts := options.searchOptions(listOpts)
+		opts.TextMatch = true
+		found, resp, err := client.Search.Code(ctx, options.Query, opts)
+		if err != nil {
+			return nil, resp, err
+		}
+		result.TotalCount = found.GetTotal()
+		result.IncompleteResults = result.IncompleteResults || found.GetIncompleteResults()
+		return found.CodeResults, resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result.Items = make([]CodeSearchItem, 0, len(codes))
+	for _, code := range codes {
+		result.Items = append(result.Items, CodeSearchItem{
+			Repository:  code.GetRepository().GetFullName(),
+			Path:        code.GetPath(),
+			Name:        code.GetName(),
+			SHA:         code.GetSHA(),
+			HTMLURL:     code.GetHTMLURL(),
+			TextMatches: mapTextMatches(code.TextMatches),
+		})
+	}
+	result.NextCursor = next
+	return result, nil
+}
+
+// SearchIssues はIssueとPull Requestを検索します
+// Pull Requestのみを検索する場合はクエリに is:pr を、Issueのみの場合は is:issue を含めます
+func SearchIssues(ctx context.Context, options SearchOptions, token string) (*SearchIssuesResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してIssueを検索
+	result := &SearchIssuesResult{}
+	issues, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.Issue, *github.Response, error) {
+		found, resp, err := client.Search.Issues(ctx, options.Query, options.searchOptions(listOpts))
+		if err != nil {
+			return nil, resp, err
+		}
+		result.TotalCount = found.GetTotal()
+		result.IncompleteResults = result.IncompleteResults || found.GetIncompleteResults()
+		return found.Issues, resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result.Items = make([]IssueSummary, 0, len(issues))
+	for _, issue := range issues {
+		result.Items = append(result.Items, mapIssueToSummary(issue))
+	}
+	result.NextCursor = next
+	return result, nil
+}
+
+// SearchCommits はコミットメッセージや作成者でコミットを検索します
+func SearchCommits(ctx context.Context, options SearchOptions, token string) (*SearchCommitsResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してコミットを検索
+	result := &SearchCommitsResult{}
+	commits, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.CommitResult, *github.Response, error) {
+		found, resp, err := client.Search.Commits(ctx, options.Query, options.searchOptions(listOpts))
+		if err != nil {
+			return nil, resp, err
+		}
+		result.TotalCount = found.GetTotal()
+		result.IncompleteResults = result.IncompleteResults || found.GetIncompleteResults()
+		return found.Commits, resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result.Items = make([]CommitSummary, 0, len(commits))
+	for _, c := range commits {
+		author := c.GetAuthor().GetLogin()
+		if author == "" {
+			author = c.GetCommit().GetAuthor().GetName()
+		}
+		result.Items = append(result.Items, CommitSummary{
+			Repository: c.GetRepository().GetFullName(),
+			SHA:        c.GetSHA(),
+			Message:    c.GetCommit().GetMessage(),
+			Author:     author,
+			Date:       mapTimestamp(c.GetCommit().GetAuthor().Date),
+			HTMLURL:    c.GetHTMLURL(),
+		})
+	}
+	result.NextCursor = next
+	return result, nil
+}
+
+// SearchUsers はユーザーとOrganizationを検索します
+func SearchUsers(ctx context.Context, options SearchOptions, token string) (*SearchUsersResult, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub APIを呼び出してユーザーを検索
+	result := &SearchUsersResult{}
+	users, next, err := paginate(ctx, options.PageOptions, func(ctx context.Context, listOpts github.ListOptions) ([]*github.User, *github.Response, error) {
+		found, resp, err := client.Search.Users(ctx, options.Query, options.searchOptions(listOpts))
+		if err != nil {
+			return nil, resp, err
+		}
+		result.TotalCount = found.GetTotal()
+		result.IncompleteResults = result.IncompleteResults || found.GetIncompleteResults()
+		return found.Users, resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 結果をマッピング
+	result.Items = make([]User, 0, len(users))
+	for _, user := range users {
+		result.Items = append(result.Items, mapGitHubUserToUser(user))
+	}
+	result.NextCursor = next
+	return result, nil
+}
+
+// mapTextMatches は一致箇所の断片をTextMatchモデルに変換します
+func mapTextMatches(matches []*github.TextMatch) []TextMatch {
+	if len(matches) == 0 {
+		return nil
+	}
+	result := make([]TextMatch, 0, len(matches))
+	for _, m := range matches {
+		tm := TextMatch{Property: m.GetProperty(), Fragment: m.GetFragment()}
+		for _, match := range m.Matches {
+			tm.Matches = append(tm.Matches, match.GetText())
+		}
+		result = append(result, tm)
+	}
+	return result
+}
+
+// mapIssueToSummary はIssueをIssueSummaryモデルに変換します
+func mapIssueToSummary(issue *github.Issue) IssueSummary {
+	summary := IssueSummary{
+		Repository:    issueRepository(issue),
+		Number:        issue.GetNumber(),
+		Title:         issue.GetTitle(),
+		State:         issue.GetState(),
+		IsPullRequest: issue.IsPullRequest(),
+		Author:        issue.GetUser().GetLogin(),
+		Comments:      issue.GetComments(),
+		HTMLURL:       issue.GetHTMLURL(),
+		CreatedAt:     mapTimestamp(issue.CreatedAt),
+		UpdatedAt:     mapTimestamp(issue.UpdatedAt),
+	}
+	for _, label := range issue.Labels {
+		summary.Labels = append(summary.Labels, label.GetName())
+	}
+	return summary
+}
+
+// issueRepository はIssueが属するリポジトリの owner/repo 形式の名前を返します
+// 検索結果にはリポジトリの詳細が含まれないため、repository_url から求めます
+func issueRepository(issue *github.Issue) string {
+	if name := issue.GetRepository().GetFullName(); name != "" {
+		return name
+	}
+	_, name, _ := strings.Cut(issue.GetRepositoryURL(), "/repos/")
+	return name
+}
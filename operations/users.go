@@ -0,0 +1,48 @@
+package operations
+
+import (
+	"context"
+	"strings"
+
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+// GetAuthenticatedUser はトークンに対応する認証済みユーザーを取得します
+func GetAuthenticatedUser(ctx context.Context, token string) (*User, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ghUser, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	user := mapGitHubUserToUser(ghUser)
+	return &user, nil
+}
+
+// GetTokenInfo はトークンを /user で検証し、ログイン名、スコープ、有効期限を返します
+func GetTokenInfo(ctx context.Context, token string) (*common.TokenInfo, error) {
+	client, err := getGitHubClient(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ghUser, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, mapGitHubError(err)
+	}
+
+	info := &common.TokenInfo{
+		Login:     ghUser.GetLogin(),
+		ExpiresAt: resp.TokenExpiration.Time,
+	}
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			info.Scopes = append(info.Scopes, scope)
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// withPagination は一覧系のツールに共通のページ指定の引数を追加します
+func withPagination(opts ...mcp.ToolOption) []mcp.ToolOption {
+	return append(opts,
+		mcp.WithNumber("page",
+			mcp.Description("ページ番号 (cursor を指定した場合は無視されます)"),
+		),
+		mcp.WithNumber("per_page",
+			mcp.Description(fmt.Sprintf("1ページあたりの結果数 (最大%d)", operations.MaxPerPage)),
+		),
+		mcp.WithString("cursor",
+			mcp.Description("前回の結果の next_cursor。指定すると続きの結果を取得します"),
+		),
+		mcp.WithNumber("max_items",
+			mcp.Description(fmt.Sprintf("指定すると最大でこの件数になるまで自動的に次のページを取得します (最大%d)", operations.MaxPaginatedItems)),
+		),
+	)
+}
+
+// pageOptionsFromArguments はツール引数からページ指定を取得します
+func pageOptionsFromArguments(args map[string]interface{}) (operations.PageOptions, error) {
+	var opts operations.PageOptions
+	for name, target := range map[string]*int{"page": &opts.Page, "per_page": &opts.PerPage, "max_items": &opts.MaxItems} {
+		value, ok := args[name]
+		if !ok {
+			continue
+		}
+		n, ok := value.(float64)
+		if !ok || n != float64(int(n)) {
+			return opts, fmt.Errorf("%s must be an integer", name)
+		}
+		*target = int(n)
+	}
+	if value, ok := args["cursor"]; ok {
+		cursor, ok := value.(string)
+		if !ok {
+			return opts, fmt.Errorf("cursor must be a string")
+		}
+		opts.Cursor = cursor
+	}
+	return opts, opts.Validate()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// policyCheck はツール引数の操作対象がポリシーで許可されているかどうかを確認します
+// 引数の型や必須項目の検証はハンドラーで行うため、対象を特定できない場合はnilを返します
+type policyCheck func(ctx context.Context, policy *common.Policy, args map[string]interface{}) error
+
+// withPolicy はツールハンドラーを実行する前にポリシーを確認します
+// ポリシーはコンテキストにも設定され、検索結果の絞り込みなどに使用されます
+func withPolicy(policy *common.Policy, check policyCheck, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx = common.WithPolicy(ctx, policy)
+		if policy != nil && check != nil {
+			if err := check(ctx, policy, request.GetArguments()); err != nil {
+				return toolResultError(err), nil
+			}
+		}
+		return handler(ctx, request)
+	}
+}
+
+// requireRepoAccess は owner と repo 引数のリポジトリに指定したアクセス権限を要求します
+func requireRepoAccess(access common.Access) policyCheck {
+	return func(ctx context.Context, policy *common.Policy, args map[string]interface{}) error {
+		owner, _ := args["owner"].(string)
+		repo, _ := args["repo"].(string)
+		if owner == "" || repo == "" {
+			return nil
+		}
+		return policy.Check(owner, repo, access)
+	}
+}
+
+// checkCreateRepository は作成するリポジトリへの書き込み権限を要求します
+// useApp はGitHub Appのインストールトークンで認証するかどうかです
+func checkCreateRepository(useApp bool) policyCheck {
+	return func(ctx context.Context, policy *common.Policy, args map[string]interface{}) error {
+		name, _ := args["name"].(string)
+		if name == "" {
+			return nil
+		}
+		owner, err := destinationOwner(ctx, args, useApp)
+		if err != nil || owner == "" {
+			return err
+		}
+		return policy.Check(owner, name, common.AccessWrite)
+	}
+}
+
+// checkForkRepository はフォーク元への読み取り権限とフォーク先への書き込み権限を要求します
+// useApp はGitHub Appのインストールトークンで認証するかどうかです
+func checkForkRepository(useApp bool) policyCheck {
+	return func(ctx context.Context, policy *common.Policy, args map[string]interface{}) error {
+		if err := requireRepoAccess(common.AccessRead)(ctx, policy, args); err != nil {
+			return err
+		}
+		repo, _ := args["repo"].(string)
+		if repo == "" {
+			return nil
+		}
+		owner, err := destinationOwner(ctx, args, useApp)
+		if err != nil || owner == "" {
+			return err
+		}
+		return policy.Check(owner, repo, common.AccessWrite)
+	}
+}
+
+// destinationOwner はリポジトリの作成先のオーナーを返します
+// organization を省略した場合は認証済みユーザーですが、GitHub Appで認証する場合は
+// インストールトークンがユーザーを表さないため /user を呼び出さず、空文字列を返します
+// (その場合 organization の指定はインストールトークンの取得時に要求されます)
+func destinationOwner(ctx context.Context, args map[string]interface{}, useApp bool) (string, error) {
+	if org, _ := args["organization"].(string); org != "" {
+		return org, nil
+	}
+	if useApp {
+		return "", nil
+	}
+	return authenticatedLogin(ctx)
+}
+
+// authenticatedLogin はコンテキストのトークンに対応するユーザーのログイン名を取得します
+// セッション開始時にトークンが検証されている場合は、検証時に取得したログイン名を使用します
+func authenticatedLogin(ctx context.Context) (string, error) {
+	if info := common.TokenInfoFromContext(ctx); info != nil {
+		return info.Login, nil
+	}
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	user, err := operations.GetAuthenticatedUser(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
+
+// filterReadableRepositories はポリシーで読み取りが許可されているリポジトリのみを返します
+func filterReadableRepositories(policy *common.Policy, repos []operations.Repository) []operations.Repository {
+	return filterReadable(policy, repos, func(repo operations.Repository) string { return repo.FullName })
+}
+
+// filterReadable は検索結果のうち、属するリポジトリの読み取りがポリシーで許可されているもののみを返します
+// fullName は結果が属するリポジトリの owner/repo 形式の名前を返します
+func filterReadable[T any](policy *common.Policy, items []T, fullName func(T) string) []T {
+	if policy == nil {
+		return items
+	}
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		owner, name, _ := strings.Cut(fullName(item), "/")
+		if policy.Access(owner, name) >= common.AccessRead {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,560 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// pullTarget はツール引数から owner / repo / pull_number を取得します
+func pullTarget(args map[string]interface{}) (owner, repo string, number int, err error) {
+	owner, ok := args["owner"].(string)
+	if !ok {
+		return "", "", 0, fmt.Errorf("owner must be a string")
+	}
+	repo, ok = args["repo"].(string)
+	if !ok {
+		return "", "", 0, fmt.Errorf("repo must be a string")
+	}
+	numberFloat, ok := args["pull_number"].(float64)
+	if !ok {
+		return "", "", 0, fmt.Errorf("pull_number must be a number")
+	}
+	return owner, repo, int(numberFloat), nil
+}
+
+// mergeMethodArgument はマージ方法の引数を取得します (省略時は空文字列)
+func mergeMethodArgument(args map[string]interface{}) (string, error) {
+	raw, ok := args["merge_method"]
+	if !ok {
+		return "", nil
+	}
+	method, ok := raw.(string)
+	if !ok || (method != "merge" && method != "squash" && method != "rebase") {
+		return "", fmt.Errorf("merge_method must be merge, squash or rebase")
+	}
+	return method, nil
+}
+
+// handleMergePullRequest はPull Requestのマージリクエストを処理します
+func handleMergePullRequest(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.MergePullRequestOptions{Owner: owner, Repo: repo, PullNumber: number}
+	if options.MergeMethod, err = mergeMethodArgument(args); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if title, ok := args["commit_title"].(string); ok {
+		options.CommitTitle = title
+	}
+	if message, ok := args["commit_message"].(string); ok {
+		options.CommitMessage = message
+	}
+	if sha, ok := args["sha"].(string); ok {
+		options.SHA = sha
+	}
+
+	// マージの実行
+	result, err := operations.MergePullRequest(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleUpdatePullRequest はPull Request更新リクエストを処理します
+func handleUpdatePullRequest(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.UpdatePullRequestOptions{Owner: owner, Repo: repo, PullNumber: number}
+	for name, target := range map[string]**string{
+		"title": &options.Title,
+		"body":  &options.Body,
+		"base":  &options.Base,
+		"state": &options.State,
+	} {
+		raw, ok := args[name]
+		if !ok {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return toolResultArgumentError(fmt.Sprintf("%s must be a string", name)), nil
+		}
+		*target = &value
+	}
+	if options.State != nil && *options.State != "open" && *options.State != "closed" {
+		return toolResultArgumentError("state must be open or closed"), nil
+	}
+	for name, target := range map[string]**bool{
+		"draft":                 &options.Draft,
+		"maintainer_can_modify": &options.MaintainerCanModify,
+	} {
+		raw, ok := args[name]
+		if !ok {
+			continue
+		}
+		value, ok := raw.(bool)
+		if !ok {
+			return toolResultArgumentError(fmt.Sprintf("%s must be a boolean", name)), nil
+		}
+		*target = &value
+	}
+
+	// Pull Request更新の実行
+	result, err := operations.UpdatePullRequest(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleUpdatePullRequestBranch はPull Requestのブランチ更新リクエストを処理します
+func handleUpdatePullRequestBranch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.UpdatePullRequestBranchOptions{Owner: owner, Repo: repo, PullNumber: number}
+	if sha, ok := args["expected_head_sha"].(string); ok {
+		options.ExpectedHeadSHA = sha
+	}
+
+	// ブランチ更新の実行
+	result, err := operations.UpdatePullRequestBranch(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleEnablePullRequestAutoMerge は自動マージの有効化リクエストを処理します
+func handleEnablePullRequestAutoMerge(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	method, err := mergeMethodArgument(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// 自動マージの有効化の実行
+	result, err := operations.EnablePullRequestAutoMerge(ctx, operations.PullRequestAutoMergeOptions{
+		Owner:       owner,
+		Repo:        repo,
+		PullNumber:  number,
+		MergeMethod: method,
+	}, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleDisablePullRequestAutoMerge は自動マージの無効化リクエストを処理します
+func handleDisablePullRequestAutoMerge(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	owner, repo, number, err := pullTarget(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// 自動マージの無効化の実行
+	result, err := operations.DisablePullRequestAutoMerge(ctx, operations.PullRequestAutoMergeOptions{
+		Owner:      owner,
+		Repo:       repo,
+		PullNumber: number,
+	}, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleGetPullRequestFiles はPull Requestの変更ファイル一覧の取得リクエストを処理します
+func handleGetPullRequestFiles(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.GetPullRequestFilesOptions{Owner: owner, Repo: repo, PullNumber: number}
+	if options.PageOptions, err = pageOptionsFromArguments(args); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// 変更ファイル一覧の取得の実行
+	result, err := operations.GetPullRequestFiles(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleGetPullRequestDiff はPull Requestのdiffの取得リクエストを処理します
+func handleGetPullRequestDiff(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.GetPullRequestDiffOptions{Owner: owner, Repo: repo, PullNumber: number}
+	if options.Files, _, err = stringArrayArgument(args, "files"); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if raw, ok := args["max_bytes"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n != float64(int(n)) || n < 1 {
+			return toolResultArgumentError("max_bytes must be a positive integer"), nil
+		}
+		options.MaxBytes = int(n)
+	}
+	if raw, ok := args["cursor"]; ok {
+		cursor, ok := raw.(string)
+		if !ok {
+			return toolResultArgumentError("cursor must be a string"), nil
+		}
+		options.Cursor = cursor
+	}
+	if err := options.Validate(); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// diffの取得の実行
+	result, err := operations.GetPullRequestDiff(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// reviewCommentsArgument はレビューとともに作成する行へのコメントの引数を取得します
+func reviewCommentsArgument(args map[string]interface{}) ([]operations.DraftReviewComment, error) {
+	raw, ok := args["comments"]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("comments must be an array of objects")
+	}
+
+	comments := make([]operations.DraftReviewComment, 0, len(items))
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("comments[%d] must be an object", i)
+		}
+
+		var comment operations.DraftReviewComment
+		for name, target := range map[string]*string{
+			"path":       &comment.Path,
+			"side":       &comment.Side,
+			"start_side": &comment.StartSide,
+			"body":       &comment.Body,
+		} {
+			if value, ok := fields[name]; ok {
+				if *target, ok = value.(string); !ok {
+					return nil, fmt.Errorf("comments[%d].%s must be a string", i, name)
+				}
+			}
+		}
+		for name, target := range map[string]*int{"line": &comment.Line, "start_line": &comment.StartLine} {
+			if value, ok := fields[name]; ok {
+				n, ok := value.(float64)
+				if !ok || n != float64(int(n)) {
+					return nil, fmt.Errorf("comments[%d].%s must be an integer", i, name)
+				}
+				*target = int(n)
+			}
+		}
+		if value, ok := fields["suggestion"]; ok {
+			suggestion, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("comments[%d].suggestion must be a string", i)
+			}
+			comment.Suggestion = &suggestion
+		}
+
+		if err := comment.Validate(); err != nil {
+			return nil, fmt.Errorf("comments[%d]: %v", i, err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+// commentIDArgument はレビューコメントのIDの引数を取得します
+func commentIDArgument(args map[string]interface{}) (int64, error) {
+	id, ok := args["comment_id"].(float64)
+	if !ok || id != float64(int64(id)) || id < 1 {
+		return 0, fmt.Errorf("comment_id must be a review comment ID")
+	}
+	return int64(id), nil
+}
+
+// handleListPullRequestReviewComments はレビューコメント一覧の取得リクエストを処理します
+func handleListPullRequestReviewComments(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	options := operations.ListPullRequestReviewCommentsOptions{Owner: owner, Repo: repo, PullNumber: number}
+	if sort, ok := args["sort"].(string); ok {
+		if sort != "created" && sort != "updated" {
+			return toolResultArgumentError("sort must be created or updated"), nil
+		}
+		options.Sort = sort
+	}
+	if direction, ok := args["direction"].(string); ok {
+		if direction != "asc" && direction != "desc" {
+			return toolResultArgumentError("direction must be asc or desc"), nil
+		}
+		options.Direction = direction
+	}
+	if options.Since, err = timeArgument(args, "since"); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+	if options.PageOptions, err = pageOptionsFromArguments(args); err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// レビューコメント一覧の取得の実行
+	result, err := operations.ListPullRequestReviewComments(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleReplyToPullRequestReviewComment はレビューコメントへの返信リクエストを処理します
+func handleReplyToPullRequestReviewComment(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	commentID, err := commentIDArgument(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	body, ok := args["body"].(string)
+	if !ok || body == "" {
+		return toolResultArgumentError("body must be a non-empty string"), nil
+	}
+
+	// 返信の実行
+	result, err := operations.ReplyToReviewComment(ctx, operations.ReplyToReviewCommentOptions{
+		Owner:      owner,
+		Repo:       repo,
+		PullNumber: number,
+		CommentID:  commentID,
+		Body:       body,
+	}, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleResolvePullRequestReviewThread はレビュースレッドの解決リクエストを処理します
+func handleResolvePullRequestReviewThread(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return handleReviewThreadResolution(ctx, request, operations.ResolveReviewThread)
+}
+
+// handleUnresolvePullRequestReviewThread はレビュースレッドの解決の取り消しリクエストを処理します
+func handleUnresolvePullRequestReviewThread(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return handleReviewThreadResolution(ctx, request, operations.UnresolveReviewThread)
+}
+
+// handleReviewThreadResolution はレビュースレッドの解決状態を変更するリクエストを処理します
+func handleReviewThreadResolution(
+	ctx context.Context,
+	request mcp.CallToolRequest,
+	apply func(context.Context, operations.ReviewThreadOptions, string) (*operations.ReviewThreadResult, error),
+) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	args := request.GetArguments()
+	owner, repo, number, err := pullTarget(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	commentID, err := commentIDArgument(args)
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// 解決状態の変更の実行
+	result, err := apply(ctx, operations.ReviewThreadOptions{
+		Owner:      owner,
+		Repo:       repo,
+		PullNumber: number,
+		CommentID:  commentID,
+	}, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// リソースのURIテンプレート
+const (
+	// contentsResourceTemplate はファイルの内容を表すリソースです
+	contentsResourceTemplate = "github://{owner}/{repo}/contents{/path*}{?ref}"
+	// treeResourceTemplate はリポジトリのファイルツリーを表すリソースです
+	treeResourceTemplate = "github://{owner}/{repo}/tree{?ref}"
+	// pullResourceTemplate はPull Requestを表すリソースです
+	pullResourceTemplate = "github://{owner}/{repo}/pulls/{number}"
+)
+
+// ResourceRepository はresources/listに表示するリポジトリを表します
+type ResourceRepository struct {
+	Owner string
+	Repo  string
+	// Ref はブランチ名、タグ名またはコミットSHAです (省略時はデフォルトブランチ)
+	Ref string
+}
+
+// parseResourceRepositories は "owner/repo[@ref],..." 形式のリポジトリ指定を解析します
+func parseResourceRepositories(value string) ([]ResourceRepository, error) {
+	var repos []ResourceRepository
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, ref, _ := strings.Cut(item, "@")
+		owner, repo, ok := strings.Cut(name, "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("%q は owner/repo[@ref] の形式ではありません", item)
+		}
+		repos = append(repos, ResourceRepository{Owner: owner, Repo: repo, Ref: ref})
+	}
+	return repos, nil
+}
+
+// contentsResourceURI はファイルの内容を表すリソースのURIを返します
+func contentsResourceURI(owner, repo, filePath, ref string) string {
+	segments := strings.Split(strings.Trim(filePath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fmt.Sprintf("github://%s/%s/contents/%s", owner, repo, strings.Join(segments, "/")) + refQuery(ref)
+}
+
+// treeResourceURI はファイルツリーを表すリソースのURIを返します
+func treeResourceURI(owner, repo, ref string) string {
+	return fmt.Sprintf("github://%s/%s/tree", owner, repo) + refQuery(ref)
+}
+
+// refQuery はrefを指定するクエリ文字列を返します
+func refQuery(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	return "?" + url.Values{"ref": {ref}}.Encode()
+}
+
+// withResourceContext はリソースハンドラーのコンテキストにタイムアウト、接続先、ポリシー、
+// GitHub Appのインストールトークンを設定し、
+// 読み取り対象のリポジトリがポリシーで許可されているかどうかを確認します
+// 読み取りは停止処理の対象として記録されます
+func withResourceContext(opts ServerOptions, d *drainer, handler server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc {
+	return withResourceDrain(d, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if opts.ToolTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.ToolTimeout)
+			defer cancel()
+		}
+		if _, ok := common.GitHubHostFromContext(ctx); !ok {
+			ctx = common.WithGitHubHost(ctx, opts.GitHubHost)
+		}
+		ctx = common.WithPolicy(ctx, opts.Policy)
+
+		owner := resourceArgument(request, "owner")
+		repo := resourceArgument(request, "repo")
+		if err := opts.Policy.Check(owner, repo, common.AccessRead); err != nil {
+			return nil, resourceError(err)
+		}
+		if opts.GitHubApp != nil {
+			var err error
+			if ctx, err = installationContext(ctx, opts.GitHubApp, owner, repo); err != nil {
+				return nil, resourceError(err)
+			}
+		}
+		return handler(ctx, request)
+	})
+}
+
+// resourceArgument はURIテンプレートに一致した変数の値を返します
+// パスのように複数のセグメントを持つ変数は "/" で連結します
+func resourceArgument(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, "/")
+	default:
+		return ""
+	}
+}
+
+// resourceError はエラーをリソース読み取りのエラーに変換します
+// リソースのエラーはJSON-RPCのエラーとして返されるため、エラーコードをメッセージに含めます
+func resourceError(err error) error {
+	return fmt.Errorf("%s: %s", common.ErrorCode(err), common.FormatGitHubError(err))
+}
+
+// registerResources はファイル、ファイルツリー、Pull Requestのリソースを登録します
+func registerResources(s *server.MCPServer, opts ServerOptions, config toolConfig, d *drainer) {
+	if config.hasToolset(ToolsetFiles) {
+		s.AddResourceTemplate(
+			mcp.NewResourceTemplate(contentsResourceTemplate, "ファイルの内容",
+				mcp.WithTemplateDescription("リポジトリのファイルの内容またはディレクトリのエントリ一覧 (refを省略した場合はデフォルトブランチ)"),
+			),
+			withResourceContext(opts, d, handleReadContentsResource),
+		)
+		s.AddResourceTemplate(
+			mcp.NewResourceTemplate(treeResourceTemplate, "ファイルツリー",
+				mcp.WithTemplateDescription("リポジトリのファイル一覧と各ファイルのリソースURI"),
+				mcp.WithTemplateMIMEType("application/json"),
+			),
+			withResourceContext(opts, d, handleReadTreeResource),
+		)
+
+		// 指定されたリポジトリのファイルツリーはresources/listに表示する
+		for _, repo := range opts.ResourceRepositories {
+			if opts.Policy.Access(repo.Owner, repo.Repo) < common.AccessRead {
+				continue
+			}
+			repo := repo
+			handler := withResourceContext(opts, d, handleReadTreeResource)
+			s.AddResource(
+				mcp.NewResource(treeResourceURI(repo.Owner, repo.Repo, repo.Ref), fmt.Sprintf("%s/%s のファイルツリー", repo.Owner, repo.Repo),
+					mcp.WithResourceDescription("リポジトリのファイル一覧と各ファイルのリソースURI"),
+					mcp.WithMIMEType("application/json"),
+				),
+				func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+					// 固定のリソースにはURIテンプレートの変数がないため、リポジトリの指定から設定する
+					request.Params.Arguments = map[string]interface{}{"owner": repo.Owner, "repo": repo.Repo, "ref": repo.Ref}
+					return handler(ctx, request)
+				},
+			)
+		}
+	}
+
+	if config.hasToolset(ToolsetPulls) {
+		s.AddResourceTemplate(
+			mcp.NewResourceTemplate(pullResourceTemplate, "Pull Request",
+				mcp.WithTemplateDescription("Pull Requestの詳細"),
+				mcp.WithTemplateMIMEType("application/json"),
+			),
+			withResourceContext(opts, d, handleReadPullRequestResource),
+		)
+	}
+}
+
+// handleReadContentsResource はファイルの内容のリソースを読み取ります
+func handleReadContentsResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return nil, resourceError(err)
+	}
+
+	owner := resourceArgument(request, "owner")
+	repo := resourceArgument(request, "repo")
+	ref := resourceArgument(request, "ref")
+	file, err := operations.GetFileContents(ctx, operations.GetFileContentOptions{
+		Owner:  owner,
+		Repo:   repo,
+		Path:   resourceArgument(request, "path"),
+		Branch: ref,
+	}, token)
+	if err != nil {
+		return nil, resourceError(err)
+	}
+
+	if file.Type == "dir" {
+		return jsonResourceContents(request.Params.URI, directoryResource(owner, repo, ref, file))
+	}
+	if file.IsBinary() {
+		return []mcp.ResourceContents{
+			mcp.BlobResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: file.MIMEType,
+				Blob:     file.Content,
+			},
+		}, nil
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: file.MIMEType,
+			Text:     file.Content,
+		},
+	}, nil
+}
+
+// directoryResourceEntry はディレクトリのリソースに含まれるエントリを表します
+type directoryResourceEntry struct {
+	operations.DirectoryEntry
+	URI string `json:"uri"`
+}
+
+// directoryResource はディレクトリのエントリ一覧に各エントリのリソースURIを付加します
+func directoryResource(owner, repo, ref string, dir *operations.FileContent) []directoryResourceEntry {
+	entries := make([]directoryResourceEntry, 0, len(dir.Entries))
+	for _, entry := range dir.Entries {
+		entries = append(entries, directoryResourceEntry{
+			DirectoryEntry: entry,
+			URI:            contentsResourceURI(owner, repo, entry.Path, ref),
+		})
+	}
+	return entries
+}
+
+// treeResource はファイルツリーのリソースの内容を表します
+type treeResource struct {
+	Owner     string              `json:"owner"`
+	Repo      string              `json:"repo"`
+	Ref       string              `json:"ref,omitempty"`
+	SHA       string              `json:"sha"`
+	Truncated bool                `json:"truncated"`
+	Files     []treeResourceEntry `json:"files"`
+}
+
+// treeResourceEntry はファイルツリーのリソースに含まれるファイルを表します
+type treeResourceEntry struct {
+	Path string `json:"path"`
+	Size int    `json:"size"`
+	URI  string `json:"uri"`
+}
+
+// handleReadTreeResource はファイルツリーのリソースを読み取ります
+func handleReadTreeResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return nil, resourceError(err)
+	}
+
+	owner := resourceArgument(request, "owner")
+	repo := resourceArgument(request, "repo")
+	ref := resourceArgument(request, "ref")
+	tree, err := operations.GetRepositoryTree(ctx, operations.GetRepositoryTreeOptions{
+		Owner: owner,
+		Repo:  repo,
+		Ref:   ref,
+	}, token)
+	if err != nil {
+		return nil, resourceError(err)
+	}
+
+	result := treeResource{
+		Owner:     owner,
+		Repo:      repo,
+		Ref:       ref,
+		SHA:       tree.SHA,
+		Truncated: tree.Truncated,
+		Files:     []treeResourceEntry{},
+	}
+	for _, entry := range tree.Entries {
+		if entry.Type != "blob" {
+			continue
+		}
+		result.Files = append(result.Files, treeResourceEntry{
+			Path: entry.Path,
+			Size: entry.Size,
+			URI:  contentsResourceURI(owner, repo, entry.Path, ref),
+		})
+	}
+	return jsonResourceContents(request.Params.URI, result)
+}
+
+// handleReadPullRequestResource はPull Requestのリソースを読み取ります
+func handleReadPullRequestResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return nil, resourceError(err)
+	}
+
+	number, err := strconv.Atoi(resourceArgument(request, "number"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: Pull Requestの番号が不正です: %q", common.ErrorCodeInvalidArgument, resourceArgument(request, "number"))
+	}
+
+	pr, err := operations.GetPullRequest(ctx, operations.GetPullRequestOptions{
+		Owner:      resourceArgument(request, "owner"),
+		Repo:       resourceArgument(request, "repo"),
+		PullNumber: number,
+	}, token)
+	if err != nil {
+		return nil, resourceError(err)
+	}
+	return jsonResourceContents(request.Params.URI, pr)
+}
+
+// jsonResourceContents は値をJSONのリソースの内容に変換します
+func jsonResourceContents(uri string, v interface{}) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
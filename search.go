@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/yamagai/github-mcp-server-sse/common"
+	"github.com/yamagai/github-mcp-server-sse/operations"
+)
+
+// searchOptionsFromArguments はツール引数から検索オプションを取得します
+func searchOptionsFromArguments(args map[string]interface{}) (operations.SearchOptions, error) {
+	query, ok := args["query"].(string)
+	if !ok {
+		return operations.SearchOptions{}, fmt.Errorf("query must be a string")
+	}
+	options := operations.SearchOptions{Query: query}
+	if sort, ok := args["sort"].(string); ok {
+		options.Sort = sort
+	}
+	if order, ok := args["order"].(string); ok {
+		if order != "asc" && order != "desc" {
+			return options, fmt.Errorf("order must be asc or desc")
+		}
+		options.Order = order
+	}
+	pageOpts, err := pageOptionsFromArguments(args)
+	if err != nil {
+		return options, err
+	}
+	options.PageOptions = pageOpts
+	return options, nil
+}
+
+// handleSearchCode はコード検索リクエストを処理します
+func handleSearchCode(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	options, err := searchOptionsFromArguments(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// コード検索の実行
+	result, err := operations.SearchCode(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// ポリシーで読み取りが許可されていないリポジトリは結果から除外する
+	result.Items = filterReadable(common.PolicyFromContext(ctx), result.Items, func(item operations.CodeSearchItem) string { return item.Repository })
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleSearchIssues はIssueとPull Requestの検索リクエストを処理します
+func handleSearchIssues(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	options, err := searchOptionsFromArguments(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// Issue検索の実行
+	result, err := operations.SearchIssues(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// ポリシーで読み取りが許可されていないリポジトリは結果から除外する
+	result.Items = filterReadable(common.PolicyFromContext(ctx), result.Items, func(item operations.IssueSummary) string { return item.Repository })
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleSearchCommits はコミット検索リクエストを処理します
+func handleSearchCommits(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	options, err := searchOptionsFromArguments(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// コミット検索の実行
+	result, err := operations.SearchCommits(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// ポリシーで読み取りが許可されていないリポジトリは結果から除外する
+	result.Items = filterReadable(common.PolicyFromContext(ctx), result.Items, func(item operations.CommitSummary) string { return item.Repository })
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
+
+// handleSearchUsers はユーザー検索リクエストを処理します
+func handleSearchUsers(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	// GitHubトークンの取得
+	token, err := common.GetAuthTokenFromContext(ctx)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// パラメータの解析
+	options, err := searchOptionsFromArguments(request.GetArguments())
+	if err != nil {
+		return toolResultArgumentError(err.Error()), nil
+	}
+
+	// ユーザー検索の実行
+	result, err := operations.SearchUsers(ctx, options, token)
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	// JSON形式で結果を返す
+	jsonResult, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResultError(err), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonResult)), nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yamagai/github-mcp-server-sse/common"
+)
+
+// streamFlushDelay はツール呼び出しの完了後、ストリームを閉じるまで待つ時間です
+//
+// これは応答の送信を保証するものではなく、ベストエフォートの回避策です。mcp-go のSSEトランスポートは
+// ハンドラーの戻り値を別のゴルーチンでセッションのキューに入れてから書き込むため、drainerからは
+// 応答がストリームに書き込まれたことを知る方法がありません。通常はキューへの投入と書き込みが
+// 即座に行われるため、ストリームを閉じる前に短い時間だけ待つことで応答が失われる可能性を減らします。
+const streamFlushDelay = 500 * time.Millisecond
+
+// drainer は実行中のツール呼び出し、リソースの読み取り、HTTPストリームを追跡し、停止時にそれらの完了を待ちます
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	calls    sync.WaitGroup
+	streams  map[*http.Request]context.CancelFunc
+}
+
+// newDrainer は新しいdrainerを作成します
+func newDrainer() *drainer {
+	return &drainer{streams: make(map[*http.Request]context.CancelFunc)}
+}
+
+// acquire はツール呼び出しまたはリソースの読み取りの開始を記録します
+// 停止処理が始まっている場合はfalseを返します
+func (d *drainer) acquire() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.calls.Add(1)
+	return true
+}
+
+// release はツール呼び出しまたはリソースの読み取りの完了を記録します
+func (d *drainer) release() {
+	d.calls.Done()
+}
+
+// track はリクエストのコンテキストを停止時にキャンセルできるようにするHTTPハンドラーを返します
+// SSEのストリームは切断されるまでレスポンスを返さないため、停止時にはコンテキストをキャンセルして終了させます
+func (d *drainer) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		d.mu.Lock()
+		d.streams[r] = cancel
+		d.mu.Unlock()
+		defer func() {
+			d.mu.Lock()
+			delete(d.streams, r)
+			d.mu.Unlock()
+		}()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// shutdown は新しいツール呼び出しの受け付けを停止し、実行中のツール呼び出しの完了を待ってから
+// 残っているストリームを閉じます
+// コンテキストが終了した場合は完了を待たずにストリームを閉じ、コンテキストのエラーを返します
+func (d *drainer) shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.calls.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+		select {
+		case <-time.After(streamFlushDelay):
+		case <-ctx.Done():
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	d.mu.Lock()
+	for _, cancel := range d.streams {
+		cancel()
+	}
+	d.mu.Unlock()
+	return err
+}
+
+// errDraining は停止処理中に新しいツール呼び出しやリソースの読み取りを受け付けられない場合のエラーメッセージです
+const errDraining = "サーバーを停止中のため、新しいリクエストを受け付けられません"
+
+// withDrain は停止処理中の新しいツール呼び出しを拒否し、実行中のツール呼び出しを記録します
+func withDrain(d *drainer, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !d.acquire() {
+			return newToolErrorResult(common.ErrorCodeUnavailable, errDraining), nil
+		}
+		defer d.release()
+		return handler(ctx, request)
+	}
+}
+
+// withResourceDrain は停止処理中の新しいリソースの読み取りを拒否し、実行中の読み取りを記録します
+func withResourceDrain(d *drainer, handler server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if !d.acquire() {
+			return nil, fmt.Errorf("%s: %s", common.ErrorCodeUnavailable, errDraining)
+		}
+		defer d.release()
+		return handler(ctx, request)
+	}
+}
+
+// HTTPConfig はHTTPサーバーの待ち受けと停止の設定を表します
+type HTTPConfig struct {
+	// Listen は待ち受けるアドレスです (例: localhost:8080、0.0.0.0:8080)
+	Listen string
+	// TLSCertFile と TLSKeyFile が指定された場合はHTTPSで待ち受けます
+	TLSCertFile string
+	TLSKeyFile  string
+	// ShutdownTimeout は停止時に実行中のツール呼び出しとストリームの終了を待つ最大時間です
+	ShutdownTimeout time.Duration
+}
+
+// tls はHTTPSで待ち受けるかどうかを判断します
+func (c HTTPConfig) tls() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// validate は設定が正しいかどうかを確認します
+func (c HTTPConfig) validate() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("--tls-cert と --tls-key は両方を指定してください")
+	}
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		return fmt.Errorf("待ち受けアドレス %q が不正です: %w", c.Listen, err)
+	}
+	return nil
+}
+
+// defaultBaseURL は待ち受けアドレスからクライアントに通知するベースURLを導出します
+// すべてのインターフェースで待ち受ける場合はlocalhostを使用します
+func (c HTTPConfig) defaultBaseURL() string {
+	scheme := "http"
+	if c.tls() {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		return scheme + "://" + c.Listen
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// isLoopback は待ち受けアドレスがループバックアドレスかどうかを判断します
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseBaseURL はクライアントに通知するベースURLを検証し、末尾の "/" を取り除いて返します
+// リバースプロキシの背後で公開する場合はパスを含めることができます
+func parseBaseURL(value string) (string, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%q のスキームはhttpまたはhttpsである必要があります", value)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("%q にホストが含まれていません", value)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("%q にクエリやフラグメントは指定できません", value)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+// ListenAndServe はHTTPサーバーを起動し、コンテキストが終了するまでリクエストを処理します
+// コンテキストが終了すると新しい接続の受け付けを停止し、実行中のツール呼び出しとストリームの終了を待ってから戻ります
+func (s *GitHubMCPServer) ListenAndServe(ctx context.Context, config HTTPConfig, handler http.Handler) error {
+	srv := &http.Server{
+		Addr:    config.Listen,
+		Handler: s.drainer.track(handler),
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if config.tls() {
+			errCh <- srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx := context.Background()
+	if config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, config.ShutdownTimeout)
+		defer cancel()
+	}
+
+	// 新しい接続の受け付けを停止してから、実行中のツール呼び出しの完了を待ちストリームを閉じる
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	}()
+	drainErr := s.drainer.shutdown(shutdownCtx)
+	if err := <-shutdownErr; err != nil {
+		srv.Close()
+		return fmt.Errorf("サーバーを正常に停止できませんでした: %w", err)
+	}
+	if drainErr != nil {
+		return fmt.Errorf("実行中のツール呼び出しの完了を待てませんでした: %w", drainErr)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// sseEndpoint はSSE接続を受け付けるパスです
+	sseEndpoint = "/sse"
+	// messageEndpoint はサーバーの既定の選択条件のセッションがメッセージを送信するパスです
+	messageEndpoint = "/message"
+)
+
+// sseRouter はツールの選択条件ごとにSSEサーバーを振り分けるHTTPハンドラーです
+//
+// mcp-go のサーバーはツール一覧をセッションごとに変えられないため、選択条件ごとに
+// MCPサーバーを作成します。SSE接続時のヘッダーから選択条件を決定し、
+// メッセージは選択条件ごとのパス (/message/{key}) で受け付けます。
+type sseRouter struct {
+	base    *GitHubMCPServer
+	baseURL string
+
+	mu      sync.Mutex
+	servers map[string]*server.SSEServer
+}
+
+// newSSERouter は新しいsseRouterを作成します
+func newSSERouter(base *GitHubMCPServer, baseURL string) *sseRouter {
+	r := &sseRouter{
+		base:    base,
+		baseURL: baseURL,
+		servers: make(map[string]*server.SSEServer),
+	}
+	r.servers[base.opts.toolConfig().key()] = r.newSSEServer(base, messageEndpoint)
+	return r
+}
+
+// newSSEServer はMCPサーバーをSSEで公開するサーバーを作成します
+func (r *sseRouter) newSSEServer(s *GitHubMCPServer, endpoint string) *server.SSEServer {
+	return server.NewSSEServer(s.server,
+		server.WithBaseURL(r.baseURL),
+		server.WithSSEEndpoint(sseEndpoint),
+		server.WithMessageEndpoint(endpoint),
+		server.WithSSEContextFunc(s.requestContext),
+	)
+}
+
+// serverFor は選択条件に対応するSSEサーバーを返します
+// 初めて使用される選択条件の場合はサーバーを作成します
+func (r *sseRouter) serverFor(config toolConfig) *server.SSEServer {
+	key := config.key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if srv, ok := r.servers[key]; ok {
+		return srv
+	}
+
+	srv := r.newSSEServer(r.base.withToolConfig(config), messageEndpoint+"/"+key)
+	r.servers[key] = srv
+	return srv
+}
+
+// ServeHTTP はSSE接続とメッセージを対応するサーバーに振り分けます
+func (r *sseRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch path := req.URL.Path; {
+	case path == sseEndpoint:
+		config, err := toolConfigFromRequest(r.base.opts.toolConfig(), req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.serverFor(config).ServeHTTP(w, req)
+	case path == messageEndpoint:
+		r.serverFor(r.base.opts.toolConfig()).ServeHTTP(w, req)
+	case strings.HasPrefix(path, messageEndpoint+"/"):
+		// 選択条件ごとのサーバーはSSE接続時にのみ作成し、メッセージのパスからは作成しない
+		r.mu.Lock()
+		srv, ok := r.servers[strings.TrimPrefix(path, messageEndpoint+"/")]
+		r.mu.Unlock()
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		srv.ServeHTTP(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ツールセット名
+const (
+	// ToolsetRepos はリポジトリの検索・作成・フォークを行うツール群です
+	ToolsetRepos = "repos"
+	// ToolsetFiles はファイルの取得・作成・更新を行うツール群です
+	ToolsetFiles = "files"
+	// ToolsetPulls はPull Requestとレビューを扱うツール群です
+	ToolsetPulls = "pulls"
+	// ToolsetIssues はIssueとそのコメントを扱うツール群です
+	ToolsetIssues = "issues"
+	// ToolsetAll はすべてのツールセットを表す特別な名前です
+	ToolsetAll = "all"
+)
+
+// ReadOnlyHeader はSSEモードでセッションを読み取り専用にするヘッダーです
+const ReadOnlyHeader = "X-MCP-Read-Only"
+
+// ToolsetsHeader はSSEモードでセッションに公開するツールセットを指定するヘッダーです
+const ToolsetsHeader = "X-MCP-Toolsets"
+
+// availableToolsets は指定可能なツールセットの一覧です
+var availableToolsets = []string{ToolsetRepos, ToolsetFiles, ToolsetPulls, ToolsetIssues}
+
+// toolConfig は公開するツールの選択条件を表します
+type toolConfig struct {
+	// readOnly が true の場合は変更を伴わないツールのみを公開します
+	readOnly bool
+	// toolsets は公開するツールセットの一覧です (nilの場合はすべて)
+	toolsets []string
+}
+
+// enabled は指定されたツールを公開するかどうかを判断します
+func (c toolConfig) enabled(toolset string, readOnly bool) bool {
+	if c.readOnly && !readOnly {
+		return false
+	}
+	return c.hasToolset(toolset)
+}
+
+// hasToolset は指定されたツールセットが選択されているかどうかを判断します
+func (c toolConfig) hasToolset(toolset string) bool {
+	if c.toolsets == nil {
+		return true
+	}
+	for _, name := range c.toolsets {
+		if name == toolset {
+			return true
+		}
+	}
+	return false
+}
+
+// restrict はリクエストで指定された条件を適用した選択条件を返します
+// リクエストからはツールを絞り込むことのみ可能で、サーバーの設定より多くのツールを公開することはできません
+func (c toolConfig) restrict(readOnly bool, toolsets []string) toolConfig {
+	restricted := toolConfig{readOnly: c.readOnly || readOnly, toolsets: c.toolsets}
+	if toolsets == nil {
+		return restricted
+	}
+	restricted.toolsets = []string{}
+	for _, name := range toolsets {
+		if c.hasToolset(name) {
+			restricted.toolsets = append(restricted.toolsets, name)
+		}
+	}
+	return restricted
+}
+
+// key は選択条件を識別する文字列を返します
+func (c toolConfig) key() string {
+	mode := "rw"
+	if c.readOnly {
+		mode = "ro"
+	}
+	toolsets := ToolsetAll
+	if c.toolsets != nil {
+		toolsets = strings.Join(c.toolsets, "+")
+	}
+	return mode + "-" + toolsets
+}
+
+// parseToolsets は "repos,files" 形式のツールセット指定を解析します
+// 空文字列または "all" を含む場合はnil (すべて) を返します
+func parseToolsets(value string) ([]string, error) {
+	var toolsets []string
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		name := strings.ToLower(strings.TrimSpace(item))
+		if name == "" {
+			continue
+		}
+		if name == ToolsetAll {
+			return nil, nil
+		}
+		if !isAvailableToolset(name) {
+			return nil, fmt.Errorf("不明なツールセット %q (%s のいずれかを指定してください)", name, strings.Join(availableToolsets, ", "))
+		}
+		if !seen[name] {
+			seen[name] = true
+			toolsets = append(toolsets, name)
+		}
+	}
+	sort.Strings(toolsets)
+	return toolsets, nil
+}
+
+// isAvailableToolset は指定された名前が有効なツールセットかどうかを判断します
+func isAvailableToolset(name string) bool {
+	for _, toolset := range availableToolsets {
+		if toolset == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toolConfigFromRequest はHTTPリクエストのヘッダーを適用したツールの選択条件を返します
+func toolConfigFromRequest(base toolConfig, r *http.Request) (toolConfig, error) {
+	readOnly := false
+	if v := r.Header.Get(ReadOnlyHeader); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return toolConfig{}, fmt.Errorf("%sヘッダーが不正です: %q", ReadOnlyHeader, v)
+		}
+		readOnly = parsed
+	}
+
+	var toolsets []string
+	if v := r.Header.Get(ToolsetsHeader); v != "" {
+		parsed, err := parseToolsets(v)
+		if err != nil {
+			return toolConfig{}, fmt.Errorf("%sヘッダーが不正です: %v", ToolsetsHeader, err)
+		}
+		toolsets = parsed
+	}
+
+	return base.restrict(readOnly, toolsets), nil
+}